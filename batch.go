@@ -0,0 +1,47 @@
+package cotlib
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ValidateRawBatch decodes and validates each document as UnmarshalXMLEvent
+// would, spreading the work over workers goroutines, and returns one error
+// per document in input order; nil marks a valid document. workers <= 0
+// uses GOMAXPROCS. Once ctx is done, documents that have not been started
+// report ctx.Err().
+func ValidateRawBatch(ctx context.Context, docs [][]byte, workers int) []error {
+	errs := make([]error, len(docs))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(docs) {
+		workers = len(docs)
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(docs) {
+					return
+				}
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				evt, err := UnmarshalXMLEvent(ctx, docs[i])
+				ReleaseEvent(evt)
+				errs[i] = err
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
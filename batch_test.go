@@ -0,0 +1,54 @@
+package cotlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidateRawBatch(t *testing.T) {
+	const n = 200
+	docs := make([][]byte, n)
+	for i := range docs {
+		if i%3 == 0 {
+			docs[i] = []byte(fmt.Sprintf(`<event uid="BAD%d"><bad></event>`, i))
+			continue
+		}
+		evt, err := NewEvent(fmt.Sprintf("B%d", i), "a-f-G", float64(i%90), float64(i%180), 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		docs[i], err = evt.ToXML()
+		ReleaseEvent(evt)
+		if err != nil {
+			t.Fatalf("ToXML: %v", err)
+		}
+	}
+
+	for _, workers := range []int{0, 1, 8} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			errs := ValidateRawBatch(context.Background(), docs, workers)
+			if len(errs) != n {
+				t.Fatalf("got %d results, want %d", len(errs), n)
+			}
+			for i, err := range errs {
+				if wantErr := i%3 == 0; (err != nil) != wantErr {
+					t.Errorf("doc %d: err = %v, want error %v", i, err, wantErr)
+				}
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i, err := range ValidateRawBatch(ctx, docs, 4) {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("doc %d after cancel: err = %v", i, err)
+		}
+	}
+
+	if errs := ValidateRawBatch(context.Background(), nil, 4); len(errs) != 0 {
+		t.Errorf("empty batch returned %v", errs)
+	}
+}
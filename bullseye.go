@@ -0,0 +1,95 @@
+package cotlib
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// BullseyeDistanceUnits is the fixed distanceUnits value required by the
+// tak-details-bullseye schema.
+const BullseyeDistanceUnits = "u-r-b-bullseye"
+
+// Bullseye represents the TAK bullseye extension describing a range and
+// bearing reference point. The attributes are decoded into fields; Raw
+// preserves the original element and is emitted when set, otherwise the
+// element is rebuilt from the fields.
+type Bullseye struct {
+	XMLName          xml.Name   `xml:"bullseye"`
+	Mils             bool       `xml:"mils,attr"`
+	Distance         float64    `xml:"distance,attr"`
+	BearingRef       string     `xml:"bearingRef,attr"` // T (true), M (magnetic) or G (grid)
+	BullseyeUID      string     `xml:"bullseyeUID,attr"`
+	DistanceUnits    string     `xml:"distanceUnits,attr"`
+	EdgeToCenter     bool       `xml:"edgeToCenter,attr"`
+	RangeRingVisible bool       `xml:"rangeRingVisible,attr"`
+	Title            string     `xml:"title,attr"`
+	HasRangeRings    bool       `xml:"hasRangeRings,attr"`
+	Raw              RawMessage `xml:"-"`
+}
+
+// NewBullseye returns a bullseye extension centred on the event with uid
+// bullseyeUID, with the given title, range in meters and bearing
+// reference (T, M or G). Bearings are in degrees and range rings are off.
+func NewBullseye(bullseyeUID, title string, distance float64, bearingRef string) *Bullseye {
+	return &Bullseye{
+		Distance:      distance,
+		BearingRef:    bearingRef,
+		BullseyeUID:   bullseyeUID,
+		DistanceUnits: BullseyeDistanceUnits,
+		Title:         title,
+	}
+}
+
+// UnmarshalXML captures the raw element and decodes its attributes.
+func (b *Bullseye) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	raw, err := captureRaw(dec, start)
+	if err != nil {
+		return err
+	}
+	type alias Bullseye
+	var a alias
+	if err := xml.Unmarshal(raw, &a); err != nil {
+		return err
+	}
+	*b = Bullseye(a)
+	b.Raw = raw
+	return nil
+}
+
+// MarshalXML writes Raw when present and the decoded fields otherwise.
+func (b Bullseye) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if len(b.Raw) > 0 {
+		return encodeRaw(enc, b.Raw)
+	}
+	start = xml.StartElement{Name: xml.Name{Local: "bullseye"}}
+	boolAttr := func(name string, v bool) xml.Attr {
+		return xml.Attr{Name: xml.Name{Local: name}, Value: strconv.FormatBool(v)}
+	}
+	strAttr := func(name, v string) xml.Attr {
+		return xml.Attr{Name: xml.Name{Local: name}, Value: v}
+	}
+	start.Attr = []xml.Attr{
+		boolAttr("mils", b.Mils),
+		decimalAttr("distance", b.Distance),
+		strAttr("bearingRef", b.BearingRef),
+		strAttr("bullseyeUID", b.BullseyeUID),
+		strAttr("distanceUnits", b.DistanceUnits),
+		boolAttr("edgeToCenter", b.EdgeToCenter),
+		boolAttr("rangeRingVisible", b.RangeRingVisible),
+		strAttr("title", b.Title),
+		boolAttr("hasRangeRings", b.HasRangeRings),
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// BullseyeBearing returns the range and bearing reference of the event's
+// bullseye detail. ok is false if the event has no bullseye.
+func (e *Event) BullseyeBearing() (distance float64, bearingRef string, ok bool) {
+	if e == nil || e.Detail == nil || e.Detail.Bullseye == nil {
+		return 0, "", false
+	}
+	return e.Detail.Bullseye.Distance, e.Detail.Bullseye.BearingRef, true
+}
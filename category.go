@@ -0,0 +1,96 @@
+package cotlib
+
+import (
+	"strings"
+
+	"github.com/NERVsystems/cotlib/cottypes"
+)
+
+// Event categories returned by Event.Category.
+const (
+	// CategorySA covers atoms ("a-"): units, equipment and other
+	// situational-awareness markers.
+	CategorySA = "sa"
+	// CategoryChat covers GeoChat messages (b-t-f).
+	CategoryChat = "chat"
+	// CategoryEmergency covers emergency alerts (b-e-r, b-e-a, b-e-s).
+	CategoryEmergency = "emergency"
+	// CategoryDrawing covers drawn shapes ("u-d-").
+	CategoryDrawing = "drawing"
+	// CategoryRoute covers routes and their waypoints ("b-m-r", "b-m-p-").
+	CategoryRoute = "route"
+	// CategoryTasking covers taskings ("t-") other than the TAK control
+	// messages under "t-x-".
+	CategoryTasking = "tasking"
+	// CategoryOther covers every other type.
+	CategoryOther = "other"
+)
+
+// Category returns the broad category of the event's type, one of the
+// Category constants, for grouping events in a UI.
+func (e *Event) Category() string {
+	if e == nil {
+		return CategoryOther
+	}
+	typ := e.Type
+	switch {
+	case strings.HasPrefix(typ, "a-"):
+		return CategorySA
+	case typ == "b-t-f" || strings.HasPrefix(typ, "b-t-f-"):
+		return CategoryChat
+	case typ == "b-e-r" || typ == "b-e-a" || typ == "b-e-s":
+		return CategoryEmergency
+	case strings.HasPrefix(typ, "u-d-"):
+		return CategoryDrawing
+	case typ == "b-m-r" || strings.HasPrefix(typ, "b-m-p-"):
+		return CategoryRoute
+	case isTaskingType(typ):
+		return CategoryTasking
+	default:
+		return CategoryOther
+	}
+}
+
+// isTaskingType reports whether typ is a tasking ("t-"), excluding the TAK
+// control messages under "t-x-" such as pings and delete requests.
+func isTaskingType(typ string) bool {
+	return strings.HasPrefix(typ, "t-") && !strings.HasPrefix(typ, "t-x-")
+}
+
+// categoryHows lists, by the catalog "what" name, the hows offered for
+// each category. Categories not listed get every how.
+var categoryHows = map[string][]string{
+	CategorySA:        {"gps", "dgps", "ins+gps", "ins", "fused", "tracker", "radio", "manual", "estimated", "calculated", "gigo"},
+	CategoryChat:      {"manual", "gigo"},
+	CategoryEmergency: {"gps", "manual", "gigo"},
+	CategoryDrawing:   {"manual", "estimated", "calculated"},
+	CategoryRoute:     {"manual", "calculated"},
+	CategoryTasking:   {"manual", "estimated", "gigo"},
+}
+
+// HowsForCategory returns the how values that suit events of category,
+// as returned by Event.Category, for offering as position sources in a
+// UI: GPS and manual entry for SA markers, but only human-entered values
+// for chat, for example. The list is advisory and validation does not
+// enforce it. CategoryOther and unknown categories get every how in the
+// catalog. Entries are returned in catalog order.
+func HowsForCategory(category string) []cottypes.HowInfo {
+	all := cottypes.GetAllHows()
+	names, ok := categoryHows[category]
+	if !ok {
+		return append([]cottypes.HowInfo(nil), all...)
+	}
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	var out []cottypes.HowInfo
+	seen := make(map[string]bool)
+	for _, h := range all {
+		if want[h.What] && !seen[h.What] {
+			seen[h.What] = true
+			out = append(out, h)
+		}
+	}
+	return out
+}
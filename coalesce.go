@@ -0,0 +1,98 @@
+package cotlib
+
+import (
+	"sync"
+	"time"
+)
+
+// Coalescer decimates a stream of SA updates by keeping only the freshest
+// event per uid until the batch is flushed. A relay can Add every incoming
+// event and Flush once per window to forward one update per track.
+//
+// The Coalescer takes ownership of events passed to Add. An event that is
+// superseded by a fresher update for the same uid, or that arrives older
+// than the one already held, is released with ReleaseEvent. Events returned
+// by Flush belong to the caller.
+//
+// A Coalescer is safe for concurrent use.
+type Coalescer struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	latest    map[string]*Event
+	order     []string
+	lastFlush time.Time
+}
+
+// NewCoalescer returns a Coalescer that is due for flushing every window.
+func NewCoalescer(window time.Duration) *Coalescer {
+	return &Coalescer{
+		window:    window,
+		latest:    make(map[string]*Event),
+		lastFlush: time.Now(),
+	}
+}
+
+// Window returns the flush interval the Coalescer was created with.
+func (c *Coalescer) Window() time.Duration {
+	return c.window
+}
+
+// Add records e as the current update for its uid if it is at least as
+// fresh, by event time, as the one already held. Nil events are ignored.
+func (c *Coalescer) Add(e *Event) {
+	if e == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	prev, ok := c.latest[e.Uid]
+	if !ok {
+		c.latest[e.Uid] = e
+		c.order = append(c.order, e.Uid)
+		return
+	}
+	if prev == e {
+		return
+	}
+	if e.Time.Time().Before(prev.Time.Time()) {
+		ReleaseEvent(e)
+		return
+	}
+	c.latest[e.Uid] = e
+	ReleaseEvent(prev)
+}
+
+// Len returns the number of uids currently held.
+func (c *Coalescer) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.order)
+}
+
+// Due reports whether at least one window has passed since the last Flush
+// (or since the Coalescer was created).
+func (c *Coalescer) Due(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return now.Sub(c.lastFlush) >= c.window
+}
+
+// Flush returns the freshest event for each uid, in the order the uids
+// were first added, and starts a new empty batch. It returns nil if no
+// events are held.
+func (c *Coalescer) Flush() []*Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastFlush = time.Now()
+	if len(c.order) == 0 {
+		return nil
+	}
+	out := make([]*Event, len(c.order))
+	for i, uid := range c.order {
+		out[i] = c.latest[uid]
+		delete(c.latest, uid)
+	}
+	c.order = c.order[:0]
+	return out
+}
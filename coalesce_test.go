@@ -0,0 +1,66 @@
+package cotlib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoalescer(t *testing.T) {
+	c := NewCoalescer(100 * time.Millisecond)
+	base := time.Now().UTC().Truncate(time.Second)
+	// Anchor the window at the first event so Due does not depend on how
+	// long the test takes to run.
+	c.lastFlush = base
+
+	add := func(uid string, offset time.Duration, lat float64) {
+		t.Helper()
+		evt, err := NewEvent(uid, "a-f-G", lat, 0, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		evt.Time = CoTTime(base.Add(offset))
+		c.Add(evt)
+	}
+
+	for i := 0; i < 10; i++ {
+		add("TRK-A", time.Duration(i)*10*time.Millisecond, float64(i))
+		add("TRK-B", time.Duration(i)*10*time.Millisecond, float64(-i))
+	}
+	// An out-of-order update older than the held one is dropped.
+	add("TRK-A", -time.Second, 50)
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+	// The last update arrived 90ms into the window.
+	if c.Due(base.Add(90 * time.Millisecond)) {
+		t.Error("Due before the window elapsed")
+	}
+	if !c.Due(base.Add(c.Window())) {
+		t.Error("not Due after the window elapsed")
+	}
+
+	batch := c.Flush()
+	if len(batch) != 2 {
+		t.Fatalf("Flush returned %d events, want 2", len(batch))
+	}
+	if batch[0].Uid != "TRK-A" || batch[0].Point.Lat != 9 {
+		t.Errorf("TRK-A = %s lat %v, want latest lat 9", batch[0].Uid, batch[0].Point.Lat)
+	}
+	if batch[1].Uid != "TRK-B" || batch[1].Point.Lat != -9 {
+		t.Errorf("TRK-B = %s lat %v, want latest lat -9", batch[1].Uid, batch[1].Point.Lat)
+	}
+	for _, e := range batch {
+		ReleaseEvent(e)
+	}
+
+	if got := c.Flush(); got != nil {
+		t.Errorf("second Flush = %d events, want nil", len(got))
+	}
+	add("TRK-C", 0, 1)
+	if got := c.Flush(); len(got) != 1 || got[0].Uid != "TRK-C" {
+		t.Errorf("Flush after new batch = %v", got)
+	} else {
+		ReleaseEvent(got[0])
+	}
+}
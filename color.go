@@ -0,0 +1,119 @@
+package cotlib
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// Color sources reported by Event.EffectiveColor.
+const (
+	// ColorSourceDetail indicates the color came from a <color> detail.
+	ColorSourceDetail = "color"
+	// ColorSourceStroke indicates the color came from the strokeColor
+	// event attribute or <strokecolor> detail.
+	ColorSourceStroke = "strokeColor"
+	// ColorSourceTeam indicates the color was mapped from the TAK team
+	// named by <__group> (or <group>).
+	ColorSourceTeam = "team"
+)
+
+// teamColors maps the ATAK team names to their ARGB display colors.
+var teamColors = map[string]uint32{
+	"white":      0xFFFFFFFF,
+	"yellow":     0xFFFFFF00,
+	"orange":     0xFFFF7700,
+	"magenta":    0xFFFF00FF,
+	"red":        0xFFFF0000,
+	"maroon":     0xFF7F0000,
+	"purple":     0xFF7F007F,
+	"dark blue":  0xFF00007F,
+	"blue":       0xFF0000FF,
+	"cyan":       0xFF00FFFF,
+	"teal":       0xFF007F7F,
+	"green":      0xFF00FF00,
+	"dark green": 0xFF007F00,
+	"brown":      0xFFA0714F,
+}
+
+// TeamColor returns the ARGB display color ATAK uses for the named team,
+// such as "Cyan" or "Dark Blue". The value is signed, as TAK writes it.
+func TeamColor(team string) (int, bool) {
+	c, ok := teamColors[strings.ToLower(strings.TrimSpace(team))]
+	return int(int32(c)), ok
+}
+
+// EffectiveColor resolves the color ATAK would display for the event. An
+// explicit <color> detail wins, then the strokeColor event attribute or
+// <strokecolor> detail, then the color of the team named by <__group> or
+// <group>. The ARGB value is signed, as TAK writes it (-1 is opaque
+// white), and source is one of the ColorSource constants. It returns
+// false when no source yields a parseable color.
+func (e *Event) EffectiveColor() (argb int, source string, ok bool) {
+	if e == nil {
+		return 0, "", false
+	}
+	d := e.Detail
+	if d != nil && d.ColorExtension != nil {
+		var helper struct {
+			ARGB  string `xml:"argb,attr"`
+			Value string `xml:"value,attr"`
+		}
+		if err := xml.Unmarshal(d.ColorExtension.Raw, &helper); err == nil {
+			if c, ok := parseARGBInt(helper.ARGB); ok {
+				return c, ColorSourceDetail, true
+			}
+			if c, ok := parseARGBInt(helper.Value); ok {
+				return c, ColorSourceDetail, true
+			}
+		}
+	}
+	if e.StrokeColor != "" {
+		if v, err := strconv.ParseUint(e.StrokeColor, 16, 32); err == nil && len(e.StrokeColor) == 8 {
+			return int(int32(v)), ColorSourceStroke, true
+		}
+	}
+	if d != nil && d.StrokeColor != nil {
+		var helper struct {
+			Value string `xml:"value,attr"`
+		}
+		if err := xml.Unmarshal(d.StrokeColor.Raw, &helper); err == nil {
+			if c, ok := parseARGBInt(helper.Value); ok {
+				return c, ColorSourceStroke, true
+			}
+		}
+	}
+	if d != nil && d.GroupExtension != nil {
+		var helper struct {
+			Name string `xml:"name,attr"`
+		}
+		if err := xml.Unmarshal(d.GroupExtension.Raw, &helper); err == nil {
+			if c, ok := TeamColor(helper.Name); ok {
+				return c, ColorSourceTeam, true
+			}
+		}
+	}
+	if d != nil && d.Group != nil {
+		if c, ok := TeamColor(d.Group.Name); ok {
+			return c, ColorSourceTeam, true
+		}
+	}
+	return 0, "", false
+}
+
+// parseARGBInt parses a decimal ARGB value as written by TAK. Both the
+// signed form (-1) and the unsigned form (4294967295) are accepted and
+// returned signed.
+func parseARGBInt(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	if v, err := strconv.ParseInt(s, 10, 32); err == nil {
+		return int(v), true
+	}
+	if v, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return int(int32(v)), true
+	}
+	return 0, false
+}
@@ -79,13 +79,17 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/NERVsystems/cotlib/ctxlog"
 
@@ -99,6 +103,9 @@ const (
 	// Increased to 5 seconds to prevent replay attacks on slow links
 	minStaleOffset = 5 * time.Second
 
+	// defaultStaleOffset is the stale offset applied to newly created events
+	defaultStaleOffset = 6 * time.Second
+
 	// maxStaleOffset is the maximum time between event time and stale time
 	// Events cannot be valid for more than 7 days to prevent stale data
 	maxStaleOffset = 7 * 24 * time.Hour
@@ -114,6 +121,9 @@ var (
 	maxElementDepth atomic.Int64
 	maxElementCount atomic.Int64
 	maxTokenLen     atomic.Int64
+	maxNamespaceLen atomic.Int64
+	maxAttrCount    atomic.Int64
+	maxLinks        atomic.Int64
 
 	// maxValueLen is the maximum length for attribute values and character data
 	// Set to 512 KiB to accommodate large KML polygons
@@ -186,6 +196,264 @@ func SetMaxTokenLen(max int64) {
 	maxTokenLen.Store(max)
 }
 
+// currentMaxAttrCount returns the maximum number of attributes per element
+func currentMaxAttrCount() int64 {
+	return maxAttrCount.Load()
+}
+
+// SetMaxAttributeCount sets the maximum number of attributes allowed on a
+// single XML element. Elements with more attributes are rejected with
+// ErrInvalidInput, bounding the work spent on attribute-flooding input.
+func SetMaxAttributeCount(max int64) {
+	if max < 0 {
+		max = 0
+	}
+	maxAttrCount.Store(max)
+}
+
+// maxFutureStart bounds how far an event's start may lead the validation
+// time, in nanoseconds.
+var maxFutureStart atomic.Int64
+
+// currentMaxFutureStart returns the allowed lead of start over now.
+func currentMaxFutureStart() time.Duration {
+	return time.Duration(maxFutureStart.Load())
+}
+
+// SetMaxFutureStart sets how far an event's start time may be ahead of the
+// current time during validation. The default of 24 hours matches the
+// window allowed for the time attribute; lowering it rejects events whose
+// start is pushed ahead by clock skew or scheduled far in advance. A value
+// of 0 rejects any future start.
+func SetMaxFutureStart(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	maxFutureStart.Store(int64(d))
+}
+
+// SetMaxLinks sets the maximum number of links an event may carry,
+// counting both event-level <link> elements and route waypoints in the
+// detail. Validation rejects events with more links with an error wrapping
+// ErrInvalidInput, bounding the work a hostile producer can cause by
+// flooding links. The default is 1000; 0 disables the check.
+func SetMaxLinks(n int) {
+	if n < 0 {
+		n = 0
+	}
+	maxLinks.Store(int64(n))
+}
+
+// currentMaxNamespaceLen returns the maximum allowed xmlns value length
+func currentMaxNamespaceLen() int64 {
+	return maxNamespaceLen.Load()
+}
+
+// SetMaxNamespaceLen sets the maximum length of the xmlns attribute value
+// accepted by UnmarshalXMLEvent. A value of 0 disables the check. The root
+// element must still fit within the token limit set by SetMaxTokenLen.
+func SetMaxNamespaceLen(max int) {
+	if max < 0 {
+		max = 0
+	}
+	maxNamespaceLen.Store(int64(max))
+}
+
+// supportedVersions holds the event versions accepted by validation.
+// A nil value accepts any numeric version.
+var supportedVersions atomic.Pointer[[]string]
+
+// SetSupportedVersions restricts the event version attribute to the given
+// values. Entries may end in ".*" to accept any minor version, for example
+// "2.*". Passing an empty list restores the default, which accepts any
+// numeric version such as "2.0".
+func SetSupportedVersions(versions []string) {
+	if len(versions) == 0 {
+		supportedVersions.Store(nil)
+		return
+	}
+	vs := append([]string(nil), versions...)
+	supportedVersions.Store(&vs)
+}
+
+// validateVersion checks the event version attribute. The version must be
+// made of dot-separated numbers and, if SetSupportedVersions was used,
+// match one of the supported versions.
+func validateVersion(version string) error {
+	for _, part := range strings.Split(version, ".") {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return fmt.Errorf("invalid version %q: %w", version, ErrInvalidInput)
+		}
+	}
+	vs := supportedVersions.Load()
+	if vs == nil {
+		return nil
+	}
+	for _, v := range *vs {
+		if v == version {
+			return nil
+		}
+		if prefix, ok := strings.CutSuffix(v, "*"); ok && strings.HasSuffix(prefix, ".") &&
+			strings.HasPrefix(version, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported version %q: %w", version, ErrInvalidInput)
+}
+
+// rejectUnknownDetails makes Detail.UnmarshalXML fail on unrecognised
+// elements instead of capturing them in Detail.Unknown.
+var rejectUnknownDetails atomic.Bool
+
+// SetRejectUnknownDetails controls how unrecognised detail elements are
+// handled during decoding. By default they are preserved in
+// Detail.Unknown; when reject is true decoding fails with ErrInvalidInput
+// as soon as one is encountered.
+func SetRejectUnknownDetails(reject bool) {
+	rejectUnknownDetails.Store(reject)
+}
+
+// preserveTimeStrings makes decoding keep the original time, start and
+// stale attribute strings so ToXML can write them back unchanged.
+var preserveTimeStrings atomic.Bool
+
+// SetPreserveTimeStrings controls whether decoded events remember their
+// time, start and stale attributes exactly as written. When enabled, ToXML
+// re-emits an original string verbatim, including fractional seconds and
+// zone offsets, for as long as the field still holds the instant it was
+// parsed from; this keeps the bytes stable for signature verification. By
+// default times are normalized to UTC in CotTimeFormat. The setting
+// applies to events decoded after the call.
+func SetPreserveTimeStrings(enabled bool) {
+	preserveTimeStrings.Store(enabled)
+}
+
+// lenientPoints makes decoding accept <point> coordinates given as child
+// elements.
+var lenientPoints atomic.Bool
+
+// SetLenientPointDecoding controls how a <point> that carries its
+// coordinates as child elements, such as <point><lat>1.5</lat>...</point>,
+// is decoded. CoT requires lat, lon, hae, ce and le attributes, so by
+// default such points are rejected with ErrInvalidInput. When enabled, the
+// child values fill in any coordinates missing from the attributes.
+func SetLenientPointDecoding(enabled bool) {
+	lenientPoints.Store(enabled)
+}
+
+// strictChatRouting makes validation check a chat's marti destinations
+// against its participants.
+var strictChatRouting atomic.Bool
+
+// SetStrictChatRouting enables a cross-check between a GeoChat's <marti>
+// destinations and its participants during detail validation. When
+// enabled, a destination uid or callsign that does not appear in the
+// chat's chatgrp, hierarchy or direct-message chatroom fails validation
+// with an error wrapping validator.ErrInvalidChat, catching chats a TAK
+// server would silently fail to deliver. It is disabled by default.
+func SetStrictChatRouting(enabled bool) {
+	strictChatRouting.Store(enabled)
+}
+
+// requireZuluTime makes decoding reject timestamps with a zone offset.
+var requireZuluTime atomic.Bool
+
+// SetRequireZuluTime controls whether decoding accepts timestamps with a
+// zone offset, such as "2024-03-01T07:00:00-05:00". They are accepted by
+// default and normalized to UTC. When enabled, as strict MITRE consumers
+// require, only UTC times written with the "Z" suffix are accepted and
+// others fail with an error wrapping ErrInvalidInput.
+func SetRequireZuluTime(enabled bool) {
+	requireZuluTime.Store(enabled)
+}
+
+// skipDetailSchemas disables schema validation of detail extensions.
+var skipDetailSchemas atomic.Bool
+
+// SetDetailSchemaValidation enables or disables XML schema validation of
+// detail extensions (including chat and chat receipts) during event
+// validation. It is enabled by default. Disabling it speeds up validation
+// considerably but lets malformed or malicious detail content through to
+// consumers, so it should only be used when the upstream source is
+// trusted. Field, time and point checks are unaffected.
+func SetDetailSchemaValidation(enabled bool) {
+	skipDetailSchemas.Store(!enabled)
+}
+
+// requireUUID makes validation require RFC 4122 UUID event uids.
+var requireUUID atomic.Bool
+
+// uuidPrefixes holds the prefixes allowed before a UUID uid.
+var uuidPrefixes atomic.Pointer[[]string]
+
+// SetRequireUUID makes event validation require the uid to be an RFC 4122
+// UUID, optionally preceded by one of the given prefixes such as
+// "ANDROID-", in addition to the ValidateUID rules. It is disabled by
+// default. Each call replaces the previously configured prefixes.
+func SetRequireUUID(require bool, prefixes ...string) {
+	ps := append([]string(nil), prefixes...)
+	uuidPrefixes.Store(&ps)
+	requireUUID.Store(require)
+}
+
+// normalizeCoordinates makes UnmarshalXMLEvent apply NormalizeLatLon to
+// the event point before validation.
+var normalizeCoordinates atomic.Bool
+
+// SetNormalizeCoordinates controls whether UnmarshalXMLEvent repairs
+// out-of-range coordinates with NormalizeLatLon before validating the
+// event. It is disabled by default, in which case such events are rejected
+// with ErrInvalidLatitude or ErrInvalidLongitude.
+func SetNormalizeCoordinates(enabled bool) {
+	normalizeCoordinates.Store(enabled)
+}
+
+// defaultEventAttrOrder is the order in which ToXML writes the attributes
+// of the <event> element unless SetEventAttributeOrder changes it.
+var defaultEventAttrOrder = []string{"version", "type", "how", "uid", "time", "start", "stale", "strokeColor", "usericon", "caveat", "releasableTo"}
+
+// eventAttrOrder holds the complete attribute order used by ToXML.
+var eventAttrOrder atomic.Pointer[[]string]
+
+func init() {
+	eventAttrOrder.Store(&defaultEventAttrOrder)
+}
+
+// SetEventAttributeOrder changes the order in which ToXML and EventWriter
+// write the attributes of the <event> element, for peers whose validators
+// depend on attribute order. order lists attribute names such as "uid" or
+// "time"; attributes it leaves out follow in the default order (version,
+// type, how, uid, time, start, stale, strokeColor, usericon, caveat,
+// releasableTo), and unknown attributes preserved from decoding always come
+// last. Unrecognised or repeated names are rejected with ErrInvalidInput
+// and leave the current order unchanged. An empty order restores the
+// default.
+func SetEventAttributeOrder(order []string) error {
+	seen := make(map[string]bool, len(defaultEventAttrOrder))
+	for _, name := range defaultEventAttrOrder {
+		seen[name] = false
+	}
+	full := make([]string, 0, len(defaultEventAttrOrder))
+	for _, name := range order {
+		used, ok := seen[name]
+		if !ok {
+			return fmt.Errorf("unknown event attribute %q: %w", name, ErrInvalidInput)
+		}
+		if used {
+			return fmt.Errorf("duplicate event attribute %q: %w", name, ErrInvalidInput)
+		}
+		seen[name] = true
+		full = append(full, name)
+	}
+	for _, name := range defaultEventAttrOrder {
+		if !seen[name] {
+			full = append(full, name)
+		}
+	}
+	eventAttrOrder.Store(&full)
+	return nil
+}
+
 // attrEscaper escapes XML special characters in attribute values.
 // It also encodes carriage returns, newlines, and tabs using
 // their numeric character references.
@@ -302,10 +570,7 @@ func RegisterCoTTypesFromFile(ctx context.Context, filename string) error {
 		} `xml:"cot"`
 	}
 
-	dec := xml.NewDecoder(bytes.NewReader(data))
-	dec.CharsetReader = nil
-	dec.Entity = nil
-	if err := decodeWithLimits(dec, &types); err != nil {
+	if err := cottypes.DecodeXML(data, &types); err != nil {
 		logger.Error("failed to decode XML",
 			"path", filename,
 			"error", err)
@@ -329,6 +594,65 @@ func RegisterCoTTypesFromFile(ctx context.Context, filename string) error {
 	return nil
 }
 
+// ValidateCoTTypesFile checks a CoTtypes.xml style file without registering
+// anything. Every cot attribute is checked the way RegisterCoTTypesFromFile
+// would accept it and with ValidateType; types that are merely absent from
+// the catalog are counted as valid, since custom files exist to add them.
+// It returns the number of valid entries and one error per bad entry, or a
+// single error if the file cannot be read or parsed.
+func ValidateCoTTypesFile(path string) (valid int, errs []error) {
+	clean := filepath.Clean(path)
+	if strings.Contains(clean, "..") {
+		return 0, []error{fmt.Errorf("invalid path %q: %w", path, ErrInvalidInput)}
+	}
+
+	data, err := os.ReadFile(clean)
+	if err != nil {
+		return 0, []error{err}
+	}
+
+	if doctypePattern.Match(data) {
+		return 0, []error{fmt.Errorf("invalid doctype in %q: %w", path, ErrInvalidInput)}
+	}
+
+	var types struct {
+		XMLName xml.Name `xml:"types"`
+		CoTs    []struct {
+			Type string `xml:"cot,attr"`
+		} `xml:"cot"`
+	}
+
+	if err := cottypes.DecodeXML(data, &types); err != nil {
+		return 0, []error{err}
+	}
+
+	for i, t := range types.CoTs {
+		if err := validateCoTTypeEntry(t.Type); err != nil {
+			errs = append(errs, fmt.Errorf("cot entry %d: %w", i+1, err))
+			continue
+		}
+		valid++
+	}
+	return valid, errs
+}
+
+// validateCoTTypeEntry checks a single cot attribute from a types file.
+func validateCoTTypeEntry(typ string) error {
+	if typ == "" {
+		return fmt.Errorf("missing cot attribute: %w", ErrMalformedType)
+	}
+	if !basicSyntaxOK(typ) {
+		if err := typeSyntaxError(typ); err != nil {
+			return err
+		}
+		return fmt.Errorf("type %q: %w", typ, ErrMalformedType)
+	}
+	if err := ValidateType(typ); err != nil && !errors.Is(err, ErrTypeNotInCatalog) {
+		return err
+	}
+	return nil
+}
+
 // RegisterCoTTypesFromReader loads and registers CoT types from an XML reader
 func RegisterCoTTypesFromReader(ctx context.Context, r io.Reader) error {
 	logger := LoggerFromContext(ctx)
@@ -351,10 +675,7 @@ func RegisterCoTTypesFromReader(ctx context.Context, r io.Reader) error {
 		} `xml:"cot"`
 	}
 
-	dec := xml.NewDecoder(bytes.NewReader(data))
-	dec.CharsetReader = nil
-	dec.Entity = nil
-	if err := decodeWithLimits(dec, &types); err != nil {
+	if err := cottypes.DecodeXML(data, &types); err != nil {
 		logger.Error("failed to decode XML from reader",
 			"error", err)
 		return err
@@ -395,10 +716,7 @@ func RegisterCoTTypesFromXMLContent(ctx context.Context, xmlContent string) erro
 		} `xml:"cot"`
 	}
 
-	dec := xml.NewDecoder(bytes.NewReader(data))
-	dec.CharsetReader = nil
-	dec.Entity = nil
-	if err := decodeWithLimits(dec, &types); err != nil {
+	if err := cottypes.DecodeXML(data, &types); err != nil {
 		logger.Error("failed to decode XML content",
 			"error", err)
 		return err
@@ -452,10 +770,7 @@ func LoadCoTTypesFromFile(ctx context.Context, path string) error {
 	var types struct {
 		Types []string `xml:"type"`
 	}
-	dec := xml.NewDecoder(bytes.NewReader(data))
-	dec.CharsetReader = nil
-	dec.Entity = nil
-	if err := decodeWithLimits(dec, &types); err != nil {
+	if err := cottypes.DecodeXML(data, &types); err != nil {
 		logger.Error("failed to parse XML",
 			"path", path,
 			"error", err)
@@ -529,7 +844,11 @@ func parseCoTTime(value string) (time.Time, error) {
 		return t, nil
 	}
 
-	return time.Parse(time.RFC3339Nano, value)
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
 }
 
 // CoTTime represents a time in CoT format (UTC without timezone offset)
@@ -551,12 +870,7 @@ func (t *CoTTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	if err := d.DecodeElement(&s, &start); err != nil {
 		return err
 	}
-	parsed, err := parseCoTTime(s)
-	if err != nil {
-		return fmt.Errorf("invalid time format: %w", err)
-	}
-	*t = CoTTime(parsed)
-	return nil
+	return t.decode(s)
 }
 
 // MarshalXMLAttr implements xml.MarshalerAttr
@@ -569,7 +883,15 @@ func (t CoTTime) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
 
 // UnmarshalXMLAttr implements xml.UnmarshalerAttr
 func (t *CoTTime) UnmarshalXMLAttr(attr xml.Attr) error {
-	parsed, err := parseCoTTime(attr.Value)
+	return t.decode(attr.Value)
+}
+
+// decode parses value into t, enforcing SetRequireZuluTime.
+func (t *CoTTime) decode(value string) error {
+	if requireZuluTime.Load() && !strings.HasSuffix(value, "Z") {
+		return fmt.Errorf("invalid time format: %q is not a UTC (Zulu) time: %w", value, ErrInvalidInput)
+	}
+	parsed, err := parseCoTTime(value)
 	if err != nil {
 		return fmt.Errorf("invalid time format: %w", err)
 	}
@@ -577,6 +899,13 @@ func (t *CoTTime) UnmarshalXMLAttr(attr xml.Attr) error {
 	return nil
 }
 
+// IsZulu reports whether t is in UTC. Decoded times are always normalized
+// to UTC, so this only reports false for times set in code with another
+// location.
+func (t CoTTime) IsZulu() bool {
+	return t.Time().Location() == time.UTC
+}
+
 // Point represents a location in 3D space with error estimates
 type Point struct {
 	Lat float64 `xml:"lat,attr"` // Latitude in degrees
@@ -616,6 +945,86 @@ func (p *Point) Validate() error {
 	return nil
 }
 
+// decodePoint decodes the <point> element start into p. Coordinates given
+// as child elements are rejected unless SetLenientPointDecoding is enabled,
+// in which case they are used for any attribute the element lacks.
+func decodePoint(dec *xml.Decoder, start xml.StartElement, p *Point) error {
+	var elem struct {
+		Point
+		ChildLat *float64 `xml:"lat"`
+		ChildLon *float64 `xml:"lon"`
+		ChildHae *float64 `xml:"hae"`
+		ChildCe  *float64 `xml:"ce"`
+		ChildLe  *float64 `xml:"le"`
+	}
+	if err := dec.DecodeElement(&elem, &start); err != nil {
+		return err
+	}
+	*p = elem.Point
+	children := []struct {
+		name  string
+		value *float64
+		dst   *float64
+	}{
+		{"lat", elem.ChildLat, &p.Lat},
+		{"lon", elem.ChildLon, &p.Lon},
+		{"hae", elem.ChildHae, &p.Hae},
+		{"ce", elem.ChildCe, &p.Ce},
+		{"le", elem.ChildLe, &p.Le},
+	}
+	for _, c := range children {
+		if c.value == nil {
+			continue
+		}
+		if !lenientPoints.Load() {
+			return fmt.Errorf("point %s given as a child element instead of an attribute: %w", c.name, ErrInvalidInput)
+		}
+		if !hasAttr(start, c.name) {
+			*c.dst = *c.value
+		}
+	}
+	return nil
+}
+
+// checkFragment reports an error wrapping ErrInvalidInput if s is not
+// well-formed XML content, such as unbalanced or mismatched tags.
+func checkFragment(s string) error {
+	dec := cottypes.NewSecureDecoder(strings.NewReader("<x>" + s + "</x>"))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, ErrInvalidInput)
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			if depth == 0 && dec.InputOffset() > int64(len("<x>")) {
+				return fmt.Errorf("unbalanced end tag: %w", ErrInvalidInput)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// hasAttr reports whether start carries an attribute with the local name.
+func hasAttr(start xml.StartElement, name string) bool {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownPoint is the sentinel written for events without a position:
+// 0,0 with the maximum circular and linear error.
+var unknownPoint = Point{Ce: 9999999.0, Le: 9999999.0}
+
 // Event represents a CoT event message
 type Event struct {
 	XMLName xml.Name `xml:"event"`
@@ -638,6 +1047,25 @@ type Event struct {
 	StrokeColor string `xml:"strokeColor,attr,omitempty"`
 	// UserIcon specifies a custom icon URL or resource for the event.
 	UserIcon string `xml:"usericon,attr,omitempty"`
+	// Caveat is the classification caveat stamped on the event by
+	// classified deployments, such as "FOUO".
+	Caveat string `xml:"caveat,attr,omitempty"`
+	// ReleasableTo lists the nations or organisations the event may be
+	// released to, such as "USA GBR".
+	ReleasableTo string `xml:"releasableTo,attr,omitempty"`
+	// NoPoint is set when a decoded event had no <point> element, as in
+	// some tasking and reply messages. Point checks are skipped during
+	// validation and ToXML writes an unknown-position sentinel point.
+	// UpdatePosition clears it; code assigning Point directly must clear
+	// it as well.
+	NoPoint bool `xml:"-"`
+
+	// released is set when the event has been returned to the pool.
+	released bool
+
+	// origTime, origStart and origStale hold the time attributes as they
+	// were decoded when SetPreserveTimeStrings is enabled.
+	origTime, origStart, origStale string
 }
 
 // Error sentinels for validation
@@ -652,16 +1080,194 @@ var (
 	ErrInvalidHow = fmt.Errorf("invalid how")
 	// ErrInvalidRelation is returned when a relation value is not recognised.
 	ErrInvalidRelation = fmt.Errorf("invalid relation")
+	// ErrInvalidContact is returned when a contact phone number or email
+	// address is malformed.
+	ErrInvalidContact = fmt.Errorf("invalid contact")
+)
+
+// Reasons a CoT type fails ValidateType. Each wraps ErrInvalidType.
+var (
+	// ErrMalformedType is returned when a type is not a dash-separated
+	// sequence of alphanumeric segments.
+	ErrMalformedType = fmt.Errorf("malformed type: %w", ErrInvalidType)
+	// ErrUnknownPrefix is returned when the first segment of a type is not
+	// a known CoT root such as "a" (atom) or "b" (bits).
+	ErrUnknownPrefix = fmt.Errorf("unknown type prefix: %w", ErrInvalidType)
+	// ErrTypeNotInCatalog is returned when a well-formed type is not
+	// registered in the catalog.
+	ErrTypeNotInCatalog = fmt.Errorf("type not in catalog: %w", ErrInvalidType)
 )
 
+// typeRoots lists the first segments of the CoT type tree.
+var typeRoots = map[string]bool{
+	"a": true, // atoms
+	"b": true, // bits
+	"c": true, // capability
+	"r": true, // reservation
+	"t": true, // tasking
+	"u": true, // drawings
+	"y": true, // replies
+}
+
+// typeSyntaxError reports why typ is not a well-formed CoT type, or nil if
+// its segments are non-empty, alphanumeric (or ".") and its root is known.
+func typeSyntaxError(typ string) error {
+	parts := strings.Split(typ, "-")
+	if len(parts) < 2 {
+		return fmt.Errorf("type %q has no segments: %w", typ, ErrMalformedType)
+	}
+	for _, p := range parts {
+		if p == "" {
+			return fmt.Errorf("type %q has an empty segment: %w", typ, ErrMalformedType)
+		}
+		for _, c := range p {
+			if c != '.' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+				return fmt.Errorf("type %q contains %q: %w", typ, c, ErrMalformedType)
+			}
+		}
+	}
+	if !typeRoots[parts[0]] {
+		return fmt.Errorf("type %q: %w", typ, ErrUnknownPrefix)
+	}
+	return nil
+}
+
+// DecodeError reports a failure to decode XML together with the byte
+// offset in the input at which the decoder stopped.
+type DecodeError struct {
+	Offset int64 // Input offset reported by the decoder
+	Err    error // Underlying decode error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode XML at offset %d: %v", e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying decode error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
 // doctypePattern matches XML DOCTYPE declarations case-insensitively
 var doctypePattern = regexp.MustCompile(`(?i)<!\s*DOCTYPE`)
 
 // Contact represents contact information
 type Contact struct {
-	XMLName  xml.Name `xml:"contact"`
-	Callsign string   `xml:"callsign,attr,omitempty"`
-	Endpoint string   `xml:"endpoint,attr,omitempty"` // Chat routing endpoint (e.g., "*:-1:stcp")
+	XMLName      xml.Name `xml:"contact"`
+	Callsign     string   `xml:"callsign,attr,omitempty"`
+	Endpoint     string   `xml:"endpoint,attr,omitempty"` // Chat routing endpoint (e.g., "*:-1:stcp")
+	Phone        string   `xml:"phone,attr,omitempty"`
+	EmailAddress string   `xml:"emailAddress,attr,omitempty"`
+}
+
+// EndpointAddr parses the contact's endpoint with ParseEndpoint.
+func (c *Contact) EndpointAddr() (host string, port int, proto string, err error) {
+	if c == nil {
+		return "", 0, "", fmt.Errorf("nil contact: %w", ErrInvalidInput)
+	}
+	return ParseEndpoint(c.Endpoint)
+}
+
+// ParseEndpoint splits a TAK endpoint of the form host:port:proto, such as
+// "192.168.1.5:4242:tcp" or "*:-1:stcp". The host may be "*" for any
+// address and the port -1 when the peer is reached over the server
+// connection rather than directly; otherwise the port must be in 0-65535.
+// IPv6 hosts may be bracketed and are returned without brackets. Malformed
+// endpoints are rejected with an error wrapping ErrInvalidInput.
+func ParseEndpoint(s string) (host string, port int, proto string, err error) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return "", 0, "", fmt.Errorf("endpoint %q is not host:port:proto: %w", s, ErrInvalidInput)
+	}
+	proto = s[i+1:]
+	j := strings.LastIndexByte(s[:i], ':')
+	if j < 0 {
+		return "", 0, "", fmt.Errorf("endpoint %q is not host:port:proto: %w", s, ErrInvalidInput)
+	}
+	host = s[:j]
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	if host == "" || strings.ContainsAny(host, " \t\r\n[]") {
+		return "", 0, "", fmt.Errorf("endpoint %q has an invalid host: %w", s, ErrInvalidInput)
+	}
+	port, perr := strconv.Atoi(s[j+1 : i])
+	if perr != nil || port < -1 || port > 65535 {
+		return "", 0, "", fmt.Errorf("endpoint %q has an invalid port: %w", s, ErrInvalidInput)
+	}
+	if proto == "" {
+		return "", 0, "", fmt.Errorf("endpoint %q has no protocol: %w", s, ErrInvalidInput)
+	}
+	for _, r := range proto {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return "", 0, "", fmt.Errorf("endpoint %q has an invalid protocol: %w", s, ErrInvalidInput)
+		}
+	}
+	return host, port, proto, nil
+}
+
+// Normalize trims surrounding whitespace from the phone number and email
+// address and removes spaces, dashes, dots and parentheses from the phone
+// number, leaving only digits and an optional leading plus.
+func (c *Contact) Normalize() {
+	if c == nil {
+		return
+	}
+	c.EmailAddress = strings.TrimSpace(c.EmailAddress)
+	c.Phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(c.Phone))
+}
+
+// Validate checks that the phone number, if set, holds only digits, dashes
+// and an optional leading plus, and that the email address, if set, has the
+// form local@domain. Errors wrap ErrInvalidContact.
+func (c *Contact) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Phone != "" && !validPhone(c.Phone) {
+		return fmt.Errorf("phone %q: %w", c.Phone, ErrInvalidContact)
+	}
+	if c.EmailAddress != "" && !validEmail(c.EmailAddress) {
+		return fmt.Errorf("email address %q: %w", c.EmailAddress, ErrInvalidContact)
+	}
+	return nil
+}
+
+// validPhone reports whether s is an optional "+" followed by digits and
+// dashes, with at least one digit.
+func validPhone(s string) bool {
+	s = strings.TrimPrefix(s, "+")
+	digits := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '-':
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+// validEmail reports whether s looks like local@domain, where the domain
+// contains a dot that is neither its first nor last character. It is a
+// sanity check rather than a full RFC 5322 parser.
+func validEmail(s string) bool {
+	at := strings.IndexByte(s, '@')
+	if at <= 0 || strings.IndexByte(s[at+1:], '@') >= 0 || strings.ContainsAny(s, " \t\r\n<>\"") {
+		return false
+	}
+	domain := s[at+1:]
+	dot := strings.LastIndexByte(domain, '.')
+	return dot > 0 && dot < len(domain)-1 && !strings.HasPrefix(domain, ".")
 }
 
 // Detail contains additional information about an event
@@ -699,10 +1305,17 @@ type Detail struct {
 	UserIcon          *UserIcon          `xml:"usericon,omitempty"`
 	UID               *UID               `xml:"uid,omitempty"`
 	Bullseye          *Bullseye          `xml:"bullseye,omitempty"`
+	Medevac           *Medevac           `xml:"_medevac_,omitempty"`
 	RouteInfo         *RouteInfo         `xml:"routeInfo,omitempty"`
 	Marti             *Marti             `xml:"marti,omitempty"`
 	Remarks           *Remarks           `xml:"remarks,omitempty"`
+	Signature         *Signature         `xml:"signature,omitempty"`
+	Radio             *Radio             `xml:"_radio_,omitempty"`
+	UASTelemetry      *UASTelemetry      `xml:"_uastool_,omitempty"`
 	Unknown           []RawMessage       `xml:"-"`
+
+	// elements counts the child elements seen by UnmarshalXML.
+	elements int
 }
 
 // Group represents a group affiliation
@@ -716,12 +1329,79 @@ type Link struct {
 	Uid      string `xml:"uid,attr"`
 	Type     string `xml:"type,attr"`
 	Relation string `xml:"relation,attr"`
+	// Children holds the raw XML content of the link, such as the KML
+	// <Style> of a styled drawing link. It is written back verbatim and
+	// must be well-formed; it is empty for the usual attribute-only link.
+	Children string `xml:",innerxml"`
+}
+
+// linkChildren reads the content of a <link> element whose start tag has
+// been consumed, up to and including its end tag, and returns it as XML.
+// It returns "" without encoding anything when the link holds nothing but
+// whitespace, as almost all links do.
+func linkChildren(dec *xml.Decoder) (string, error) {
+	var buf bytes.Buffer
+	var enc *xml.Encoder
+	var pending xml.CharData
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				if enc == nil {
+					return "", nil
+				}
+				if err := enc.Flush(); err != nil {
+					return "", err
+				}
+				return buf.String(), nil
+			}
+			depth--
+		case xml.CharData:
+			if enc == nil && len(bytes.TrimSpace(t)) == 0 {
+				pending = append(pending, t...)
+				continue
+			}
+		}
+		if enc == nil {
+			enc = xml.NewEncoder(&buf)
+			if len(pending) > 0 {
+				if err := enc.EncodeToken(pending); err != nil {
+					return "", err
+				}
+			}
+		}
+		if err := enc.EncodeToken(tok); err != nil {
+			return "", err
+		}
+	}
+}
+
+// canonicalChildren returns link content s re-encoded the way linkChildren
+// decodes it, so a link built in code and a decoded copy of it produce the
+// same canonical bytes. s is returned unchanged if it is not well-formed.
+func canonicalChildren(s string) string {
+	dec := cottypes.NewSecureDecoder(strings.NewReader("<link>" + s + "</link>"))
+	if _, err := dec.Token(); err != nil {
+		return s
+	}
+	out, err := linkChildren(dec)
+	if err != nil {
+		return s
+	}
+	return out
 }
 
 // UnmarshalXML implements xml.Unmarshaler for Event. It preserves any
 // attributes that are not recognised by storing them in UnknownAttrs.
 func (e *Event) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
-	*e = Event{}
+	*e = Event{NoPoint: true}
 
 	for _, a := range start.Attr {
 		switch a.Name.Local {
@@ -737,18 +1417,31 @@ func (e *Event) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 			if err := e.Time.UnmarshalXMLAttr(a); err != nil {
 				return err
 			}
+			if preserveTimeStrings.Load() {
+				e.origTime = a.Value
+			}
 		case "start":
 			if err := e.Start.UnmarshalXMLAttr(a); err != nil {
 				return err
 			}
+			if preserveTimeStrings.Load() {
+				e.origStart = a.Value
+			}
 		case "stale":
 			if err := e.Stale.UnmarshalXMLAttr(a); err != nil {
 				return err
 			}
+			if preserveTimeStrings.Load() {
+				e.origStale = a.Value
+			}
 		case "strokeColor":
 			e.StrokeColor = a.Value
 		case "usericon":
 			e.UserIcon = a.Value
+		case "caveat":
+			e.Caveat = a.Value
+		case "releasableTo":
+			e.ReleasableTo = a.Value
 		default:
 			e.UnknownAttrs = append(e.UnknownAttrs, a)
 		}
@@ -766,9 +1459,10 @@ func (e *Event) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 		case xml.StartElement:
 			switch t.Name.Local {
 			case "point":
-				if err := dec.DecodeElement(&e.Point, &t); err != nil {
+				if err := decodePoint(dec, t, &e.Point); err != nil {
 					return err
 				}
+				e.NoPoint = false
 			case "detail":
 				var d Detail
 				if err := dec.DecodeElement(&d, &t); err != nil {
@@ -776,10 +1470,25 @@ func (e *Event) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 				}
 				e.Detail = &d
 			case "link":
+				// Links are decoded by hand because innerxml is only
+				// filled when decoding from bytes, not from the token
+				// stream of the hardened decoder.
 				var l Link
-				if err := dec.DecodeElement(&l, &t); err != nil {
+				for _, a := range t.Attr {
+					switch a.Name.Local {
+					case "uid":
+						l.Uid = a.Value
+					case "type":
+						l.Type = a.Value
+					case "relation":
+						l.Relation = a.Value
+					}
+				}
+				children, err := linkChildren(dec)
+				if err != nil {
 					return err
 				}
+				l.Children = children
 				e.Links = append(e.Links, l)
 			default:
 				if err := dec.Skip(); err != nil {
@@ -809,6 +1518,7 @@ func (d *Detail) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 		}
 		switch t := tok.(type) {
 		case xml.StartElement:
+			d.elements++
 			switch t.Name.Local {
 			case "group":
 				var g Group
@@ -870,7 +1580,7 @@ func (d *Detail) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 					return err
 				}
 				d.Archive = &a
-			case "attachmentList":
+			case "attachmentList", "attachment_list":
 				var al AttachmentList
 				if err := dec.DecodeElement(&al, &t); err != nil {
 					return err
@@ -1020,6 +1730,12 @@ func (d *Detail) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 					return err
 				}
 				d.Bullseye = &b
+			case "_medevac_":
+				var m Medevac
+				if err := dec.DecodeElement(&m, &t); err != nil {
+					return err
+				}
+				d.Medevac = &m
 			case "routeInfo":
 				var ri RouteInfo
 				if err := dec.DecodeElement(&ri, &t); err != nil {
@@ -1038,15 +1754,45 @@ func (d *Detail) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 					return err
 				}
 				d.Remarks = &r
+			case "signature":
+				if !isHMACSignature(t) {
+					if err := d.decodeUnknown(dec, t); err != nil {
+						return err
+					}
+					continue
+				}
+				var sig Signature
+				if err := dec.DecodeElement(&sig, &t); err != nil {
+					return err
+				}
+				d.Signature = &sig
+			case "_radio_", "sensor":
+				// Only the first radio element is typed; later ones,
+				// and sensors without a frequency, stay in Unknown.
+				if d.Radio != nil || (t.Name.Local == "sensor" && !isRadioSensor(t)) {
+					if err := d.decodeUnknown(dec, t); err != nil {
+						return err
+					}
+					continue
+				}
+				var r Radio
+				if err := dec.DecodeElement(&r, &t); err != nil {
+					return err
+				}
+				d.Radio = &r
+			case "_uastool_":
+				var u UASTelemetry
+				if err := dec.DecodeElement(&u, &t); err != nil {
+					return err
+				}
+				d.UASTelemetry = &u
 			default:
 				if strings.EqualFold(t.Name.Local, "remarks") {
 					return fmt.Errorf("unexpected element %s", t.Name.Local)
 				}
-				raw, err := captureRaw(dec, t)
-				if err != nil {
+				if err := d.decodeUnknown(dec, t); err != nil {
 					return err
 				}
-				d.Unknown = append(d.Unknown, raw)
 			}
 		case xml.EndElement:
 			if t.Name == start.Name {
@@ -1057,14 +1803,37 @@ func (d *Detail) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
-// MarshalXML implements xml.Marshaler for Detail.
-func (d *Detail) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
-	if err := enc.EncodeToken(start); err != nil {
+// decodeUnknown captures an element without a typed field into Unknown,
+// passing it through any registered detail hook first.
+func (d *Detail) decodeUnknown(dec *xml.Decoder, t xml.StartElement) error {
+	hook := detailHook(t.Name.Local)
+	if hook == nil && rejectUnknownDetails.Load() {
+		return fmt.Errorf("unknown detail element %s: %w", t.Name.Local, ErrInvalidInput)
+	}
+	raw, err := captureRaw(dec, t)
+	if err != nil {
 		return err
 	}
-	if d.Contact != nil {
-		if err := enc.Encode(d.Contact); err != nil {
-			return err
+	if hook != nil {
+		if raw, err = hook(raw); err != nil {
+			return fmt.Errorf("detail hook %s: %w", t.Name.Local, err)
+		}
+		if len(raw) == 0 {
+			return nil
+		}
+	}
+	d.Unknown = append(d.Unknown, raw)
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler for Detail.
+func (d *Detail) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if d.Contact != nil {
+		if err := enc.Encode(d.Contact); err != nil {
+			return err
 		}
 	}
 	if d.Group != nil {
@@ -1183,7 +1952,7 @@ func (d *Detail) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 		}
 	}
 	if d.LabelsOn != nil {
-		if err := encodeRaw(enc, d.LabelsOn.Raw); err != nil {
+		if err := enc.Encode(d.LabelsOn); err != nil {
 			return err
 		}
 	}
@@ -1223,7 +1992,12 @@ func (d *Detail) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 		}
 	}
 	if d.Bullseye != nil {
-		if err := encodeRaw(enc, d.Bullseye.Raw); err != nil {
+		if err := enc.Encode(d.Bullseye); err != nil {
+			return err
+		}
+	}
+	if d.Medevac != nil {
+		if err := enc.Encode(d.Medevac); err != nil {
 			return err
 		}
 	}
@@ -1238,9 +2012,7 @@ func (d *Detail) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 		}
 	}
 	if d.Remarks != nil {
-		if len(d.Remarks.Raw) > 0 && d.Remarks.Text == "" &&
-			d.Remarks.Source == "" && d.Remarks.SourceID == "" &&
-			d.Remarks.To == "" && d.Remarks.Time.Time().IsZero() {
+		if d.Remarks.rawOnly() {
 			if err := encodeRaw(enc, d.Remarks.Raw); err != nil {
 				return err
 			}
@@ -1250,6 +2022,21 @@ func (d *Detail) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 			}
 		}
 	}
+	if d.Radio != nil {
+		if err := enc.Encode(d.Radio); err != nil {
+			return err
+		}
+	}
+	if d.UASTelemetry != nil {
+		if err := enc.Encode(d.UASTelemetry); err != nil {
+			return err
+		}
+	}
+	if d.Signature != nil {
+		if err := enc.Encode(d.Signature); err != nil {
+			return err
+		}
+	}
 	for _, raw := range d.Unknown {
 		if err := encodeRaw(enc, raw); err != nil {
 			return err
@@ -1269,7 +2056,7 @@ func NewEvent(uid, typ string, lat, lon, hae float64) (*Event, error) {
 		How:     "m-g",
 		Time:    CoTTime(now),
 		Start:   CoTTime(now),
-		Stale:   CoTTime(now.Add(6 * time.Second)),
+		Stale:   CoTTime(now.Add(defaultStaleOffset)),
 		Point: Point{
 			Lat: lat,
 			Lon: lon,
@@ -1290,41 +2077,312 @@ func NewPresenceEvent(uid string, lat, lon, hae float64) (*Event, error) {
 	return NewEvent(uid, "t-x-takp-v", lat, lon, hae)
 }
 
+// NewDeleteEvent creates a t-x-d-d tasking that instructs TAK clients to
+// remove the marker identified by uid. The marker is referenced only
+// through a detail link; the tasking itself has the uid "<uid>-t-x-d-d" so
+// receivers do not mistake it for an update of the marker. It carries the
+// __forcedelete flag, its time is the current time and it goes stale after
+// the minimum allowed offset.
+func NewDeleteEvent(uid string) (*Event, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	evt := getEvent()
+	*evt = Event{
+		Version: "2.0",
+		Uid:     uid + "-t-x-d-d",
+		Type:    "t-x-d-d",
+		How:     "h-g-i-g-o",
+		Time:    CoTTime(now),
+		Start:   CoTTime(now),
+		Stale:   CoTTime(now.Add(minStaleOffset)),
+		Point: Point{
+			Ce: 9999999.0,
+			Le: 9999999.0,
+		},
+	}
+	var link bytes.Buffer
+	link.WriteString(`<link uid="`)
+	xml.EscapeText(&link, []byte(uid))
+	link.WriteString(`" relation="none" type="none"/>`)
+	evt.Detail = &Detail{
+		LinkDetail: &DetailLink{Raw: link.Bytes()},
+		Unknown:    []RawMessage{RawMessage(`<__forcedelete/>`)},
+	}
+	if err := evt.ValidateAt(now); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	return evt, nil
+}
+
+// NewTaskingEvent creates a tasking event of type taskType, such as "t-k"
+// (strike) or "t-s" (ISR), directed at the entity targetUID of type
+// targetType. The target is referenced by a link with relation "t-o"
+// (object of tasking). taskType must be a tasking type registered in the
+// catalog; other types, including the TAK control messages under "t-x-",
+// are rejected with an error wrapping ErrInvalidType. The event carries no
+// position (NoPoint is set) and is validated before it is returned.
+func NewTaskingEvent(uid, taskType, targetUID, targetType string) (*Event, error) {
+	if !isTaskingType(taskType) {
+		return nil, fmt.Errorf("%q is not a tasking type: %w", taskType, ErrInvalidType)
+	}
+	if !isRegisteredType(taskType) {
+		return nil, fmt.Errorf("unknown tasking type %s: %w", taskType, ErrTypeNotInCatalog)
+	}
+	if err := ValidateUID(targetUID); err != nil {
+		return nil, fmt.Errorf("invalid target uid %q: %w", targetUID, err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	evt := getEvent()
+	*evt = Event{
+		Version: "2.0",
+		Uid:     uid,
+		Type:    taskType,
+		How:     "h-e",
+		Time:    CoTTime(now),
+		Start:   CoTTime(now),
+		Stale:   CoTTime(now.Add(defaultStaleOffset)),
+		Point:   unknownPoint,
+		NoPoint: true,
+	}
+	if err := evt.AddValidatedLink(targetUID, targetType, "t-o"); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	if err := evt.ValidateAt(now); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	return evt, nil
+}
+
+// NewMinimalEvent creates the smallest event of type typ that passes
+// validation, for use in tests and as a template. The event has the uid
+// "minimal-" followed by the type, how "m-g", an unknown-position point,
+// and a time and start of now with the default stale offset. Types whose
+// messages need a detail get the minimum valid one: GeoChat (b-t-f) a
+// __chat, emergencies (b-e-r, b-e-a, b-e-s) an <emergency>, and file
+// transfers (b-t-b, b-t-i, b-t-v, b-t-a, b-t-d) a <fileshare>. Types not
+// in the catalog are rejected with an error wrapping ErrTypeNotInCatalog,
+// and wildcard patterns with one wrapping ErrInvalidType.
+//
+// The returned Event is obtained from the internal pool and may be
+// released with ReleaseEvent.
+func NewMinimalEvent(typ string) (*Event, error) {
+	if strings.Contains(typ, "*") {
+		return nil, fmt.Errorf("wildcard type %q: %w", typ, ErrInvalidType)
+	}
+	if err := ValidateType(typ); err != nil {
+		return nil, err
+	}
+	uid := "minimal-" + typ
+	now := time.Now().UTC().Truncate(time.Second)
+	evt := getEvent()
+	*evt = Event{
+		Version: "2.0",
+		Uid:     uid,
+		Type:    typ,
+		How:     "m-g",
+		Time:    CoTTime(now),
+		Start:   CoTTime(now),
+		Stale:   CoTTime(now.Add(defaultStaleOffset)),
+		Point:   unknownPoint,
+	}
+	switch typ {
+	case "b-t-f":
+		evt.Detail = &Detail{Chat: &Chat{ID: allChatRooms, Message: "message", Sender: uid}}
+	case "b-e-r", "b-e-a", "b-e-s":
+		evt.Detail = &Detail{Emergency: &Emergency{
+			Raw: RawMessage(`<emergency type="911 Alert">` + uid + `</emergency>`),
+		}}
+	case "b-t-b", "b-t-i", "b-t-v", "b-t-a", "b-t-d":
+		evt.Detail = &Detail{FileShare: &FileShare{
+			Raw: RawMessage(`<fileshare filename="file" name="file" senderCallsign="` + uid +
+				`" senderUid="` + uid + `" senderUrl="http://localhost/file" sha256="` +
+				strings.Repeat("0", 64) + `" sizeInBytes="0"/>`),
+		}}
+	}
+	if err := evt.ValidateAt(now); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	return evt, nil
+}
+
+// cloneEvent returns a pooled copy of e whose links, unknown attributes and
+// detail can be changed without affecting e. Detail extensions themselves
+// are shared, so callers replace them rather than modifying them in place.
+func cloneEvent(e *Event) *Event {
+	out := getEvent()
+	*out = *e
+	out.released = false
+	out.Links = append([]Link(nil), e.Links...)
+	out.UnknownAttrs = append([]xml.Attr(nil), e.UnknownAttrs...)
+	if e.Detail != nil {
+		d := *e.Detail
+		d.RouteLinks = append([]RouteLink(nil), e.Detail.RouteLinks...)
+		d.Unknown = append([]RawMessage(nil), e.Detail.Unknown...)
+		out.Detail = &d
+	}
+	return out
+}
+
+// AsDelete returns a copy of the event that expires immediately, which
+// TAK clients treat as a request to remove the marker. The time and start
+// are set to the current time and stale to the minimum allowed offset
+// after it. Links, unknown attributes and the detail are copied; the
+// extension values referenced by the detail are shared with e.
+//
+// The returned Event is obtained from the internal pool and may be
+// released with ReleaseEvent. The original event is not modified.
+func (e *Event) AsDelete() *Event {
+	if e == nil {
+		return nil
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	out := cloneEvent(e)
+	out.Time = CoTTime(now)
+	out.Start = CoTTime(now)
+	out.Stale = CoTTime(now.Add(minStaleOffset))
+	return out
+}
+
+// NewReply builds a reply to the tasking event e, such as an acknowledgement
+// ("y-a"), completion ("y-c") or status ("y-s") report. replyType must be a
+// "y-" type in the catalog. The reply links back to the tasking with the
+// "p-t" relation and carries text, if any, in <remarks>.
+//
+// The reply uid is derived from the tasking uid and reply type, so repeated
+// replies of the same type replace each other as CoT updates. The reply has
+// no position (NoPoint is set). It returns an error wrapping ErrInvalidInput
+// if e is not a tasking ("t-") event and ErrInvalidType if replyType is not
+// a known reply type.
+//
+// The returned Event is obtained from the internal pool and may be
+// released with ReleaseEvent.
+func (e *Event) NewReply(replyType, text string) (*Event, error) {
+	if e == nil {
+		return nil, fmt.Errorf("nil event")
+	}
+	if !strings.HasPrefix(e.Type, "t-") {
+		return nil, fmt.Errorf("event type %q is not a tasking: %w", e.Type, ErrInvalidInput)
+	}
+	if !strings.HasPrefix(replyType, "y-") {
+		return nil, fmt.Errorf("reply type %q is not a reply: %w", replyType, ErrInvalidType)
+	}
+	if err := ValidateType(replyType); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	evt := getEvent()
+	*evt = Event{
+		Version: "2.0",
+		Uid:     e.Uid + "-" + replyType,
+		Type:    replyType,
+		How:     "h-g-i-g-o",
+		Time:    CoTTime(now),
+		Start:   CoTTime(now),
+		Stale:   CoTTime(now.Add(defaultStaleOffset)),
+		Point:   unknownPoint,
+		NoPoint: true,
+		Links:   []Link{{Uid: e.Uid, Type: e.Type, Relation: "p-t"}},
+	}
+	if text != "" {
+		evt.Detail = &Detail{Remarks: &Remarks{Text: text}}
+	}
+	if err := evt.ValidateAt(now); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	return evt, nil
+}
+
+// Keepalive types used by TAK clients and servers.
+const (
+	pingType = "t-x-c-t"
+	pongType = "t-x-c-t-r"
+)
+
+// IsPing reports whether e is a TAK keepalive ping (type t-x-c-t).
+func (e *Event) IsPing() bool {
+	return e != nil && e.Type == pingType
+}
+
+// NewPong builds the t-x-c-t-r reply to the ping e, sent as selfUid. The
+// pong links back to the ping with a "p-t" relation so the sender can match
+// it and measure latency, and it carries no position (NoPoint is set). It
+// returns an error wrapping ErrInvalidInput if e is not a ping, and the
+// ValidateUID error if selfUid is invalid.
+//
+// The returned Event is obtained from the internal pool and may be
+// released with ReleaseEvent.
+func (e *Event) NewPong(selfUid string) (*Event, error) {
+	if !e.IsPing() {
+		if e == nil {
+			return nil, fmt.Errorf("nil event")
+		}
+		return nil, fmt.Errorf("event type %q is not a ping: %w", e.Type, ErrInvalidInput)
+	}
+	if err := ValidateUID(selfUid); err != nil {
+		return nil, fmt.Errorf("invalid uid %q: %w", selfUid, err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	evt := getEvent()
+	*evt = Event{
+		Version: "2.0",
+		Uid:     selfUid,
+		Type:    pongType,
+		How:     "m-g",
+		Time:    CoTTime(now),
+		Start:   CoTTime(now),
+		Stale:   CoTTime(now.Add(defaultStaleOffset)),
+		Point:   unknownPoint,
+		NoPoint: true,
+		Links:   []Link{{Uid: e.Uid, Type: e.Type, Relation: "p-t"}},
+	}
+	if err := evt.ValidateAt(now); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	return evt, nil
+}
+
 // ValidateType checks if a CoT type is valid
 func ValidateType(typ string) error {
 	if typ == "" {
-		return fmt.Errorf("empty type: %w", ErrInvalidType)
+		return fmt.Errorf("empty type: %w", ErrMalformedType)
 	}
 	if len(typ) > 100 {
-		return fmt.Errorf("type too long: %w", ErrInvalidType)
+		return fmt.Errorf("type too long: %w", ErrMalformedType)
 	}
 
 	if strings.HasSuffix(typ, "-") {
-		return fmt.Errorf("type cannot end with dash: %w", ErrInvalidType)
+		return fmt.Errorf("type cannot end with dash: %w", ErrMalformedType)
 	}
 
 	// Fast path for wildcard patterns that don't need catalog lookup
 	if strings.Contains(typ, "*") {
 		parts := strings.Split(typ, "-")
 		if len(parts) < 2 {
-			return fmt.Errorf("invalid type format: %w", ErrInvalidType)
+			return fmt.Errorf("invalid type format: %w", ErrMalformedType)
 		}
 
 		// Only allow a trailing segment consisting solely of '*'
 		for i, p := range parts {
 			if strings.Contains(p, "*") {
 				if p != "*" {
-					return fmt.Errorf("wildcard must be standalone segment: %w", ErrInvalidType)
+					return fmt.Errorf("wildcard must be standalone segment: %w", ErrMalformedType)
 				}
 				if i != len(parts)-1 {
-					return fmt.Errorf("wildcard only allowed at end of type: %w", ErrInvalidType)
+					return fmt.Errorf("wildcard only allowed at end of type: %w", ErrMalformedType)
 				}
 			}
 		}
 
 		// Validate the prefix
 		if parts[0] != "a" && parts[0] != "b" && parts[0] != "t" {
-			return fmt.Errorf("invalid type prefix: %w", ErrInvalidType)
+			return fmt.Errorf("invalid type prefix: %w", ErrUnknownPrefix)
 		}
 		return nil
 	}
@@ -1333,13 +2391,13 @@ func ValidateType(typ string) error {
 	if strings.HasPrefix(typ, "a-.") {
 		parts := strings.Split(typ, "-")
 		if len(parts) < 2 {
-			return fmt.Errorf("invalid type format: %w", ErrInvalidType)
+			return fmt.Errorf("invalid type format: %w", ErrMalformedType)
 		}
 		if parts[0] != "a" {
-			return fmt.Errorf("wildcard only allowed in atomic types: %w", ErrInvalidType)
+			return fmt.Errorf("wildcard only allowed in atomic types: %w", ErrMalformedType)
 		}
 		if parts[1] != "." {
-			return fmt.Errorf("invalid wildcard format: %w", ErrInvalidType)
+			return fmt.Errorf("invalid wildcard format: %w", ErrMalformedType)
 		}
 		return nil
 	}
@@ -1348,8 +2406,6 @@ func ValidateType(typ string) error {
 	cat := cottypes.GetCatalog()
 	_, err := cat.GetType(context.Background(), typ)
 	if err != nil {
-		invalidErr := fmt.Errorf("invalid type: %w", ErrInvalidType)
-
 		// Attempt wildcard resolution by replacing f/h/n/u segments with '.'
 		parts := strings.Split(typ, "-")
 		for i, seg := range parts {
@@ -1364,7 +2420,10 @@ func ValidateType(typ string) error {
 			}
 		}
 
-		return invalidErr
+		if err := typeSyntaxError(typ); err != nil {
+			return err
+		}
+		return fmt.Errorf("type %q: %w", typ, ErrTypeNotInCatalog)
 	}
 
 	return nil
@@ -1404,6 +2463,33 @@ func ValidateRelation(relation string) error {
 	return nil
 }
 
+// ValidateHows checks every how value in codes with ValidateHow, for
+// example to reject a configuration at startup. The returned error joins
+// one error per unrecognised code, each wrapping ErrInvalidHow, or is nil
+// if all codes are valid.
+func ValidateHows(codes []string) error {
+	var errs []error
+	for _, c := range codes {
+		if err := ValidateHow(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateRelations checks every relation value in codes with
+// ValidateRelation. The returned error joins one error per unrecognised
+// code, each wrapping ErrInvalidRelation, or is nil if all codes are valid.
+func ValidateRelations(codes []string) error {
+	var errs []error
+	for _, c := range codes {
+		if err := ValidateRelation(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Validate checks if the event is valid
 func (e *Event) Validate() error {
 	return e.ValidateAt(time.Now().UTC())
@@ -1411,13 +2497,36 @@ func (e *Event) Validate() error {
 
 // ValidateAt checks if the event is valid using the provided reference time
 func (e *Event) ValidateAt(now time.Time) error {
+	return e.validateProfileAt(now, ProfileTAK)
+}
+
+// validateProfileAt checks the event against the rules of profile p using
+// the provided reference time.
+func (e *Event) validateProfileAt(now time.Time, p Profile) error {
+	switch p {
+	case ProfileTAK, ProfileStrict, ProfileLenient:
+	default:
+		return fmt.Errorf("unknown validation profile %d", int(p))
+	}
+
 	// Check required fields
 	if e.Version == "" {
 		return fmt.Errorf("missing version")
 	}
+	if err := validateVersion(e.Version); err != nil {
+		return err
+	}
 	if e.Uid == "" {
 		return fmt.Errorf("missing uid")
 	}
+	if requireUUID.Load() {
+		if err := ValidateUID(e.Uid); err != nil {
+			return fmt.Errorf("invalid uid %q: %w", e.Uid, err)
+		}
+		if err := ValidateUIDUUID(e.Uid); err != nil {
+			return err
+		}
+	}
 	if e.Type == "" {
 		return fmt.Errorf("missing type")
 	}
@@ -1426,10 +2535,28 @@ func (e *Event) ValidateAt(now time.Time) error {
 	if err := ValidateType(e.Type); err != nil {
 		return err
 	}
+	if p == ProfileStrict && !isRegisteredType(e.Type) {
+		return fmt.Errorf("unknown type %s: %w", e.Type, ErrInvalidType)
+	}
 
 	// Validate how field if present
-	if err := ValidateHow(e.How); err != nil {
-		return fmt.Errorf("invalid how: %w", err)
+	if p == ProfileStrict && e.How == "" {
+		return fmt.Errorf("missing how")
+	}
+	if p != ProfileLenient {
+		if err := ValidateHow(e.How); err != nil {
+			return fmt.Errorf("invalid how: %w", err)
+		}
+	}
+
+	if max := maxLinks.Load(); max > 0 {
+		n := len(e.Links)
+		if e.Detail != nil {
+			n += len(e.Detail.RouteLinks)
+		}
+		if int64(n) > max {
+			return fmt.Errorf("event has %d links, more than the limit of %d: %w", n, max, ErrInvalidInput)
+		}
 	}
 
 	// Validate link relations
@@ -1441,13 +2568,89 @@ func (e *Event) ValidateAt(now time.Time) error {
 		if err := ValidateType(link.Type); err != nil {
 			return fmt.Errorf("invalid link type in link %d: %w", i, err)
 		}
+		if link.Children != "" {
+			if err := checkFragment(link.Children); err != nil {
+				return fmt.Errorf("invalid children in link %d: %w", i, err)
+			}
+		}
+	}
+
+	// Validate drawing attributes and contact details
+	if p != ProfileLenient {
+		if err := validateStrokeColor(e.StrokeColor); err != nil {
+			return err
+		}
+		if err := validateUserIcon(e.UserIcon); err != nil {
+			return err
+		}
+		if e.Detail != nil {
+			if err := e.Detail.Contact.Validate(); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Validate time fields
-	eventTime := e.Time.Time()
-	startTime := e.Start.Time()
-	staleTime := e.Stale.Time()
+	if err := validateTimes(e.Time.Time(), e.Start.Time(), e.Stale.Time(), now); err != nil {
+		return err
+	}
+
+	// Validate point
+	if !e.NoPoint {
+		if err := e.Point.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate detail extensions if present
+	if e.Detail != nil && p != ProfileLenient && !skipDetailSchemas.Load() {
+		if err := e.Detail.Validate(); err != nil {
+			return err
+		}
+	}
 
+	return nil
+}
+
+// detailSchemaNames lists the names accepted by ValidateDetailSubset. It
+// is collected by running the checks on an empty Detail, which asks want
+// about every extension without validating anything.
+var detailSchemaNames = sync.OnceValue(func() map[string]bool {
+	names := make(map[string]bool)
+	_ = (&Detail{}).validate(func(name string) bool {
+		names[name] = true
+		return true
+	})
+	names["__chat"] = true
+	names["__chatreceipt"] = true
+	return names
+})
+
+// ValidateDetailSubset validates only the listed detail extensions of e
+// against their schemas and ignores the rest, for gateways whose partners
+// accept a limited set of details. Names are the detail element names,
+// for example "contact", "track", "__group", "__chat" or "__chatreceipt".
+// Listed extensions that the event does not carry are skipped. An unknown
+// name is rejected with ErrInvalidInput. Only detail schemas are checked;
+// use Validate for the rest of the event.
+func ValidateDetailSubset(e *Event, schemas []string) error {
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+	known := detailSchemaNames()
+	only := make(map[string]bool, len(schemas))
+	for _, name := range schemas {
+		if !known[name] {
+			return fmt.Errorf("unknown detail schema %q: %w", name, ErrInvalidInput)
+		}
+		only[name] = true
+	}
+	return e.Detail.validate(func(name string) bool { return only[name] })
+}
+
+// validateTimes checks the event time window relative to now and the
+// ordering of the start and stale times.
+func validateTimes(eventTime, startTime, staleTime, now time.Time) error {
 	// Check time ranges
 	if eventTime.Before(now.Add(-24 * time.Hour)) {
 		return fmt.Errorf("time must be within 24 hours of current time")
@@ -1460,67 +2663,117 @@ func (e *Event) ValidateAt(now time.Time) error {
 	if startTime.After(eventTime) {
 		return fmt.Errorf("start time after event time")
 	}
+	if startTime.After(now.Add(currentMaxFutureStart())) {
+		return fmt.Errorf("start time more than %v ahead of current time", currentMaxFutureStart())
+	}
 
 	// Check stale time
 	staleDiff := staleTime.Sub(eventTime)
 	if staleDiff < minStaleOffset {
 		return fmt.Errorf("stale time too close to event time")
 	}
-	// Skip maximum stale offset checks to allow extended validity
-
-	// Validate point
-	if err := e.Point.Validate(); err != nil {
-		return err
+	if staleDiff > maxStaleOffset {
+		return fmt.Errorf("stale time more than %v after event time", maxStaleOffset)
 	}
 
-	// Validate chat-related extensions if present
-	if e.Detail != nil {
-		if e.Detail.Chat != nil {
-			data, err := xml.Marshal(e.Detail.Chat)
-			if err != nil {
-				return fmt.Errorf("marshal chat: %w", err)
+	return nil
+}
+
+// Validate checks the detail extensions against their embedded schemas,
+// the same checks Event.ValidateAt performs on an event's detail. It can be
+// used to test a Detail built on its own, for example as a template. Unlike
+// event validation it runs even when schema validation has been disabled
+// with SetDetailSchemaValidation. A nil Detail is valid.
+func (d *Detail) Validate() error {
+	return d.validate(func(string) bool { return true })
+}
+
+// validate runs the schema checks for the detail extensions accepted by
+// want, which is called with the names used by ValidateDetailSubset.
+func (d *Detail) validate(want func(name string) bool) error {
+	if d == nil {
+		return nil
+	}
+	if d.Chat != nil && want("__chat") {
+		data, err := xml.Marshal(d.Chat)
+		if err != nil {
+			return fmt.Errorf("marshal chat: %w", err)
+		}
+		if err := validator.ValidateAgainstSchema("chat", data); err != nil {
+			if err2 := validator.ValidateAgainstSchema("tak-details-__chat", data); err2 != nil {
+				err = errors.Join(err, err2)
+				return fmt.Errorf("chat validation failed%s: %w", detailPath(err), err)
 			}
-			if err := validator.ValidateAgainstSchema("chat", data); err != nil {
-				if err2 := validator.ValidateAgainstSchema("tak-details-__chat", data); err2 != nil {
-					return fmt.Errorf("chat validation failed: %w", errors.Join(err, err2))
-				}
-			} else {
-				if err := validator.ValidateChat(data); err != nil {
-					return fmt.Errorf("chat validation failed: %w", err)
-				}
+		} else {
+			if err := validator.ValidateChat(data); err != nil {
+				return fmt.Errorf("chat validation failed: %w", err)
 			}
 		}
-		if e.Detail.ChatReceipt != nil {
-			var data []byte
-			if len(e.Detail.ChatReceipt.Raw) > 0 {
-				data = e.Detail.ChatReceipt.Raw
-			} else {
-				var err error
-				data, err = xml.Marshal(e.Detail.ChatReceipt)
-				if err != nil {
-					return fmt.Errorf("marshal chatReceipt: %w", err)
-				}
+		if strictChatRouting.Load() && want("marti") {
+			if err := d.Chat.checkRouting(d.Marti); err != nil {
+				return fmt.Errorf("chat validation failed: %w", err)
 			}
-			if err := validator.ValidateAgainstSchema("chatReceipt", data); err != nil {
-				if err := validator.ValidateAgainstSchema("tak-details-__chatreceipt", data); err != nil {
-					return fmt.Errorf("chatReceipt validation failed: %w", err)
-				}
+		}
+	}
+	if d.ChatReceipt != nil && want("__chatreceipt") {
+		var data []byte
+		if len(d.ChatReceipt.Raw) > 0 {
+			data = d.ChatReceipt.Raw
+		} else {
+			var err error
+			data, err = xml.Marshal(d.ChatReceipt)
+			if err != nil {
+				return fmt.Errorf("marshal chatReceipt: %w", err)
 			}
 		}
-
-		if err := e.validateDetailSchemas(); err != nil {
-			return err
+		if err := validator.ValidateAgainstSchema("chatReceipt", data); err != nil {
+			if err := validator.ValidateAgainstSchema("tak-details-__chatreceipt", data); err != nil {
+				return fmt.Errorf("chatReceipt validation failed%s: %w", detailPath(err), err)
+			}
 		}
 	}
 
-	return nil
+	return d.validateSchemas(want)
 }
 
-func (e *Event) validateDetailSchemas() error {
-	if e.Detail == nil {
-		return nil
+// RequiresTAKDialect reports whether any of the event's details validate
+// only against the TAK variant of a schema that also has a strict MITRE
+// form. names lists those elements, "__chat" and "__chatreceipt", so
+// operators can flag producers that emit non-standard CoT. Details that
+// fail both schemas are not listed; Validate reports them.
+func (e *Event) RequiresTAKDialect() (bool, []string) {
+	if e == nil || e.Detail == nil {
+		return false, nil
+	}
+	d := e.Detail
+	var names []string
+	if d.Chat != nil {
+		if data, err := xml.Marshal(d.Chat); err == nil && takOnly(data, "chat", "tak-details-__chat") {
+			names = append(names, "__chat")
+		}
 	}
+	if d.ChatReceipt != nil {
+		data := []byte(d.ChatReceipt.Raw)
+		if len(data) == 0 {
+			data, _ = xml.Marshal(d.ChatReceipt)
+		}
+		if len(data) > 0 && takOnly(data, "chatReceipt", "tak-details-__chatreceipt") {
+			names = append(names, "__chatreceipt")
+		}
+	}
+	return len(names) > 0, names
+}
+
+// takOnly reports whether data fails the strict schema but passes the TAK
+// one.
+func takOnly(data []byte, strict, tak string) bool {
+	return validator.ValidateAgainstSchema(strict, data) != nil &&
+		validator.ValidateAgainstSchema(tak, data) == nil
+}
 
+// validateSchemas checks each known detail extension accepted by want
+// against its schema.
+func (d *Detail) validateSchemas(want func(name string) bool) error {
 	type field struct {
 		name   string
 		schema string
@@ -1532,40 +2785,40 @@ func (e *Event) validateDetailSchemas() error {
 			name:   "emergency",
 			schema: "tak-details-emergency",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Emergency == nil {
+				if d.Emergency == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Emergency.Raw, true, nil
+				return d.Emergency.Raw, true, nil
 			},
 		},
 		{
 			name:   "__serverdestination",
 			schema: "tak-details-__serverdestination",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.ServerDestination == nil {
+				if d.ServerDestination == nil {
 					return nil, false, nil
 				}
-				return e.Detail.ServerDestination.Raw, true, nil
+				return d.ServerDestination.Raw, true, nil
 			},
 		},
 		{
 			name:   "__group",
 			schema: "tak-details-__group",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.GroupExtension == nil {
+				if d.GroupExtension == nil {
 					return nil, false, nil
 				}
-				return e.Detail.GroupExtension.Raw, true, nil
+				return d.GroupExtension.Raw, true, nil
 			},
 		},
 		{
 			name:   "contact",
 			schema: "tak-details-contact",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Contact == nil {
+				if d.Contact == nil {
 					return nil, false, nil
 				}
-				b, err := xml.Marshal(e.Detail.Contact)
+				b, err := xml.Marshal(d.Contact)
 				return b, true, err
 			},
 		},
@@ -1573,90 +2826,108 @@ func (e *Event) validateDetailSchemas() error {
 			name:   "track",
 			schema: "tak-details-track",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Track == nil {
+				if d.Track == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Track.Raw, true, nil
+				return d.Track.Raw, true, nil
 			},
 		},
 		{
 			name:   "status",
 			schema: "tak-details-status",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Status == nil {
+				if d.Status == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Status.Raw, true, nil
+				return d.Status.Raw, true, nil
 			},
 		},
 		{
 			name:   "archive",
 			schema: "tak-details-archive",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Archive == nil {
+				if d.Archive == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Archive.Raw, true, nil
+				return d.Archive.Raw, true, nil
 			},
 		},
 		{
 			name:   "__video",
 			schema: "tak-details-__video",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Video == nil {
+				if d.Video == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Video.Raw, true, nil
+				return d.Video.Raw, true, nil
 			},
 		},
 		{
 			name:   "attachment_list",
 			schema: "tak-details-attachment_list",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.AttachmentList == nil {
+				if d.AttachmentList == nil {
 					return nil, false, nil
 				}
-				return e.Detail.AttachmentList.Raw, true, nil
+				return d.AttachmentList.Raw, true, nil
 			},
 		},
 		{
 			name:   "uid",
 			schema: "tak-details-uid",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.UID == nil {
+				if d.UID == nil {
 					return nil, false, nil
 				}
-				return e.Detail.UID.Raw, true, nil
+				return d.UID.Raw, true, nil
 			},
 		},
 		{
 			name:   "bullseye",
 			schema: "tak-details-bullseye",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Bullseye == nil {
+				if d.Bullseye == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Bullseye.Raw, true, nil
+				if len(d.Bullseye.Raw) > 0 {
+					return d.Bullseye.Raw, true, nil
+				}
+				data, err := xml.Marshal(d.Bullseye)
+				return data, true, err
+			},
+		},
+		{
+			name:   "_medevac_",
+			schema: "tak-details-_medevac_",
+			data: func() ([]byte, bool, error) {
+				if d.Medevac == nil {
+					return nil, false, nil
+				}
+				if len(d.Medevac.Raw) > 0 {
+					return d.Medevac.Raw, true, nil
+				}
+				data, err := xml.Marshal(d.Medevac)
+				return data, true, err
 			},
 		},
 		{
 			name:   "routeinfo",
 			schema: "tak-details-routeinfo",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.RouteInfo == nil {
+				if d.RouteInfo == nil {
 					return nil, false, nil
 				}
-				return e.Detail.RouteInfo.Raw, true, nil
+				return d.RouteInfo.Raw, true, nil
 			},
 		},
 		{
 			name:   "marti",
 			schema: "tak-details-marti",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Marti == nil {
+				if d.Marti == nil {
 					return nil, false, nil
 				}
-				b, err := xml.Marshal(e.Detail.Marti)
+				b, err := xml.Marshal(d.Marti)
 				return b, true, err
 			},
 		},
@@ -1664,191 +2935,235 @@ func (e *Event) validateDetailSchemas() error {
 			name:   "environment",
 			schema: "tak-details-environment",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Environment == nil {
+				if d.Environment == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Environment.Raw, true, nil
+				return d.Environment.Raw, true, nil
 			},
 		},
 		{
 			name:   "fileshare",
 			schema: "tak-details-fileshare",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.FileShare == nil {
+				if d.FileShare == nil {
 					return nil, false, nil
 				}
-				return e.Detail.FileShare.Raw, true, nil
+				return d.FileShare.Raw, true, nil
 			},
 		},
 		{
 			name:   "precisionlocation",
 			schema: "tak-details-precisionlocation",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.PrecisionLocation == nil {
+				if d.PrecisionLocation == nil {
 					return nil, false, nil
 				}
-				return e.Detail.PrecisionLocation.Raw, true, nil
+				return d.PrecisionLocation.Raw, true, nil
 			},
 		},
 		{
 			name:   "takv",
 			schema: "tak-details-takv",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Takv == nil {
+				if d.Takv == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Takv.Raw, true, nil
+				return d.Takv.Raw, true, nil
 			},
 		},
 		{
 			name:   "mission",
 			schema: "tak-details-mission",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Mission == nil {
+				if d.Mission == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Mission.Raw, true, nil
+				return d.Mission.Raw, true, nil
 			},
 		},
 		{
 			name:   "shape",
 			schema: "tak-details-shape",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Shape == nil {
+				if d.Shape == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Shape.Raw, true, nil
+				return d.Shape.Raw, true, nil
 			},
 		},
 		{
 			name:   "__geofence",
 			schema: "tak-details-__geofence",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Geofence == nil {
+				if d.Geofence == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Geofence.Raw, true, nil
+				return d.Geofence.Raw, true, nil
 			},
 		},
 		{
 			name:   "strokeColor",
 			schema: "tak-details-strokeColor",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.StrokeColor == nil {
+				if d.StrokeColor == nil {
 					return nil, false, nil
 				}
-				return e.Detail.StrokeColor.Raw, true, nil
+				return d.StrokeColor.Raw, true, nil
 			},
 		},
 		{
 			name:   "strokeWeight",
 			schema: "tak-details-strokeWeight",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.StrokeWeight == nil {
+				if d.StrokeWeight == nil {
 					return nil, false, nil
 				}
-				return e.Detail.StrokeWeight.Raw, true, nil
+				return d.StrokeWeight.Raw, true, nil
 			},
 		},
 		{
 			name:   "fillColor",
 			schema: "tak-details-fillColor",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.FillColor == nil {
+				if d.FillColor == nil {
 					return nil, false, nil
 				}
-				return e.Detail.FillColor.Raw, true, nil
+				return d.FillColor.Raw, true, nil
 			},
 		},
 		{
 			name:   "height",
 			schema: "tak-details-height",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Height == nil {
+				if d.Height == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Height.Raw, true, nil
+				return d.Height.Raw, true, nil
 			},
 		},
 		{
 			name:   "height_unit",
 			schema: "tak-details-height_unit",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.HeightUnit == nil {
+				if d.HeightUnit == nil {
 					return nil, false, nil
 				}
-				return e.Detail.HeightUnit.Raw, true, nil
+				return d.HeightUnit.Raw, true, nil
 			},
 		},
 		{
 			name:   "labels_on",
 			schema: "tak-details-labels_on",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.LabelsOn == nil {
+				if d.LabelsOn == nil {
 					return nil, false, nil
 				}
-				return e.Detail.LabelsOn.Raw, true, nil
+				if len(d.LabelsOn.Raw) > 0 {
+					return d.LabelsOn.Raw, true, nil
+				}
+				data, err := xml.Marshal(d.LabelsOn)
+				return data, true, err
 			},
 		},
 		{
 			name:   "color",
 			schema: "tak-details-color",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.ColorExtension == nil {
+				if d.ColorExtension == nil {
 					return nil, false, nil
 				}
-				return e.Detail.ColorExtension.Raw, true, nil
+				return d.ColorExtension.Raw, true, nil
 			},
 		},
 		{
 			name:   "hierarchy",
 			schema: "tak-details-hierarchy",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Hierarchy == nil {
+				if d.Hierarchy == nil {
 					return nil, false, nil
 				}
-				return e.Detail.Hierarchy.Raw, true, nil
+				return d.Hierarchy.Raw, true, nil
 			},
 		},
 		{
 			name:   "link",
 			schema: "tak-details-link",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.LinkDetail == nil {
+				if d.LinkDetail == nil {
 					return nil, false, nil
 				}
-				return e.Detail.LinkDetail.Raw, true, nil
+				return d.LinkDetail.Raw, true, nil
 			},
 		},
 		{
 			name:   "usericon",
 			schema: "tak-details-usericon",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.UserIcon == nil {
+				if d.UserIcon == nil {
 					return nil, false, nil
 				}
-				return e.Detail.UserIcon.Raw, true, nil
+				return d.UserIcon.Raw, true, nil
 			},
 		},
 		{
 			name:   "remarks",
 			schema: "tak-details-remarks",
 			data: func() ([]byte, bool, error) {
-				if e.Detail.Remarks == nil {
+				if d.Remarks == nil {
+					return nil, false, nil
+				}
+				if d.Remarks.rawOnly() {
+					return d.Remarks.Raw, true, nil
+				}
+				data, err := xml.Marshal(d.Remarks)
+				return data, true, err
+			},
+		},
+		{
+			name:   "_radio_",
+			schema: "tak-details-_radio_",
+			data: func() ([]byte, bool, error) {
+				if d.Radio == nil {
+					return nil, false, nil
+				}
+				if len(d.Radio.Raw) > 0 {
+					return d.Radio.Raw, true, nil
+				}
+				data, err := xml.Marshal(d.Radio)
+				return data, true, err
+			},
+		},
+		{
+			name:   "_uastool_",
+			schema: "tak-details-_uastool_",
+			data: func() ([]byte, bool, error) {
+				if d.UASTelemetry == nil {
 					return nil, false, nil
 				}
-				if len(e.Detail.Remarks.Raw) > 0 && e.Detail.Remarks.Text == "" &&
-					e.Detail.Remarks.Source == "" && e.Detail.Remarks.SourceID == "" &&
-					e.Detail.Remarks.To == "" && e.Detail.Remarks.Time.Time().IsZero() {
-					return e.Detail.Remarks.Raw, true, nil
+				if len(d.UASTelemetry.Raw) > 0 {
+					return d.UASTelemetry.Raw, true, nil
 				}
-				data, err := xml.Marshal(e.Detail.Remarks)
+				data, err := xml.Marshal(d.UASTelemetry)
+				return data, true, err
+			},
+		},
+		{
+			name:   "signature",
+			schema: "tak-details-signature",
+			data: func() ([]byte, bool, error) {
+				if d.Signature == nil {
+					return nil, false, nil
+				}
+				data, err := xml.Marshal(d.Signature)
 				return data, true, err
 			},
 		},
 	}
 
 	for _, f := range fields {
+		if !want(f.name) {
+			continue
+		}
 		data, ok, err := f.data()
 		if err != nil {
 			return fmt.Errorf("marshal %s: %w", f.name, err)
@@ -1857,46 +3172,507 @@ func (e *Event) validateDetailSchemas() error {
 			continue
 		}
 		if err := validator.ValidateAgainstSchema(f.schema, data); err != nil {
-			return fmt.Errorf("invalid %s: %w", f.name, err)
+			return fmt.Errorf("invalid %s%s: %w", f.name, detailPath(err), err)
+		}
+	}
+
+	if d.Marti != nil && want("marti") {
+		if err := d.Marti.validateDests(); err != nil {
+			return fmt.Errorf("invalid marti: %w", err)
+		}
+	}
+	if d.Geofence != nil && want("__geofence") {
+		if err := d.Geofence.validateEnums(); err != nil {
+			return fmt.Errorf("invalid __geofence: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Altitude source values returned by Event.AltitudeSource when the event
+// has no precisionlocation altsrc.
+const (
+	// AltSrcUnknown indicates the event has no altitude (HAE is 9999999).
+	AltSrcUnknown = "UNKNOWN"
+	// AltSrcUnspecified indicates the event has an altitude whose source
+	// was not reported. It matches the TAK "???" altsrc value.
+	AltSrcUnspecified = "???"
+)
+
+// AltitudeSource returns the provenance of the event's altitude. The altsrc
+// attribute of the precisionlocation detail is returned when present.
+// Otherwise AltSrcUnknown is returned if the event has no point or HAE is
+// the 9999999 "unknown" sentinel, and AltSrcUnspecified if the point
+// carries a real HAE.
+func (e *Event) AltitudeSource() string {
+	if e == nil {
+		return AltSrcUnknown
+	}
+	if e.Detail != nil && e.Detail.PrecisionLocation != nil && len(e.Detail.PrecisionLocation.Raw) > 0 {
+		var helper struct {
+			AltSrc string `xml:"altsrc,attr"`
+		}
+		if err := xml.Unmarshal(e.Detail.PrecisionLocation.Raw, &helper); err == nil && helper.AltSrc != "" {
+			return helper.AltSrc
+		}
+	}
+	if e.NoPoint || e.Point.Hae == 9999999.0 {
+		return AltSrcUnknown
+	}
+	return AltSrcUnspecified
+}
+
+// howSources maps precisionlocation geopointsrc/altsrc values to the how
+// descriptors passed to cottypes.GetHowValue.
+var howSources = map[string]string{
+	"GPS":  "gps",
+	"DGPS": "dgps",
+	"USER": "entered",
+	"CALC": "calculated",
+}
+
+// InferHow derives a likely how value for an event that lacks one. The
+// source reported by the precisionlocation detail (geopointsrc, falling
+// back to altsrc) is mapped through the how catalog, so a GPS fix yields
+// the TAK GPS value h-g-i-g-o and a user-entered point yields h-e. A
+// satellite source (GPS or DGPS) is only trusted when the event has a
+// point with a known circular error. It returns false when no source is
+// reported or the source is not recognised; a bare point carries no
+// provenance and is never enough on its own.
+func (e *Event) InferHow() (string, bool) {
+	if e == nil || e.Detail == nil || e.Detail.PrecisionLocation == nil || len(e.Detail.PrecisionLocation.Raw) == 0 {
+		return "", false
+	}
+	var helper struct {
+		GeoPointSrc string `xml:"geopointsrc,attr"`
+		AltSrc      string `xml:"altsrc,attr"`
+	}
+	if err := xml.Unmarshal(e.Detail.PrecisionLocation.Raw, &helper); err != nil {
+		return "", false
+	}
+	src := helper.GeoPointSrc
+	if src == "" {
+		src = helper.AltSrc
+	}
+	src = strings.ToUpper(strings.TrimSpace(src))
+	descriptor, ok := howSources[src]
+	if !ok {
+		return "", false
+	}
+	if (src == "GPS" || src == "DGPS") && (e.NoPoint || e.Point.Ce <= 0 || e.Point.Ce >= 9999999.0) {
+		return "", false
+	}
+	how, err := cottypes.GetHowValue(descriptor)
+	if err != nil {
+		return "", false
+	}
+	return how, true
+}
+
+// SetUserIcon sets the event's custom icon to iconsetPath, writing both the
+// usericon event attribute and the <usericon iconsetpath="..."/> detail so
+// that consumers reading either form see the same icon. An empty path
+// removes both. The detail form is checked against the
+// tak-details-usericon schema when the event is validated.
+func (e *Event) SetUserIcon(iconsetPath string) {
+	if e == nil {
+		return
+	}
+	e.UserIcon = iconsetPath
+	if iconsetPath == "" {
+		if e.Detail != nil {
+			e.Detail.UserIcon = nil
+		}
+		return
+	}
+	if e.Detail == nil {
+		e.Detail = &Detail{}
+	}
+	e.Detail.UserIcon = NewUserIcon(iconsetPath)
+}
+
+// IconPath returns the event's custom icon path. The iconsetpath of the
+// usericon detail is preferred, falling back to the usericon event
+// attribute. It returns "" if neither is set.
+func (e *Event) IconPath() string {
+	if e == nil {
+		return ""
+	}
+	if e.Detail != nil {
+		if p := e.Detail.UserIcon.IconsetPath(); p != "" {
+			return p
+		}
+	}
+	return e.UserIcon
+}
+
+// HasPoint reports whether the event carries a position. It is false for
+// decoded events that had no <point> element.
+func (e *Event) HasPoint() bool {
+	return e != nil && !e.NoPoint
+}
+
+// Summary returns a compact one-line description of the event for logs and
+// command-line tools, for example
+//
+//	uid=ABC type=a-f-G (Ground) callsign=ALPHA @30.00000,-85.00000 stale=30s
+//
+// The type's full name is taken from the catalog and omitted for
+// unregistered types, the callsign is read from the contact detail and the
+// stale interval is measured from the event time. Events without a point
+// omit the position.
+func (e *Event) Summary() string {
+	if e == nil {
+		return "<nil>"
+	}
+	buf := make([]byte, 0, 96)
+	buf = append(buf, "uid="...)
+	buf = append(buf, e.Uid...)
+	buf = append(buf, " type="...)
+	buf = append(buf, e.Type...)
+	if t, ok := LookupType(e.Type); ok && t.FullName != "" {
+		buf = append(buf, " ("...)
+		buf = append(buf, t.FullName...)
+		buf = append(buf, ')')
+	}
+	if e.Detail != nil && e.Detail.Contact != nil && e.Detail.Contact.Callsign != "" {
+		buf = append(buf, " callsign="...)
+		buf = append(buf, e.Detail.Contact.Callsign...)
+	}
+	if !e.NoPoint {
+		buf = append(buf, " @"...)
+		buf = strconv.AppendFloat(buf, e.Point.Lat, 'f', 5, 64)
+		buf = append(buf, ',')
+		buf = strconv.AppendFloat(buf, e.Point.Lon, 'f', 5, 64)
+	}
+	if t, s := e.Time.Time(), e.Stale.Time(); !t.IsZero() && !s.IsZero() {
+		buf = append(buf, " stale="...)
+		buf = append(buf, s.Sub(t).String()...)
+	}
+	return string(buf)
+}
+
+// StartOffset returns the event's start time relative to epoch, for
+// placing the event on a playback timeline. The offset is negative when
+// the event starts before epoch. It returns 0 for a nil event.
+func (e *Event) StartOffset(epoch time.Time) time.Duration {
+	if e == nil {
+		return 0
+	}
+	return e.Start.Time().Sub(epoch)
+}
+
+// Age returns how long before at the event was generated, measured from
+// its time attribute rather than start or stale. The age is negative when
+// the event is dated after at, which happens when the sender's clock runs
+// ahead; callers that prefer to ignore skew can clamp it to zero. It
+// returns 0 for a nil event.
+func (e *Event) Age(at time.Time) time.Duration {
+	if e == nil {
+		return 0
+	}
+	return at.Sub(e.Time.Time())
+}
+
+// IsStale reports whether the event's stale time is at or before at.
+// Events without a stale time, and nil events, are never stale.
+func (e *Event) IsStale(at time.Time) bool {
+	if e == nil {
+		return false
+	}
+	stale := e.Stale.Time()
+	return !stale.IsZero() && !at.Before(stale)
+}
+
+// TimeString returns the event's time attribute exactly as ToXML writes it:
+// in CotTimeFormat and UTC, or as received when the event was decoded under
+// SetPreserveTimeStrings and the time has not changed since. It returns ""
+// if the time is unset.
+func (e *Event) TimeString() string {
+	if e == nil {
+		return ""
+	}
+	return preservedTime(e.origTime, e.Time)
+}
+
+// StartString returns the event's start attribute as ToXML writes it. It
+// returns "" if the start time is unset.
+func (e *Event) StartString() string {
+	if e == nil {
+		return ""
+	}
+	return preservedTime(e.origStart, e.Start)
+}
+
+// StaleString returns the event's stale attribute as ToXML writes it. It
+// returns "" if the stale time is unset.
+func (e *Event) StaleString() string {
+	if e == nil {
+		return ""
+	}
+	return preservedTime(e.origStale, e.Stale)
+}
+
+// SortByStart sorts events in ascending order of start time. Events with
+// equal start times keep their relative order and nil entries sort last.
+func SortByStart(events []*Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.Start.Time().Before(b.Start.Time())
+	})
+}
+
+// detailPath returns " at detail/<path>" locating the schema violation
+// reported in err, or "" if err carries no location. When err joins the
+// results of several schemas the deepest path is used, since it comes from
+// the schema that matched the most of the document.
+func detailPath(err error) string {
+	var best string
+	var walk func(error)
+	walk = func(err error) {
+		if err == nil {
+			return
+		}
+		if se, ok := err.(*validator.SchemaError); ok {
+			if strings.Count(se.Path, "/") > strings.Count(best, "/") || best == "" {
+				best = se.Path
+			}
+			return
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walk(e)
+			}
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		}
+	}
+	walk(err)
+	if best == "" {
+		return ""
+	}
+	return " at detail/" + best
+}
+
+// UpdatePosition sets the point and time fields of the event for a new
+// position report. Time and start are set to t and stale is moved so that
+// the existing stale offset is preserved. Only the point and the time
+// windows are validated; details are not revalidated. The event is left
+// unchanged if validation fails. An event without a point (NoPoint) gains
+// one, with unknown error estimates.
+func (e *Event) UpdatePosition(lat, lon, hae float64, t time.Time) error {
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+
+	offset := defaultStaleOffset
+	if !e.Time.Time().IsZero() && e.Stale.Time().After(e.Time.Time()) {
+		offset = e.Stale.Time().Sub(e.Time.Time())
+	}
+
+	t = t.UTC()
+	pt := e.Point
+	if e.NoPoint {
+		pt = unknownPoint
+	}
+	pt.Lat = lat
+	pt.Lon = lon
+	pt.Hae = hae
+	if err := pt.Validate(); err != nil {
+		return err
+	}
+	stale := t.Add(offset)
+	if err := validateTimes(t, t, stale, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	e.Point = pt
+	e.NoPoint = false
+	e.Time = CoTTime(t)
+	e.Start = CoTTime(t)
+	e.Stale = CoTTime(stale)
+	return nil
+}
+
+// SetTTL sets the stale time to the event time plus d, the period for which
+// the event remains valid. A TTL shorter than the minimum stale offset (5s)
+// is raised to that minimum. A TTL longer than the maximum stale offset
+// (7 days) is rejected with an error wrapping ErrInvalidInput and the event
+// is left unchanged, as is an event whose time is not set.
+func (e *Event) SetTTL(d time.Duration) error {
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+	if e.Time.Time().IsZero() {
+		return fmt.Errorf("event time not set: %w", ErrInvalidInput)
+	}
+	if d > maxStaleOffset {
+		return fmt.Errorf("TTL %s exceeds maximum stale offset %s: %w", d, maxStaleOffset, ErrInvalidInput)
+	}
+	if d < minStaleOffset {
+		d = minStaleOffset
+	}
+	e.Stale = CoTTime(e.Time.Time().Add(d))
+	return nil
+}
+
+// AddLink adds a link to the event
+func (e *Event) AddLink(link *Link) {
+	e.Links = append(e.Links, *link)
+}
+
+// InjectIdentity adds identity information to the event
+func (e *Event) InjectIdentity(selfUid, groupName, groupRole string) {
+	// Add group information
+	if e.Detail == nil {
+		e.Detail = &Detail{}
+	}
+	if e.Detail.Group == nil {
+		e.Detail.Group = &Group{}
+	}
+	e.Detail.Group.Name = groupName
+	e.Detail.Group.Role = groupRole
+
+	// Add self link if not already present
+	for _, l := range e.Links {
+		if l.Relation == "p-p" && l.Uid == selfUid {
+			return
+		}
+	}
+	e.AddLink(&Link{
+		Uid:      selfUid,
+		Type:     "t-x-takp-v",
+		Relation: "p-p",
+	})
+}
+
+// Rekey changes the event's uid to newUID everywhere the old uid refers to
+// the event itself: links pointing at the old uid (such as a self p-p
+// link), and the chat sender and chatgrp members. It is intended for
+// cloned events that describe a new entity. A chat that was decoded is
+// re-encoded from its fields, dropping attributes that are not modelled.
+// newUID is checked with ValidateUID and the event is left unchanged if
+// it is invalid.
+func (e *Event) Rekey(newUID string) error {
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+	if err := ValidateUID(newUID); err != nil {
+		return err
+	}
+	old := e.Uid
+	e.Uid = newUID
+	if old == "" || old == newUID {
+		return nil
+	}
+	for i := range e.Links {
+		if e.Links[i].Uid == old {
+			e.Links[i].Uid = newUID
+		}
+	}
+	if e.Detail == nil || e.Detail.Chat == nil {
+		return nil
+	}
+	c := e.Detail.Chat
+	changed := false
+	if c.Sender == old {
+		c.Sender = newUID
+		changed = true
+	}
+	for i := range c.ChatGrps {
+		g := &c.ChatGrps[i]
+		for _, u := range []*string{&g.UID0, &g.UID1, &g.UID2} {
+			if *u == old {
+				*u = newUID
+				changed = true
+			}
 		}
 	}
-
+	if changed {
+		c.Raw = nil
+	}
 	return nil
 }
 
-// AddLink adds a link to the event
-func (e *Event) AddLink(link *Link) {
-	e.Links = append(e.Links, *link)
+// TypeSegments splits a CoT type into its dash-separated segments.
+// For example, "a-f-G-U-C" returns ["a" "f" "G" "U" "C"]. An empty type
+// returns nil.
+func TypeSegments(typ string) []string {
+	if typ == "" {
+		return nil
+	}
+	return strings.Split(typ, "-")
 }
 
-// InjectIdentity adds identity information to the event
-func (e *Event) InjectIdentity(selfUid, groupName, groupRole string) {
-	// Add group information
-	if e.Detail == nil {
-		e.Detail = &Detail{}
-	}
-	if e.Detail.Group == nil {
-		e.Detail.Group = &Group{}
+// TypeAffiliation returns the affiliation character (the second segment)
+// of an atomic ("a-") type, for example 'f' for "a-f-G". The boolean is
+// false for non-atomic types or when the segment is not a single character.
+func TypeAffiliation(typ string) (byte, bool) {
+	return atomSegment(typ, 1)
+}
+
+// TypeDimension returns the battle dimension character (the third segment)
+// of an atomic ("a-") type, for example 'G' for "a-f-G". The boolean is
+// false for non-atomic types or when the segment is not a single character.
+func TypeDimension(typ string) (byte, bool) {
+	return atomSegment(typ, 2)
+}
+
+// atomSegment returns the single-character segment at index i of an
+// atomic type.
+func atomSegment(typ string, i int) (byte, bool) {
+	parts := TypeSegments(typ)
+	if len(parts) <= i || parts[0] != "a" || len(parts[i]) != 1 {
+		return 0, false
 	}
-	e.Detail.Group.Name = groupName
-	e.Detail.Group.Role = groupRole
+	return parts[i][0], true
+}
 
-	// Add self link if not already present
-	for _, l := range e.Links {
-		if l.Relation == "p-p" && l.Uid == selfUid {
-			return
+// wildcardAffiliations are the affiliations that catalog wildcard types
+// ("a-.-...") are expanded into when the catalog is loaded.
+var wildcardAffiliations = []string{"f", "h", "n", "u"}
+
+// WildcardForm returns the affiliation-wildcard form of an atomic type,
+// with the affiliation segment replaced by ".", for example "a-.-G-E-X-N"
+// for "a-h-G-E-X-N". Storing metadata under this form lets one entry serve
+// every affiliation of a type family.
+//
+// The catalog stores wildcard types expanded into each affiliation, so the
+// form is reported as existing when every expansion is present or when the
+// wildcard itself was registered. The boolean is false for non-atomic types
+// and for types with no wildcard form.
+func WildcardForm(typ string) (string, bool) {
+	if _, ok := TypeAffiliation(typ); !ok {
+		return "", false
+	}
+	cat := cottypes.GetCatalog()
+	if cat == nil {
+		return "", false
+	}
+	ctx := context.Background()
+	parts := TypeSegments(typ)
+	parts[1] = "."
+	form := strings.Join(parts, "-")
+	if _, err := cat.GetType(ctx, form); err == nil {
+		return form, true
+	}
+	for _, aff := range wildcardAffiliations {
+		parts[1] = aff
+		if _, err := cat.GetType(ctx, strings.Join(parts, "-")); err != nil {
+			return "", false
 		}
 	}
-	e.AddLink(&Link{
-		Uid:      selfUid,
-		Type:     "t-x-takp-v",
-		Relation: "p-p",
-	})
+	return form, true
 }
 
 // Is checks if the event matches a predicate
 func (e *Event) Is(pred string) bool {
-	parts := strings.Split(e.Type, "-")
+	parts := TypeSegments(e.Type)
 	if len(parts) < 2 {
 		return false
 	}
@@ -1918,6 +3694,56 @@ func (e *Event) Is(pred string) bool {
 	}
 }
 
+// Classification returns the event's caveat and releasableTo attributes,
+// for filtering and routing events in classified deployments. Both are
+// empty for unmarked events.
+func (e *Event) Classification() (caveat, relTo string) {
+	if e == nil {
+		return "", ""
+	}
+	return e.Caveat, e.ReleasableTo
+}
+
+// Postures returned by Event.Posture.
+const (
+	PostureFriend  = "friend"
+	PostureHostile = "hostile"
+	PostureNeutral = "neutral"
+	PostureUnknown = "unknown"
+	PosturePending = "pending"
+	PostureNone    = "none"
+)
+
+// Posture classifies the event by the affiliation segment of its type into
+// one of the Posture constants, as used for friend/hostile coloring.
+// Assumed friends ("a") count as friendly, and suspects, jokers and fakers
+// ("s", "j", "k") as hostile. Non-atomic types and the "o" (none
+// specified) and "x" (other) affiliations yield PostureNone; a wildcard or
+// unrecognized affiliation yields PostureUnknown.
+func (e *Event) Posture() string {
+	if e == nil || !strings.HasPrefix(e.Type, "a-") {
+		return PostureNone
+	}
+	aff, ok := TypeAffiliation(e.Type)
+	if !ok {
+		return PostureUnknown
+	}
+	switch aff {
+	case 'f', 'a':
+		return PostureFriend
+	case 'h', 's', 'j', 'k':
+		return PostureHostile
+	case 'n':
+		return PostureNeutral
+	case 'p':
+		return PosturePending
+	case 'o', 'x':
+		return PostureNone
+	default:
+		return PostureUnknown
+	}
+}
+
 // WithLogger adds a logger to the context
 func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
 	return ctxlog.WithLogger(ctx, l)
@@ -1973,6 +3799,13 @@ func GetTypeInfoBatch(names []string) ([]cottypes.Type, error) {
 	return results, nil
 }
 
+// TypesByFamily returns all registered types grouped by the first segment
+// of their name ("a", "b", "t", "y", ...), each group sorted by name. It is
+// intended for generating reference documentation.
+func TypesByFamily() map[string][]cottypes.Type {
+	return cottypes.GetCatalog().GroupByPrefix(context.Background())
+}
+
 // FindTypesByDescription searches for types matching the given description.
 // The search is case-insensitive and matches partial descriptions.
 //
@@ -2001,12 +3834,108 @@ func FindTypesByFullName(name string) []cottypes.Type {
 	return cottypes.GetCatalog().FindByFullName(context.Background(), name)
 }
 
+// SuggestTypes returns up to limit types for autocomplete as a user types.
+// A type matches when its code starts with prefix or when any word of its
+// full name starts with prefix (case-insensitive). Results are ranked by
+// depth so that shorter codes come first, then alphabetically by code.
+// If limit is not positive all matches are returned. An empty prefix
+// returns no suggestions.
+func SuggestTypes(prefix string, limit int) []cottypes.Type {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return nil
+	}
+	cat := cottypes.GetCatalog()
+	if cat == nil {
+		return nil
+	}
+
+	upper := strings.ToUpper(prefix)
+	isSep := func(r rune) bool {
+		return r == '/' || r == ' ' || r == '-' || r == '_'
+	}
+	var matches []cottypes.Type
+	for _, t := range cat.GetAllTypes(context.Background()) {
+		if strings.HasPrefix(t.Name, prefix) {
+			matches = append(matches, t)
+			continue
+		}
+		for _, word := range strings.FieldsFunc(strings.ToUpper(t.FullName), isSep) {
+			if strings.HasPrefix(word, upper) {
+				matches = append(matches, t)
+				break
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		di := strings.Count(matches[i].Name, "-")
+		dj := strings.Count(matches[j].Name, "-")
+		if di != dj {
+			return di < dj
+		}
+		if len(matches[i].Name) != len(matches[j].Name) {
+			return len(matches[i].Name) < len(matches[j].Name)
+		}
+		return matches[i].Name < matches[j].Name
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
 // UnmarshalXMLEvent parses an XML byte slice into an Event. The returned Event
 // is obtained from an internal pool; callers should release it with
 // ReleaseEvent when finished.
 // The function uses the standard library's encoding/xml Decoder under the hood.
 func UnmarshalXMLEvent(ctx context.Context, data []byte) (*Event, error) {
+	return unmarshalXMLEvent(ctx, data, nil)
+}
+
+// ParseEvent decodes and validates data like UnmarshalXMLEvent and passes
+// the event to use, returning use's error. The event is released to the
+// pool when use returns, including when it panics, so use must not retain
+// the event. use is not called if decoding fails.
+func ParseEvent(ctx context.Context, data []byte, use func(*Event) error) error {
+	evt, err := UnmarshalXMLEvent(ctx, data)
+	if err != nil {
+		return err
+	}
+	defer ReleaseEvent(evt)
+	return use(evt)
+}
+
+// DecodeStats describes a single call to UnmarshalXMLEventStats.
+type DecodeStats struct {
+	// Bytes is the size of the input.
+	Bytes int
+	// DecodeDuration is the time spent parsing the XML.
+	DecodeDuration time.Duration
+	// ValidateDuration is the time spent validating the decoded event.
+	ValidateDuration time.Duration
+	// DetailCount is the number of child elements of <detail>.
+	DetailCount int
+}
+
+// UnmarshalXMLEventStats behaves like UnmarshalXMLEvent and also reports
+// the input size, the time spent decoding and validating, and the number
+// of detail elements, for export as metrics. On error the stats cover the
+// stages that ran before the failure.
+func UnmarshalXMLEventStats(ctx context.Context, data []byte) (*Event, DecodeStats, error) {
+	var stats DecodeStats
+	evt, err := unmarshalXMLEvent(ctx, data, &stats)
+	return evt, stats, err
+}
+
+// unmarshalXMLEvent implements UnmarshalXMLEvent, filling stats if it is
+// not nil.
+func unmarshalXMLEvent(ctx context.Context, data []byte, stats *DecodeStats) (*Event, error) {
 	logger := LoggerFromContext(ctx)
+	if stats != nil {
+		stats.Bytes = len(data)
+	}
 
 	if len(data) > int(currentMaxXMLSize()) {
 		logger.Error("xml size exceeds limit",
@@ -2024,8 +3953,10 @@ func UnmarshalXMLEvent(ctx context.Context, data []byte) (*Event, error) {
 	// Check namespace length
 	if idx := bytes.Index(data, []byte(`xmlns="`)); idx >= 0 {
 		end := bytes.Index(data[idx+7:], []byte(`"`))
-		if end > 1024 {
-			logger.Error("namespace value too long")
+		if limit := currentMaxNamespaceLen(); limit > 0 && int64(end) > limit {
+			logger.Error("namespace value too long",
+				"length", end,
+				"limit", limit)
 			return nil, ErrInvalidInput
 		}
 	}
@@ -2034,10 +3965,19 @@ func UnmarshalXMLEvent(ctx context.Context, data []byte) (*Event, error) {
 	defer putDecoder(pd)
 
 	evt := getEvent()
-	if err := decodeWithLimits(pd.dec, evt); err != nil {
+	decodeStart := time.Now()
+	err := decodeWithLimits(pd.dec, evt)
+	if stats != nil {
+		stats.DecodeDuration = time.Since(decodeStart)
+		if evt.Detail != nil {
+			stats.DetailCount = evt.Detail.elements
+		}
+	}
+	if err != nil {
 		ReleaseEvent(evt)
-		logger.Error("failed to decode XML", "error", err)
-		return nil, fmt.Errorf("failed to decode XML: %w", err)
+		offset := pd.dec.InputOffset()
+		logger.Error("failed to decode XML", "offset", offset, "error", err)
+		return nil, &DecodeError{Offset: offset, Err: err}
 	}
 
 	if evt.Type == "b-t-f" && evt.Detail != nil && evt.Detail.Remarks != nil {
@@ -2047,7 +3987,22 @@ func UnmarshalXMLEvent(ctx context.Context, data []byte) (*Event, error) {
 		evt.Message = evt.Detail.Remarks.Text
 	}
 
-	if err := evt.ValidateAt(time.Now().UTC()); err != nil {
+	if normalizeCoordinates.Load() && !evt.NoPoint {
+		lat, lon, changed := NormalizeLatLon(evt.Point.Lat, evt.Point.Lon)
+		if changed {
+			logger.Debug("normalized out-of-range coordinates",
+				"lat", evt.Point.Lat, "lon", evt.Point.Lon,
+				"normalized_lat", lat, "normalized_lon", lon)
+			evt.Point.Lat, evt.Point.Lon = lat, lon
+		}
+	}
+
+	validateStart := time.Now()
+	err = evt.ValidateAt(validateStart.UTC())
+	if stats != nil {
+		stats.ValidateDuration = time.Since(validateStart)
+	}
+	if err != nil {
 		ReleaseEvent(evt)
 		logger.Error("event validation failed", "error", err)
 		return nil, err
@@ -2074,6 +4029,118 @@ func ValidateLatLon(lat, lon float64) error {
 	return nil
 }
 
+// validateStrokeColor checks that a strokeColor event attribute is empty
+// or an 8-digit hexadecimal ARGB value such as "ff00ff00".
+func validateStrokeColor(c string) error {
+	if c == "" {
+		return nil
+	}
+	if len(c) != 8 {
+		return fmt.Errorf("invalid strokeColor %q: want 8 hex digits ARGB: %w", c, ErrInvalidInput)
+	}
+	for i := 0; i < len(c); i++ {
+		if !isHexDigit(c[i]) {
+			return fmt.Errorf("invalid strokeColor %q: want 8 hex digits ARGB: %w", c, ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// validateUserIcon checks that a usericon event attribute contains no
+// control characters. Empty values are allowed.
+func validateUserIcon(path string) error {
+	for _, r := range path {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("invalid usericon %q: contains control character: %w", path, ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// NormalizeLatLon brings out-of-range coordinates back into the ranges
+// accepted by ValidateLatLon. Longitude is wrapped into [-180, 180], so 190
+// becomes -170. A latitude beyond a pole is folded back over it, which also
+// moves the longitude to the opposite meridian: (95, 10) becomes (85, -170).
+// Coordinates that are already valid, and NaN or infinite values, are
+// returned unchanged with changed set to false.
+func NormalizeLatLon(lat, lon float64) (nlat, nlon float64, changed bool) {
+	if math.IsNaN(lat) || math.IsNaN(lon) || math.IsInf(lat, 0) || math.IsInf(lon, 0) {
+		return lat, lon, false
+	}
+	if ValidateLatLon(lat, lon) == nil {
+		return lat, lon, false
+	}
+	if lat < -90 || lat > 90 {
+		t := math.Mod(lat+90, 360)
+		if t < 0 {
+			t += 360
+		}
+		if t <= 180 {
+			lat = t - 90
+		} else {
+			lat = 270 - t
+			lon += 180
+		}
+	}
+	if lon < -180 || lon > 180 {
+		lon = math.Mod(lon+180, 360)
+		if lon < 0 {
+			lon += 360
+		}
+		lon -= 180
+	}
+	return lat, lon, true
+}
+
+// ValidateUIDUUID checks that uid is an RFC 4122 UUID in its canonical
+// 8-4-4-4-12 hexadecimal form, for example
+// "0c4e1f8a-2b6d-4c3e-9f1a-7d2e5b8c9a10". If prefixes were configured with
+// SetRequireUUID, a uid made of one of them followed by a UUID is also
+// accepted. Errors wrap ErrInvalidUID.
+func ValidateUIDUUID(uid string) error {
+	if isUUID(uid) {
+		return nil
+	}
+	if ps := uuidPrefixes.Load(); ps != nil {
+		for _, p := range *ps {
+			if rest, ok := strings.CutPrefix(uid, p); ok && p != "" && isUUID(rest) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("uid %q is not a UUID: %w", uid, ErrInvalidUID)
+}
+
+// isUUID reports whether s is a canonical RFC 4122 UUID with a known
+// version (1-8) and the RFC 4122 variant.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHexDigit(c) {
+				return false
+			}
+		}
+	}
+	if s[14] < '1' || s[14] > '8' {
+		return false
+	}
+	return strings.IndexByte("89abAB", s[19]) >= 0
+}
+
+// isHexDigit reports whether c is an ASCII hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
 // ValidateUID checks if a UID is valid.
 // It rejects empty values, leading hyphens, double dots,
 // whitespace, and UIDs longer than 64 characters.
@@ -2099,60 +4166,105 @@ func ValidateUID(uid string) error {
 // ToXML serialises an Event to CoT-compliant XML.
 // Attribute values are escaped to prevent XML-injection.
 // The <point> element is always emitted so that the
-// zero coordinate (0° N 0° E) is representable.
+// zero coordinate (0° N 0° E) is representable. Events with
+// NoPoint set are written with an unknown-position sentinel point.
 func (e *Event) ToXML() ([]byte, error) {
+	return e.marshalXML(true), nil
+}
+
+// ToXMLFragment serialises an Event like ToXML but without the leading
+// XML declaration, for embedding the <event> element in a larger document
+// such as a data package manifest or a multi-event wrapper.
+func (e *Event) ToXMLFragment() ([]byte, error) {
+	return e.marshalXML(false), nil
+}
+
+// marshalXML returns the event's XML, preceded by the XML declaration if
+// header is set.
+func (e *Event) marshalXML(header bool) []byte {
 	buf := getBuffer()
 	defer putBuffer(buf)
 	buf.Grow(256)
-	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-	var tmp [32]byte
-
-	// <event>
-	buf.WriteString("<event")
-	if e.Version != "" {
-		buf.WriteString(` version="`)
-		buf.WriteString(escapeAttr(e.Version))
-		buf.WriteByte('"')
-	}
-	if e.Type != "" {
-		buf.WriteString(` type="`)
-		buf.WriteString(escapeAttr(e.Type))
-		buf.WriteByte('"')
-	}
-	if e.How != "" {
-		buf.WriteString(` how="`)
-		buf.WriteString(escapeAttr(e.How))
-		buf.WriteByte('"')
+	if header {
+		buf.WriteString(xmlHeader)
 	}
-	if e.Uid != "" {
-		buf.WriteString(` uid="`)
-		buf.WriteString(escapeAttr(e.Uid))
-		buf.WriteByte('"')
+	e.writeXML(buf)
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out
+}
+
+// xmlHeader is the XML declaration written before a serialised event.
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+// writeEventAttr writes the named <event> attribute, if it is set.
+func (e *Event) writeEventAttr(buf *bytes.Buffer, name string) {
+	var value string
+	switch name {
+	case "version":
+		value = escapeAttr(e.Version)
+	case "type":
+		value = escapeAttr(e.Type)
+	case "how":
+		value = escapeAttr(e.How)
+	case "uid":
+		value = escapeAttr(e.Uid)
+	case "time":
+		value = preservedTime(e.origTime, e.Time)
+	case "start":
+		value = preservedTime(e.origStart, e.Start)
+	case "stale":
+		value = preservedTime(e.origStale, e.Stale)
+	case "strokeColor":
+		value = escapeAttr(e.StrokeColor)
+	case "usericon":
+		value = escapeAttr(e.UserIcon)
+	case "caveat":
+		value = escapeAttr(e.Caveat)
+	case "releasableTo":
+		value = escapeAttr(e.ReleasableTo)
 	}
-	if !e.Time.Time().IsZero() {
-		buf.WriteString(` time="`)
-		buf.WriteString(e.Time.Time().UTC().Format(CotTimeFormat))
-		buf.WriteByte('"')
+	if value == "" {
+		return
 	}
-	if !e.Start.Time().IsZero() {
-		buf.WriteString(` start="`)
-		buf.WriteString(e.Start.Time().UTC().Format(CotTimeFormat))
-		buf.WriteByte('"')
+	buf.WriteByte(' ')
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	buf.WriteString(value)
+	buf.WriteByte('"')
+}
+
+// attrTime formats t for an event attribute, or returns "" if it is zero.
+func attrTime(t CoTTime) string {
+	if t.Time().IsZero() {
+		return ""
 	}
-	if !e.Stale.Time().IsZero() {
-		buf.WriteString(` stale="`)
-		buf.WriteString(e.Stale.Time().UTC().Format(CotTimeFormat))
-		buf.WriteByte('"')
+	return t.Time().UTC().Format(CotTimeFormat)
+}
+
+// preservedTime returns orig, the attribute string t was decoded from, if
+// t still holds that instant, and the normalized form of t otherwise.
+func preservedTime(orig string, t CoTTime) string {
+	if orig != "" {
+		if p, err := parseCoTTime(orig); err == nil && p.Equal(t.Time()) {
+			return escapeAttr(orig)
+		}
 	}
-	if e.StrokeColor != "" {
-		buf.WriteString(` strokeColor="`)
-		buf.WriteString(escapeAttr(e.StrokeColor))
-		buf.WriteByte('"')
+	return attrTime(t)
+}
+
+// writeXML appends the <event> element for e to buf.
+func (e *Event) writeXML(buf *bytes.Buffer) {
+	var tmp [32]byte
+	pt := e.Point
+	if e.NoPoint {
+		pt = unknownPoint
 	}
-	if e.UserIcon != "" {
-		buf.WriteString(` usericon="`)
-		buf.WriteString(escapeAttr(e.UserIcon))
-		buf.WriteByte('"')
+
+	// <event>
+	buf.WriteString("<event")
+	for _, name := range *eventAttrOrder.Load() {
+		e.writeEventAttr(buf, name)
 	}
 	for _, a := range e.UnknownAttrs {
 		buf.WriteByte(' ')
@@ -2170,29 +4282,30 @@ func (e *Event) ToXML() ([]byte, error) {
 	// <point>
 	buf.WriteString("  <point")
 	buf.WriteString(` lat="`)
-	buf.Write(strconv.AppendFloat(tmp[:0], e.Point.Lat, 'f', 6, 64))
+	buf.Write(strconv.AppendFloat(tmp[:0], pt.Lat, 'f', 6, 64))
 	buf.WriteString(`" lon="`)
-	buf.Write(strconv.AppendFloat(tmp[:0], e.Point.Lon, 'f', 6, 64))
+	buf.Write(strconv.AppendFloat(tmp[:0], pt.Lon, 'f', 6, 64))
 	buf.WriteByte('"')
-	if e.Point.Hae != 0 {
+	if pt.Hae != 0 {
 		buf.WriteString(` hae="`)
-		buf.Write(strconv.AppendFloat(tmp[:0], e.Point.Hae, 'f', 1, 64))
+		buf.Write(strconv.AppendFloat(tmp[:0], pt.Hae, 'f', 1, 64))
 		buf.WriteByte('"')
 	}
-	if e.Point.Ce != 0 {
+	if pt.Ce != 0 {
 		buf.WriteString(` ce="`)
-		buf.Write(strconv.AppendFloat(tmp[:0], e.Point.Ce, 'f', 1, 64))
+		buf.Write(strconv.AppendFloat(tmp[:0], pt.Ce, 'f', 1, 64))
 		buf.WriteByte('"')
 	}
-	if e.Point.Le != 0 {
+	if pt.Le != 0 {
 		buf.WriteString(` le="`)
-		buf.Write(strconv.AppendFloat(tmp[:0], e.Point.Le, 'f', 1, 64))
+		buf.Write(strconv.AppendFloat(tmp[:0], pt.Le, 'f', 1, 64))
 		buf.WriteByte('"')
 	}
 	buf.WriteString("/>\n")
 
 	// <detail> (optional)
 	if e.Detail != nil {
+		detailStart := buf.Len()
 		buf.WriteString("  <detail>\n")
 		if c := e.Detail.Contact; c != nil {
 			buf.WriteString("    <contact")
@@ -2201,6 +4314,16 @@ func (e *Event) ToXML() ([]byte, error) {
 				buf.WriteString(escapeAttr(c.Callsign))
 				buf.WriteByte('"')
 			}
+			if c.Phone != "" {
+				buf.WriteString(` phone="`)
+				buf.WriteString(escapeAttr(c.Phone))
+				buf.WriteByte('"')
+			}
+			if c.EmailAddress != "" {
+				buf.WriteString(` emailAddress="`)
+				buf.WriteString(escapeAttr(c.EmailAddress))
+				buf.WriteByte('"')
+			}
 			buf.WriteString("/>\n")
 		}
 		if g := e.Detail.Group; g != nil {
@@ -2480,11 +4603,27 @@ func (e *Event) ToXML() ([]byte, error) {
 		if e.Detail.Marti != nil {
 			buf.WriteString("    <marti>\n")
 			for _, d := range e.Detail.Marti.Dest {
+				if d.rawCurrent() {
+					buf.WriteString("      ")
+					buf.Write(d.Raw)
+					buf.WriteByte('\n')
+					continue
+				}
 				buf.WriteString("      <dest")
-				if d.Callsign != "" {
-					buf.WriteString(` callsign="`)
-					buf.WriteString(escapeAttr(d.Callsign))
-					buf.WriteByte('"')
+				for _, a := range [...]struct{ name, value string }{
+					{"callsign", d.Callsign},
+					{"uid", d.UID},
+					{"group", d.Group},
+					{"mission", d.Mission},
+					{"after", d.After},
+				} {
+					if a.value != "" {
+						buf.WriteByte(' ')
+						buf.WriteString(a.name)
+						buf.WriteString(`="`)
+						buf.WriteString(escapeAttr(a.value))
+						buf.WriteByte('"')
+					}
 				}
 				buf.WriteString("/>")
 				buf.WriteByte('\n')
@@ -2561,9 +4700,47 @@ func (e *Event) ToXML() ([]byte, error) {
 			buf.Write(e.Detail.Shape.Raw)
 			buf.WriteByte('\n')
 		}
+		if e.Detail.UserIcon != nil {
+			buf.WriteString("    ")
+			buf.Write(e.Detail.UserIcon.Raw)
+			buf.WriteByte('\n')
+		}
+		if lo := e.Detail.LabelsOn; lo != nil {
+			buf.WriteString("    ")
+			if len(lo.Raw) > 0 {
+				buf.Write(lo.Raw)
+			} else {
+				buf.WriteString(`<labelson value="`)
+				buf.WriteString(strconv.FormatBool(lo.Value))
+				buf.WriteString(`"/>`)
+			}
+			buf.WriteByte('\n')
+		}
+		if b := e.Detail.Bullseye; b != nil {
+			data := []byte(b.Raw)
+			if len(data) == 0 {
+				data, _ = xml.Marshal(b)
+			}
+			if len(data) > 0 {
+				buf.WriteString("    ")
+				buf.Write(data)
+				buf.WriteByte('\n')
+			}
+		}
+		if m := e.Detail.Medevac; m != nil {
+			data := []byte(m.Raw)
+			if len(data) == 0 {
+				data, _ = xml.Marshal(m)
+			}
+			if len(data) > 0 {
+				buf.WriteString("    ")
+				buf.Write(data)
+				buf.WriteByte('\n')
+			}
+		}
 		if e.Detail.Remarks != nil {
 			r := e.Detail.Remarks
-			if len(r.Raw) > 0 && r.Text == "" && r.Source == "" && r.SourceID == "" && r.To == "" && r.Time.Time().IsZero() {
+			if r.rawOnly() {
 				buf.WriteString("    ")
 				buf.Write(r.Raw)
 				buf.WriteByte('\n')
@@ -2589,6 +4766,16 @@ func (e *Event) ToXML() ([]byte, error) {
 					buf.WriteString(r.Time.Time().UTC().Format(CotTimeFormat))
 					buf.WriteByte('"')
 				}
+				if r.Keywords != "" {
+					buf.WriteString(` keywords="`)
+					buf.WriteString(escapeAttr(r.Keywords))
+					buf.WriteByte('"')
+				}
+				if r.Color != "" {
+					buf.WriteString(` color="`)
+					buf.WriteString(escapeAttr(r.Color))
+					buf.WriteByte('"')
+				}
 				if r.Text == "" {
 					buf.WriteString("/>")
 					buf.WriteByte('\n')
@@ -2599,12 +4786,46 @@ func (e *Event) ToXML() ([]byte, error) {
 				}
 			}
 		}
+		if r := e.Detail.Radio; r != nil {
+			data := []byte(r.Raw)
+			if len(data) == 0 {
+				data, _ = xml.Marshal(r)
+			}
+			if len(data) > 0 {
+				buf.WriteString("    ")
+				buf.Write(data)
+				buf.WriteByte('\n')
+			}
+		}
+		if u := e.Detail.UASTelemetry; u != nil {
+			data := []byte(u.Raw)
+			if len(data) == 0 {
+				data, _ = xml.Marshal(u)
+			}
+			if len(data) > 0 {
+				buf.WriteString("    ")
+				buf.Write(data)
+				buf.WriteByte('\n')
+			}
+		}
+		if sig := e.Detail.Signature; sig != nil {
+			buf.WriteString("    ")
+			sig.write(buf)
+			buf.WriteByte('\n')
+		}
 		for _, raw := range e.Detail.Unknown {
 			buf.WriteString("    ")
 			buf.Write(raw)
 			buf.WriteByte('\n')
 		}
-		buf.WriteString("  </detail>\n")
+		if buf.Len() == detailStart+len("  <detail>\n") {
+			// Nothing was written inside the detail; keep it self-closing
+			// rather than emitting a whitespace-only block.
+			buf.Truncate(detailStart)
+			buf.WriteString("  <detail/>\n")
+		} else {
+			buf.WriteString("  </detail>\n")
+		}
 	}
 
 	// <link> (0..n)
@@ -2625,13 +4846,16 @@ func (e *Event) ToXML() ([]byte, error) {
 			buf.WriteString(escapeAttr(l.Relation))
 			buf.WriteByte('"')
 		}
+		if l.Children != "" {
+			buf.WriteByte('>')
+			buf.WriteString(l.Children)
+			buf.WriteString("</link>\n")
+			continue
+		}
 		buf.WriteString("/>\n")
 	}
 
 	buf.WriteString("</event>")
-	out := make([]byte, buf.Len())
-	copy(out, buf.Bytes())
-	return out, nil
 }
 
 // SetEventHowFromDescriptor sets the how field on an event using a descriptor.
@@ -2672,6 +4896,57 @@ func (e *Event) AddValidatedLink(uid, linkType, relation string) error {
 	return nil
 }
 
+// ValidateLinkSemantics reports links whose type is obviously inconsistent
+// with their relation. It is not part of Validate because producers vary
+// widely in how they populate links; call it explicitly to opt in.
+//
+// Currently a parent-point ("p-p") link must refer to a unit ("a-" prefix)
+// or a presence event ("t-x-takp-v").
+func (e *Event) ValidateLinkSemantics() error {
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+	for i, l := range e.Links {
+		if l.Relation == "p-p" && !strings.HasPrefix(l.Type, "a-") && l.Type != "t-x-takp-v" {
+			return fmt.Errorf("link %d: relation p-p with type %s: %w", i, l.Type, ErrInvalidRelation)
+		}
+	}
+	return nil
+}
+
+// LinkedUIDs returns the uids referenced by the event's links in the order
+// they first appear, without duplicates. Links with an empty uid are
+// ignored. Route waypoints in the detail are not included.
+func (e *Event) LinkedUIDs() []string {
+	if e == nil {
+		return nil
+	}
+	var uids []string
+	seen := make(map[string]bool, len(e.Links))
+	for _, l := range e.Links {
+		if l.Uid == "" || seen[l.Uid] {
+			continue
+		}
+		seen[l.Uid] = true
+		uids = append(uids, l.Uid)
+	}
+	return uids
+}
+
+// LinksByRelation returns the event's links whose relation equals rel.
+func (e *Event) LinksByRelation(rel string) []Link {
+	if e == nil {
+		return nil
+	}
+	var links []Link
+	for _, l := range e.Links {
+		if l.Relation == rel {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
 // GetHowDescriptor returns a human-readable description of the how value.
 // For example: "h-g-i-g-o" returns "gps".
 func GetHowDescriptor(how string) (string, error) {
@@ -2683,3 +4958,16 @@ func GetHowDescriptor(how string) (string, error) {
 func GetRelationDescription(relation string) (string, error) {
 	return cottypes.GetRelationDescription(relation)
 }
+
+// AllHows returns every how value in the catalog, for example to populate
+// a selection list. The returned slice is a copy and may be modified.
+func AllHows() []cottypes.HowInfo {
+	return append([]cottypes.HowInfo(nil), cottypes.GetAllHows()...)
+}
+
+// FindHows returns the how values whose descriptor or nickname contains
+// descriptor (case-insensitive). An empty descriptor returns all values.
+// The returned slice is a copy and may be modified.
+func FindHows(descriptor string) []cottypes.HowInfo {
+	return append([]cottypes.HowInfo(nil), cottypes.FindHowsByDescriptor(descriptor)...)
+}
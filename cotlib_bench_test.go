@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/xml"
+	"io"
 	"testing"
+	"time"
 )
 
 func BenchmarkNewEvent(b *testing.B) {
@@ -29,6 +31,20 @@ func BenchmarkToXML(b *testing.B) {
 	}
 }
 
+func BenchmarkEventWriter(b *testing.B) {
+	evt, err := NewEvent("bench", "a-f-G", 30.0, -85.0, 0.0)
+	if err != nil {
+		b.Fatalf("NewEvent returned error: %v", err)
+	}
+	w := NewEventWriter(io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := w.Write(evt); err != nil {
+			b.Fatalf("Write error: %v", err)
+		}
+	}
+}
+
 func BenchmarkUnmarshalXMLEvent(b *testing.B) {
 	evt, err := NewEvent("bench", "a-f-G", 30.0, -85.0, 0.0)
 	if err != nil {
@@ -80,3 +96,73 @@ func BenchmarkDecodeWithLimits(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkUpdatePosition(b *testing.B) {
+	evt, err := NewEvent("bench", "a-f-G", 30.0, -85.0, 0.0)
+	if err != nil {
+		b.Fatalf("NewEvent returned error: %v", err)
+	}
+	evt.Detail = &Detail{
+		Contact: &Contact{Callsign: "BENCH"},
+		Track:   &Track{Raw: []byte(`<track course="90" speed="10"/>`)},
+	}
+	now := time.Now().UTC()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := evt.UpdatePosition(30.0, -85.0, 0.0, now); err != nil {
+			b.Fatalf("UpdatePosition error: %v", err)
+		}
+	}
+}
+
+func BenchmarkUpdatePositionFullValidate(b *testing.B) {
+	evt, err := NewEvent("bench", "a-f-G", 30.0, -85.0, 0.0)
+	if err != nil {
+		b.Fatalf("NewEvent returned error: %v", err)
+	}
+	evt.Detail = &Detail{
+		Contact: &Contact{Callsign: "BENCH"},
+		Track:   &Track{Raw: []byte(`<track course="90" speed="10"/>`)},
+	}
+	now := time.Now().UTC()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		evt.Point.Lat = 30.0
+		evt.Point.Lon = -85.0
+		evt.Time = CoTTime(now)
+		evt.Start = CoTTime(now)
+		evt.Stale = CoTTime(now.Add(defaultStaleOffset))
+		if err := evt.Validate(); err != nil {
+			b.Fatalf("Validate error: %v", err)
+		}
+	}
+}
+
+func BenchmarkValidateDetailSchemas(b *testing.B) {
+	evt, err := NewEvent("bench", "a-f-G", 30.0, -85.0, 0.0)
+	if err != nil {
+		b.Fatalf("NewEvent returned error: %v", err)
+	}
+	evt.Detail = &Detail{
+		Contact: &Contact{Callsign: "BENCH"},
+		Track:   &Track{Raw: []byte(`<track course="90" speed="10"/>`)},
+	}
+	defer SetDetailSchemaValidation(true)
+	for _, enabled := range []bool{true, false} {
+		name := "enabled"
+		if !enabled {
+			name = "disabled"
+		}
+		b.Run(name, func(b *testing.B) {
+			SetDetailSchemaValidation(enabled)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := evt.Validate(); err != nil {
+					b.Fatalf("Validate error: %v", err)
+				}
+			}
+		})
+	}
+}
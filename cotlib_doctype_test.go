@@ -6,6 +6,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/NERVsystems/cotlib/cottypes"
 )
 
 func TestTypeRegistrationsRejectDOCTYPE(t *testing.T) {
@@ -61,3 +64,71 @@ func TestTypeRegistrationsRejectDOCTYPE(t *testing.T) {
 		}
 	})
 }
+
+func TestParsersRejectExternalEntities(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Format(CotTimeFormat)
+	stale := time.Now().UTC().Add(time.Minute).Format(CotTimeFormat)
+	// declared is a classic XXE payload; undeclared references the entity
+	// without a DTD so it reaches the decoder itself.
+	payloads := map[string]struct{ types, load, event string }{
+		"declared": {
+			types: `<?xml version="1.0"?><!DOCTYPE types [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><types><cot cot="a-f-G-&xxe;"/></types>`,
+			load:  `<?xml version="1.0"?><!DOCTYPE types [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><types><type>a-f-G-&xxe;</type></types>`,
+			event: `<?xml version="1.0"?><!DOCTYPE event [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><event version="2.0" uid="&xxe;" type="a-f-G" how="m-g" time="` + now + `" start="` + now + `" stale="` + stale + `"><point lat="0" lon="0" hae="0" ce="1" le="1"/></event>`,
+		},
+		"undeclared": {
+			types: `<types><cot cot="a-f-G-&xxe;"/></types>`,
+			load:  `<types><type>a-f-G-&xxe;</type></types>`,
+			event: `<event version="2.0" uid="&xxe;" type="a-f-G" how="m-g" time="` + now + `" start="` + now + `" stale="` + stale + `"><point lat="0" lon="0" hae="0" ce="1" le="1"/></event>`,
+		},
+	}
+	writeTemp := func(t *testing.T, data string) string {
+		t.Helper()
+		f, err := os.CreateTemp(t.TempDir(), "xxe-*.xml")
+		if err != nil {
+			t.Fatalf("temp file: %v", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return f.Name()
+	}
+
+	for name, p := range payloads {
+		t.Run(name, func(t *testing.T) {
+			parsers := map[string]func() error{
+				"UnmarshalXMLEvent": func() error {
+					evt, err := UnmarshalXMLEvent(ctx, []byte(p.event))
+					ReleaseEvent(evt)
+					return err
+				},
+				"RegisterCoTTypesFromXMLContent": func() error {
+					return RegisterCoTTypesFromXMLContent(ctx, p.types)
+				},
+				"RegisterCoTTypesFromReader": func() error {
+					return RegisterCoTTypesFromReader(ctx, strings.NewReader(p.types))
+				},
+				"RegisterCoTTypesFromFile": func() error {
+					return RegisterCoTTypesFromFile(ctx, writeTemp(t, p.types))
+				},
+				"LoadCoTTypesFromFile": func() error {
+					return LoadCoTTypesFromFile(ctx, writeTemp(t, p.load))
+				},
+				"ValidateCoTTypesFile": func() error {
+					_, errs := ValidateCoTTypesFile(writeTemp(t, p.types))
+					return errors.Join(errs...)
+				},
+				"cottypes.RegisterXML": func() error {
+					return cottypes.RegisterXML(ctx, []byte(p.types))
+				},
+			}
+			for fn, parse := range parsers {
+				if err := parse(); err == nil {
+					t.Errorf("%s accepted an external entity payload", fn)
+				}
+			}
+		})
+	}
+}
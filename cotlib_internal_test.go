@@ -2,8 +2,15 @@ package cotlib
 
 import (
 	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/NERVsystems/cotlib/cottypes"
 )
 
 func TestMaxValueLenRace(t *testing.T) {
@@ -79,3 +86,267 @@ func TestLoggerRace(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestMaxNamespaceLen(t *testing.T) {
+	prevNS := currentMaxNamespaceLen()
+	prevTok := currentMaxTokenLen()
+	defer func() {
+		SetMaxNamespaceLen(int(prevNS))
+		SetMaxTokenLen(prevTok)
+	}()
+	// Leave room for the root element so only the namespace limit applies.
+	SetMaxTokenLen(4096)
+	SetMaxNamespaceLen(1024)
+
+	now := time.Now().UTC()
+	ns := "urn:" + strings.Repeat("x", 1021)
+	data := []byte(fmt.Sprintf(`<event xmlns="%s" version="2.0" uid="NS1" type="a-f-G" how="m-g" time="%[2]s" start="%[2]s" stale="%[3]s">`+
+		`<point lat="0" lon="0" hae="0" ce="1" le="1"/></event>`,
+		ns, now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat)))
+
+	if _, err := UnmarshalXMLEvent(context.Background(), data); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for %d byte namespace, got %v", len(ns), err)
+	}
+
+	SetMaxNamespaceLen(2048)
+	evt, err := UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal with raised limit: %v", err)
+	}
+	ReleaseEvent(evt)
+
+	SetMaxNamespaceLen(0)
+	evt, err = UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal with limit disabled: %v", err)
+	}
+	ReleaseEvent(evt)
+}
+
+func TestMaxAttributeCount(t *testing.T) {
+	prevTok := currentMaxTokenLen()
+	prevAttr := currentMaxAttrCount()
+	defer func() {
+		SetMaxTokenLen(prevTok)
+		SetMaxAttributeCount(prevAttr)
+	}()
+	// Raise the token limit so only the attribute count applies.
+	SetMaxTokenLen(1 << 20)
+
+	now := time.Now().UTC()
+	event := func(attrs int) []byte {
+		var sb strings.Builder
+		for i := 0; i < attrs; i++ {
+			fmt.Fprintf(&sb, ` a%d="x"`, i)
+		}
+		return []byte(fmt.Sprintf(`<event version="2.0" uid="ATTR1" type="a-f-G" how="m-g" time="%[1]s" start="%[1]s" stale="%[2]s">`+
+			`<point lat="0" lon="0" hae="0" ce="1" le="1"/><detail><flood%[3]s/></detail></event>`,
+			now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat), sb.String()))
+	}
+
+	if _, err := UnmarshalXMLEvent(context.Background(), event(5000)); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput for 5000 attributes, got %v", err)
+	}
+
+	evt, err := UnmarshalXMLEvent(context.Background(), event(16))
+	if err != nil {
+		t.Fatalf("unmarshal with 16 attributes: %v", err)
+	}
+	ReleaseEvent(evt)
+
+	SetMaxAttributeCount(8)
+	if _, err := UnmarshalXMLEvent(context.Background(), event(16)); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected ErrInvalidInput with lowered limit, got %v", err)
+	}
+}
+
+func TestMaxFutureStart(t *testing.T) {
+	prev := currentMaxFutureStart()
+	defer SetMaxFutureStart(prev)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	evt, err := NewEvent("FUT1", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	schedule := func(lead time.Duration) {
+		evt.Time = CoTTime(now.Add(lead))
+		evt.Start = CoTTime(now.Add(lead))
+		evt.Stale = CoTTime(now.Add(lead + time.Hour))
+	}
+
+	schedule(2 * time.Hour)
+	if err := evt.ValidateAt(now); err != nil {
+		t.Fatalf("start 2h ahead with default window: %v", err)
+	}
+
+	SetMaxFutureStart(time.Hour)
+	if err := evt.ValidateAt(now); err == nil || !strings.Contains(err.Error(), "start time") {
+		t.Fatalf("start 2h ahead with 1h window: got %v", err)
+	}
+	schedule(30 * time.Minute)
+	if err := evt.ValidateAt(now); err != nil {
+		t.Fatalf("start 30m ahead with 1h window: %v", err)
+	}
+
+	SetMaxFutureStart(0)
+	if err := evt.ValidateAt(now); err == nil {
+		t.Fatal("future start accepted with zero window")
+	}
+	schedule(0)
+	if err := evt.ValidateAt(now); err != nil {
+		t.Fatalf("start at now with zero window: %v", err)
+	}
+}
+
+func TestLenientPointDecoding(t *testing.T) {
+	evt, err := NewEvent("PT-CHILD", "a-f-G", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	base, err := evt.ToXML()
+	ReleaseEvent(evt)
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	i := strings.Index(string(base), "<point ")
+	j := i + strings.Index(string(base[i:]), "/>") + len("/>")
+	doc := string(base[:i]) +
+		`<point><lat>37.5</lat><lon>-122.25</lon><hae>10</hae><ce>5</ce><le>7</le></point>` +
+		string(base[j:])
+
+	if _, err := UnmarshalXMLEvent(context.Background(), []byte(doc)); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("strict decode error = %v, want ErrInvalidInput", err)
+	} else if !strings.Contains(err.Error(), "child element") {
+		t.Errorf("strict decode error %q does not name the child-element form", err)
+	}
+
+	SetLenientPointDecoding(true)
+	defer SetLenientPointDecoding(false)
+	got, err := UnmarshalXMLEvent(context.Background(), []byte(doc))
+	if err != nil {
+		t.Fatalf("lenient decode: %v", err)
+	}
+	defer ReleaseEvent(got)
+	if want := (Point{Lat: 37.5, Lon: -122.25, Hae: 10, Ce: 5, Le: 7}); got.Point != want {
+		t.Errorf("point = %+v, want %+v", got.Point, want)
+	}
+
+	mixed := strings.Replace(doc, `<point>`, `<point lat="1" lon="2">`, 1)
+	got2, err := UnmarshalXMLEvent(context.Background(), []byte(mixed))
+	if err != nil {
+		t.Fatalf("mixed decode: %v", err)
+	}
+	defer ReleaseEvent(got2)
+	if got2.Point.Lat != 1 || got2.Point.Lon != 2 || got2.Point.Ce != 5 {
+		t.Errorf("attributes should win over children: %+v", got2.Point)
+	}
+}
+
+func TestMaxLinks(t *testing.T) {
+	SetMaxLinks(3)
+	defer SetMaxLinks(1000)
+
+	evt, err := NewEvent("LINKS-1", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	for i := 0; i < 4; i++ {
+		evt.Links = append(evt.Links, Link{Uid: fmt.Sprintf("L%d", i), Type: "a-f-G", Relation: "p-p"})
+		err := evt.Validate()
+		switch n := len(evt.Links); {
+		case n <= 3 && err != nil:
+			t.Errorf("%d links: unexpected error %v", n, err)
+		case n > 3 && !errors.Is(err, ErrInvalidInput):
+			t.Errorf("%d links: error = %v, want ErrInvalidInput", n, err)
+		}
+	}
+
+	evt.Links = evt.Links[:2]
+	evt.Detail = &Detail{RouteLinks: []RouteLink{{}, {}}}
+	if err := evt.Validate(); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("route waypoints not counted: %v", err)
+	}
+
+	SetMaxLinks(0)
+	evt.Detail = nil
+	for i := 0; i < 10; i++ {
+		evt.Links = append(evt.Links, Link{Uid: "X", Type: "a-f-G", Relation: "p-p"})
+	}
+	if err := evt.Validate(); err != nil {
+		t.Errorf("unlimited: %v", err)
+	}
+}
+
+func TestTrimRawWhitespace(t *testing.T) {
+	capture := func(doc string) string {
+		t.Helper()
+		dec := cottypes.NewSecureDecoder(strings.NewReader(doc))
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatalf("token: %v", err)
+		}
+		raw, err := captureRaw(dec, tok.(xml.StartElement))
+		if err != nil {
+			t.Fatalf("captureRaw: %v", err)
+		}
+		return string(raw)
+	}
+	const (
+		compact = `<sensorData id="s1"><reading unit="C">21.5</reading><note> </note><empty></empty></sensorData>`
+		pretty  = "<sensorData id=\"s1\">\n  <reading unit=\"C\">21.5</reading>\n  <note> </note>\n  <empty>\n  </empty>\n</sensorData>"
+	)
+
+	if capture(pretty) == capture(compact) {
+		t.Fatal("whitespace trimmed with the option disabled")
+	}
+
+	SetTrimRawWhitespace(true)
+	defer SetTrimRawWhitespace(false)
+	want := strings.Replace(compact, "<empty></empty>", "<empty>\n  </empty>", 1)
+	if got := capture(pretty); got != want {
+		t.Errorf("trimmed pretty capture:\ngot  %s\nwant %s", got, want)
+	}
+	if got := capture(compact); got != compact {
+		t.Errorf("compact capture changed:\ngot  %s\nwant %s", got, compact)
+	}
+	const text = "<remarks>\n  two lines\n  of text\n</remarks>"
+	if got := capture(text); got != text {
+		t.Errorf("text content changed:\ngot  %q\nwant %q", got, text)
+	}
+}
+
+func TestRequireZuluTime(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	zulu := now.Format(CotTimeFormat)
+	offset := now.In(time.FixedZone("EST", -5*60*60)).Format(time.RFC3339)
+	doc := func(ts string) []byte {
+		return []byte(fmt.Sprintf(`<event version="2.0" uid="Z-1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s">`+
+			`<point lat="1" lon="2" hae="0" ce="10" le="10"/></event>`, ts, ts, now.Add(time.Minute).Format(CotTimeFormat)))
+	}
+
+	evt, err := UnmarshalXMLEvent(context.Background(), doc(offset))
+	if err != nil {
+		t.Fatalf("offset time rejected by default: %v", err)
+	}
+	if !evt.Time.IsZulu() {
+		t.Error("decoded time not normalized to UTC")
+	}
+	ReleaseEvent(evt)
+	if CoTTime(now.In(time.FixedZone("EST", -5*60*60))).IsZulu() {
+		t.Error("IsZulu true for a non-UTC time")
+	}
+
+	SetRequireZuluTime(true)
+	defer SetRequireZuluTime(false)
+	evt, err = UnmarshalXMLEvent(context.Background(), doc(zulu))
+	if err != nil {
+		t.Fatalf("Zulu time rejected: %v", err)
+	}
+	ReleaseEvent(evt)
+	if _, err := UnmarshalXMLEvent(context.Background(), doc(offset)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("offset time error = %v, want ErrInvalidInput", err)
+	}
+}
@@ -1,15 +1,20 @@
 package cotlib
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/NERVsystems/cotlib/cottypes"
 )
 
 // Constants for testing
@@ -302,13 +307,25 @@ func TestEventValidation(t *testing.T) {
 		event := *validEvent
 		event.Type = "a-f-G"
 		event.Stale = CoTTime(event.Time.Time().Add(8 * 24 * time.Hour))
-		if err := event.Validate(); err != nil {
-			t.Errorf("Event.Validate() error = %v, wantErr false for long stale", err)
+		if err := event.Validate(); err == nil {
+			t.Error("Event.Validate() error = nil, wantErr true for stale beyond 7 days")
 		}
 
 		event.Type = "t-x-takp-v"
+		if err := event.Validate(); err == nil {
+			t.Error("Event.Validate() error = nil, wantErr true for stale beyond 7 days")
+		}
+
+		event.Stale = CoTTime(event.Time.Time().Add(maxStaleOffset))
 		if err := event.Validate(); err != nil {
-			t.Errorf("Event.Validate() error = %v, wantErr false for long stale", err)
+			t.Errorf("Event.Validate() error = %v, wantErr false for stale at 7 days", err)
+		}
+
+		data := fmt.Sprintf(`<event version="2.0" uid="LONG1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/></event>`,
+			event.Time.Time().Format(CotTimeFormat), event.Time.Time().Format(CotTimeFormat),
+			event.Time.Time().Add(30*24*time.Hour).Format(CotTimeFormat))
+		if _, err := UnmarshalXMLEvent(context.Background(), []byte(data)); err == nil {
+			t.Error("decoded event with stale 30 days out was accepted")
 		}
 	})
 
@@ -576,6 +593,35 @@ func TestValidateTypeDotWildcardNegative(t *testing.T) {
 	}
 }
 
+func TestValidateTypeReasons(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want error
+	}{
+		{"x-f-G", ErrUnknownPrefix},
+		{"a_b_c", ErrMalformedType},
+		{"a--G", ErrMalformedType},
+		{"a-f-ZZZZ", ErrTypeNotInCatalog},
+	}
+	reasons := []error{ErrUnknownPrefix, ErrMalformedType, ErrTypeNotInCatalog}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			err := ValidateType(tt.typ)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ValidateType(%q) = %v, want %v", tt.typ, err, tt.want)
+			}
+			if !errors.Is(err, ErrInvalidType) {
+				t.Errorf("error does not wrap ErrInvalidType: %v", err)
+			}
+			for _, r := range reasons {
+				if r != tt.want && errors.Is(err, r) {
+					t.Errorf("error also matches %v", r)
+				}
+			}
+		})
+	}
+}
+
 func TestEmbeddedTypesValidation(t *testing.T) {
 	// Test common tactical types
 	tacticalTypes := []string{
@@ -1038,6 +1084,34 @@ func TestEventBuilderInvalid(t *testing.T) {
 	}
 }
 
+func TestEventBuilderWithValidatedType(t *testing.T) {
+	b := NewEventBuilder("B3", "a-f-G", 10.0, -20.0, 0).WithValidatedType("a-f-G-U-C")
+	if err := b.Err(); err != nil {
+		t.Fatalf("valid type: Err() = %v", err)
+	}
+	evt, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if evt.Type != "a-f-G-U-C" {
+		t.Errorf("type = %q, want a-f-G-U-C", evt.Type)
+	}
+	ReleaseEvent(evt)
+
+	b = NewEventBuilder("B4", "a-f-G", 10.0, -20.0, 0).WithValidatedType("a-f-")
+	if err := b.Err(); !errors.Is(err, ErrInvalidType) {
+		t.Fatalf("invalid type: Err() = %v, want ErrInvalidType", err)
+	}
+	// A corrected type clears the error.
+	if err := b.WithValidatedType("a-f-G").Err(); err != nil {
+		t.Errorf("corrected type: Err() = %v", err)
+	}
+	b.WithValidatedType("not a type")
+	if _, err := b.Build(); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("Build with invalid type = %v, want ErrInvalidType", err)
+	}
+}
+
 func TestCoTTimeRFC3339NanoRoundTrip(t *testing.T) {
 	const ts = "2025-06-03T17:31:12.013Z"
 	want, err := time.Parse(time.RFC3339Nano, ts)
@@ -1101,3 +1175,1216 @@ func TestCoTTimeRFC3339NanoRoundTrip(t *testing.T) {
 		}
 	})
 }
+
+func TestUnmarshalXMLEventDecodeErrorOffset(t *testing.T) {
+	now := time.Now().UTC()
+	data := []byte(fmt.Sprintf(`<event version="2.0" uid="U1" type="a-f-G" time="%s" start="%s" stale="%s">`+
+		`<point lat="1" lon="2" hae="0" ce="1" le="1"/><detail><contact callsign="A"/>`,
+		now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat)))
+
+	evt, err := UnmarshalXMLEvent(context.Background(), data)
+	if err == nil {
+		ReleaseEvent(evt)
+		t.Fatal("expected error for truncated document")
+	}
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("expected *DecodeError, got %T: %v", err, err)
+	}
+	if de.Offset <= 0 || de.Offset > int64(len(data)) {
+		t.Errorf("Offset = %d, want within (0, %d]", de.Offset, len(data))
+	}
+	if de.Err == nil || errors.Unwrap(err) != de.Err {
+		t.Errorf("Unwrap() did not return underlying error")
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("offset %d", de.Offset)) {
+		t.Errorf("error %q does not mention offset", err)
+	}
+}
+
+func TestUpdatePosition(t *testing.T) {
+	evt, err := NewEvent("pos1", "a-f-G", 10, 20, 5)
+	if err != nil {
+		t.Fatalf("NewEvent() error = %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Stale = CoTTime(evt.Time.Time().Add(time.Minute))
+	evt.Detail = &Detail{Track: &Track{Raw: []byte(`<track speed="10"/>`)}}
+
+	next := evt.Time.Time().Add(2 * time.Second)
+	if err := evt.UpdatePosition(11, 21, 6, next); err != nil {
+		t.Fatalf("UpdatePosition() error = %v", err)
+	}
+	if evt.Point.Lat != 11 || evt.Point.Lon != 21 || evt.Point.Hae != 6 {
+		t.Errorf("Point = %+v, want lat 11 lon 21 hae 6", evt.Point)
+	}
+	if evt.Point.Ce != 9999999.0 || evt.Point.Le != 9999999.0 {
+		t.Errorf("error estimates not preserved: %+v", evt.Point)
+	}
+	if !evt.Time.Time().Equal(next) || !evt.Start.Time().Equal(next) {
+		t.Errorf("Time/Start = %v/%v, want %v", evt.Time.Time(), evt.Start.Time(), next)
+	}
+	if got := evt.Stale.Time().Sub(evt.Time.Time()); got != time.Minute {
+		t.Errorf("stale offset = %v, want %v", got, time.Minute)
+	}
+
+	// Invalid coordinates are rejected and leave the event untouched.
+	before := evt.Point
+	if err := evt.UpdatePosition(95, 21, 6, next.Add(time.Second)); !errors.Is(err, ErrInvalidLatitude) {
+		t.Errorf("UpdatePosition(lat=95) error = %v, want ErrInvalidLatitude", err)
+	}
+	if err := evt.UpdatePosition(11, 181, 6, next.Add(time.Second)); !errors.Is(err, ErrInvalidLongitude) {
+		t.Errorf("UpdatePosition(lon=181) error = %v, want ErrInvalidLongitude", err)
+	}
+	if evt.Point != before || !evt.Time.Time().Equal(next) {
+		t.Error("event modified by failed update")
+	}
+
+	// Times outside the accepted window are rejected.
+	if err := evt.UpdatePosition(11, 21, 6, time.Now().Add(-48*time.Hour)); err == nil {
+		t.Error("expected error for time outside window")
+	}
+}
+
+func TestSuggestTypes(t *testing.T) {
+	got := SuggestTypes("a-f-G", 5)
+	if len(got) != 5 {
+		t.Fatalf("SuggestTypes() returned %d results, want 5", len(got))
+	}
+	if got[0].Name != "a-f-G" {
+		t.Errorf("first suggestion = %s, want a-f-G", got[0].Name)
+	}
+	for i := 1; i < len(got); i++ {
+		prev, cur := got[i-1].Name, got[i].Name
+		dp, dc := strings.Count(prev, "-"), strings.Count(cur, "-")
+		if dp > dc || (dp == dc && len(prev) > len(cur)) ||
+			(dp == dc && len(prev) == len(cur) && prev > cur) {
+			t.Errorf("suggestions out of order: %s before %s", prev, cur)
+		}
+		if !strings.HasPrefix(cur, "a-f-G") {
+			t.Errorf("suggestion %s does not match prefix", cur)
+		}
+	}
+
+	all := SuggestTypes("a-f-G", 0)
+	if len(all) <= 5 {
+		t.Errorf("SuggestTypes() with no limit returned %d results, want more than 5", len(all))
+	}
+
+	byName := SuggestTypes("nbc", 0)
+	if len(byName) == 0 {
+		t.Fatal("expected full-name matches for nbc")
+	}
+	for _, typ := range byName {
+		if !strings.Contains(strings.ToUpper(typ.FullName), "NBC") && !strings.HasPrefix(typ.Name, "nbc") {
+			t.Errorf("unexpected match %s (%s)", typ.Name, typ.FullName)
+		}
+	}
+
+	if got := SuggestTypes("", 10); len(got) != 0 {
+		t.Errorf("empty prefix returned %d results", len(got))
+	}
+}
+
+func TestDeleteEvents(t *testing.T) {
+	t.Run("new_delete_event", func(t *testing.T) {
+		evt, err := NewDeleteEvent("MARKER-1")
+		if err != nil {
+			t.Fatalf("NewDeleteEvent: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		if evt.Type != "t-x-d-d" {
+			t.Errorf("Type = %q, want t-x-d-d", evt.Type)
+		}
+		if evt.Uid == "MARKER-1" {
+			t.Error("delete tasking reuses the marker uid")
+		}
+		if d := evt.Stale.Time().Sub(evt.Time.Time()); d != minStaleOffset {
+			t.Errorf("stale - time = %v, want %v", d, minStaleOffset)
+		}
+		if !strings.Contains(string(evt.Detail.LinkDetail.Raw), `uid="MARKER-1"`) {
+			t.Errorf("link detail %s does not reference marker", evt.Detail.LinkDetail.Raw)
+		}
+		if err := evt.Validate(); err != nil {
+			t.Errorf("Validate: %v", err)
+		}
+	})
+
+	t.Run("as_delete", func(t *testing.T) {
+		evt, err := NewEvent("MARKER-2", "a-f-G", 10, 20, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.Stale = CoTTime(evt.Time.Time().Add(time.Hour))
+		evt.AddLink(&Link{Uid: "PARENT", Type: "a-f-G", Relation: "p-p"})
+		origStale := evt.Stale
+
+		del := evt.AsDelete()
+		defer ReleaseEvent(del)
+		if del == evt {
+			t.Fatal("AsDelete returned the original event")
+		}
+		if del.Uid != evt.Uid || del.Type != evt.Type {
+			t.Errorf("delete uid/type = %s/%s, want %s/%s", del.Uid, del.Type, evt.Uid, evt.Type)
+		}
+		if d := del.Stale.Time().Sub(del.Time.Time()); d != minStaleOffset {
+			t.Errorf("stale - time = %v, want %v", d, minStaleOffset)
+		}
+		if err := del.Validate(); err != nil {
+			t.Errorf("Validate: %v", err)
+		}
+		if evt.Stale != origStale {
+			t.Error("AsDelete modified the original event")
+		}
+		del.Links[0].Uid = "OTHER"
+		if evt.Links[0].Uid != "PARENT" {
+			t.Error("AsDelete shares links with the original event")
+		}
+	})
+}
+
+func TestEventVersionValidation(t *testing.T) {
+	defer SetSupportedVersions(nil)
+
+	evt, err := NewEvent("V1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+
+	check := func(version string, valid bool) {
+		t.Helper()
+		evt.Version = version
+		err := evt.Validate()
+		if valid && err != nil {
+			t.Errorf("version %q rejected: %v", version, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("version %q: expected ErrInvalidInput, got %v", version, err)
+		}
+	}
+
+	check("2.0", true)
+	check("2.1", true)
+	check("banana", false)
+	check("2.", false)
+
+	SetSupportedVersions([]string{"2.*"})
+	check("2.0", true)
+	check("2.3", true)
+	check("3.0", false)
+
+	SetSupportedVersions([]string{"2.0"})
+	check("2.0", true)
+	check("2.1", false)
+}
+
+func TestRejectUnknownDetails(t *testing.T) {
+	defer SetRejectUnknownDetails(false)
+
+	now := time.Now().UTC()
+	data := []byte(fmt.Sprintf(`<event version="2.0" uid="RU1" type="a-f-G" how="m-g" time="%[1]s" start="%[1]s" stale="%[2]s">`+
+		`<point lat="0" lon="0" hae="0" ce="1" le="1"/><detail><extra/></detail></event>`,
+		now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat)))
+
+	evt, err := UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal with capture: %v", err)
+	}
+	if evt.Detail == nil || len(evt.Detail.Unknown) != 1 {
+		t.Errorf("expected <extra/> captured in Unknown, got %+v", evt.Detail)
+	}
+	ReleaseEvent(evt)
+
+	SetRejectUnknownDetails(true)
+	if _, err := UnmarshalXMLEvent(context.Background(), data); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("expected ErrInvalidInput for unknown detail, got %v", err)
+	}
+}
+
+func TestSetDetailSchemaValidation(t *testing.T) {
+	defer SetDetailSchemaValidation(true)
+
+	evt, err := NewEvent("DS1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Detail = &Detail{Track: &Track{Raw: []byte(`<track speed="10"/>`)}}
+
+	if err := evt.Validate(); err == nil {
+		t.Fatal("expected error for invalid track with schema validation enabled")
+	}
+	SetDetailSchemaValidation(false)
+	if err := evt.Validate(); err != nil {
+		t.Errorf("invalid track rejected with schema validation disabled: %v", err)
+	}
+	evt.Point.Lat = 100
+	if err := evt.Validate(); err == nil {
+		t.Error("expected point validation to remain active")
+	}
+}
+
+func TestTypeSegments(t *testing.T) {
+	tests := []struct {
+		typ      string
+		segments int
+		aff      byte
+		dim      byte
+		atomic   bool
+	}{
+		{"a-f-G-U-C", 5, 'f', 'G', true},
+		{"a-h-A", 3, 'h', 'A', true},
+		{"a-.-G", 3, '.', 'G', true},
+		{"b-m-p-s-p-i", 6, 0, 0, false},
+		{"t-x-takp-v", 4, 0, 0, false},
+		{"", 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			if got := len(TypeSegments(tt.typ)); got != tt.segments {
+				t.Errorf("len(TypeSegments) = %d, want %d", got, tt.segments)
+			}
+			aff, ok := TypeAffiliation(tt.typ)
+			if ok != tt.atomic || aff != tt.aff {
+				t.Errorf("TypeAffiliation = %q, %v, want %q, %v", aff, ok, tt.aff, tt.atomic)
+			}
+			dim, ok := TypeDimension(tt.typ)
+			if ok != tt.atomic || dim != tt.dim {
+				t.Errorf("TypeDimension = %q, %v, want %q, %v", dim, ok, tt.dim, tt.atomic)
+			}
+		})
+	}
+
+	if _, ok := TypeDimension("a-f"); ok {
+		t.Error("TypeDimension(\"a-f\") reported a dimension")
+	}
+}
+
+func TestEventPosture(t *testing.T) {
+	tests := map[string]string{
+		"a-f-G-U-C":   PostureFriend,
+		"a-a-A":       PostureFriend,
+		"a-h-G":       PostureHostile,
+		"a-s-S":       PostureHostile,
+		"a-j-A":       PostureHostile,
+		"a-k-G":       PostureHostile,
+		"a-n-G":       PostureNeutral,
+		"a-u-S":       PostureUnknown,
+		"a-p-G":       PosturePending,
+		"a-o-G":       PostureNone,
+		"a-.-G":       PostureUnknown,
+		"b-m-p-s-p-i": PostureNone,
+		"t-x-takp-v":  PostureNone,
+		"":            PostureNone,
+	}
+	for typ, want := range tests {
+		evt := &Event{Type: typ}
+		if got := evt.Posture(); got != want {
+			t.Errorf("Posture(%q) = %q, want %q", typ, got, want)
+		}
+	}
+	var nilEvt *Event
+	if got := nilEvt.Posture(); got != PostureNone {
+		t.Errorf("nil Posture = %q", got)
+	}
+}
+
+func TestHowsForCategory(t *testing.T) {
+	sa := (&Event{Type: "a-f-G-U-C"}).Category()
+	if sa != CategorySA {
+		t.Fatalf("Category(a-f-G-U-C) = %q", sa)
+	}
+	hasHow := func(hows []cottypes.HowInfo, value string) bool {
+		for _, h := range hows {
+			if h.Value == value {
+				return true
+			}
+		}
+		return false
+	}
+	saHows := HowsForCategory(sa)
+	if !hasHow(saHows, "m-g") || !hasHow(saHows, "h-e") {
+		t.Errorf("SA hows %+v lack GPS or manual", saHows)
+	}
+
+	chat := HowsForCategory((&Event{Type: "b-t-f"}).Category())
+	if len(chat) == 0 || hasHow(chat, "m-g") {
+		t.Errorf("chat hows = %+v", chat)
+	}
+	if got, all := len(HowsForCategory(CategoryOther)), len(cottypes.GetAllHows()); got != all {
+		t.Errorf("other category got %d hows, want all %d", got, all)
+	}
+
+	for typ, want := range map[string]string{
+		"b-e-r":      CategoryEmergency,
+		"u-d-c-c":    CategoryDrawing,
+		"b-m-r":      CategoryRoute,
+		"t-k":        CategoryTasking,
+		"t-x-takp-v": CategoryOther,
+	} {
+		if got := (&Event{Type: typ}).Category(); got != want {
+			t.Errorf("Category(%s) = %q, want %q", typ, got, want)
+		}
+	}
+}
+
+func TestAltitudeSource(t *testing.T) {
+	evt, err := NewEvent("ALT1", "a-f-G", 10, 20, 9999999.0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+
+	if got := evt.AltitudeSource(); got != AltSrcUnknown {
+		t.Errorf("sentinel HAE: AltitudeSource() = %q, want %q", got, AltSrcUnknown)
+	}
+
+	evt.Point.Hae = 250
+	if got := evt.AltitudeSource(); got != AltSrcUnspecified {
+		t.Errorf("real HAE: AltitudeSource() = %q, want %q", got, AltSrcUnspecified)
+	}
+
+	evt.Detail = &Detail{PrecisionLocation: &PrecisionLocation{Raw: []byte(`<precisionlocation geopointsrc="GPS" altsrc="DTED2"/>`)}}
+	if got := evt.AltitudeSource(); got != "DTED2" {
+		t.Errorf("precisionlocation: AltitudeSource() = %q, want DTED2", got)
+	}
+}
+
+func TestRegisterDetailHook(t *testing.T) {
+	defer RegisterDetailHook("oldfoo", nil)
+	RegisterDetailHook("oldfoo", func(raw RawMessage) (RawMessage, error) {
+		if strings.Contains(string(raw), "bad") {
+			return nil, errors.New("bad oldfoo")
+		}
+		return RawMessage(`<newfoo/>`), nil
+	})
+
+	now := time.Now().UTC()
+	build := func(detail string) []byte {
+		return []byte(fmt.Sprintf(`<event version="2.0" uid="HK1" type="a-f-G" how="m-g" time="%[1]s" start="%[1]s" stale="%[2]s">`+
+			`<point lat="0" lon="0" hae="0" ce="1" le="1"/><detail>%[3]s</detail></event>`,
+			now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat), detail))
+	}
+
+	evt, err := UnmarshalXMLEvent(context.Background(), build(`<oldfoo/><other/>`))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	if len(evt.Detail.Unknown) != 2 || string(evt.Detail.Unknown[0]) != `<newfoo/>` {
+		t.Errorf("Unknown = %q, want rewritten <newfoo/> first", evt.Detail.Unknown)
+	}
+
+	if _, err := UnmarshalXMLEvent(context.Background(), build(`<oldfoo note="bad"/>`)); err == nil ||
+		!strings.Contains(err.Error(), "bad oldfoo") {
+		t.Errorf("expected hook error, got %v", err)
+	}
+}
+
+func TestEventWithoutPoint(t *testing.T) {
+	now := time.Now().UTC()
+	data := fmt.Sprintf(`<event version="2.0" uid="REPLY1" type="y-a-r" how="m-g" time="%s" start="%s" stale="%s"><detail><link uid="TASK1" type="t-x-c-t" relation="p-p"/></detail></event>`,
+		now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat))
+
+	evt, err := UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal reply without point: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	if evt.HasPoint() || !evt.NoPoint {
+		t.Error("expected event to report no point")
+	}
+	if err := evt.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !strings.Contains(string(out), `<point lat="0.000000" lon="0.000000" ce="9999999.0" le="9999999.0"/>`) {
+		t.Errorf("sentinel point not written: %s", out)
+	}
+	again, err := UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("re-unmarshal: %v", err)
+	}
+	defer ReleaseEvent(again)
+	if !again.HasPoint() {
+		t.Error("re-decoded event should carry the sentinel point")
+	}
+
+	// A position update gives the event a real point.
+	if err := evt.UpdatePosition(11, 21, 6, now.Add(time.Second)); err != nil {
+		t.Fatalf("UpdatePosition: %v", err)
+	}
+	if !evt.HasPoint() {
+		t.Error("HasPoint false after UpdatePosition")
+	}
+	out, err = evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML after update: %v", err)
+	}
+	if !strings.Contains(string(out), `<point lat="11.000000" lon="21.000000" hae="6.0"`) {
+		t.Errorf("updated point not written: %s", out)
+	}
+
+	// Events built in code still have their point validated.
+	bad := &Event{Version: "2.0", Uid: "NP2", Type: "a-f-G", How: "m-g",
+		Time: CoTTime(now), Start: CoTTime(now), Stale: CoTTime(now.Add(time.Minute))}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected zero CE/LE to fail for an event with a point")
+	}
+}
+
+func TestWildcardForm(t *testing.T) {
+	RegisterCoTType("a-.-Z")
+
+	tests := []struct {
+		typ  string
+		want string
+		ok   bool
+	}{
+		{"a-h-G-E-X-N", "a-.-G-E-X-N", true},
+		{"a-f-G-E-X-N", "a-.-G-E-X-N", true},
+		{"a-.-G-E-X-N", "a-.-G-E-X-N", true},
+		{"a-f-Z", "a-.-Z", true},   // registered wildcard
+		{"a-h-G-ZZZZ", "", false},  // no wildcard entry
+		{"b-m-p-s-p-i", "", false}, // not atomic
+		{"a", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := WildcardForm(tt.typ)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("WildcardForm(%q) = %q, %v; want %q, %v", tt.typ, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUnmarshalXMLEventCommentsAndPIs(t *testing.T) {
+	now := time.Now().UTC()
+	ev := fmt.Sprintf(`<event version="2.0" uid="C1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><!-- inner --><point lat="1" lon="2" hae="0" ce="5" le="5"/></event>`,
+		now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat))
+
+	tests := map[string]string{
+		"leading_comment": `<?xml version="1.0" encoding="UTF-8"?>` + "\n<!-- generated by X -->\n" + ev,
+		"stylesheet_pi":   `<?xml version="1.0" encoding="UTF-8"?><?xml-stylesheet type="text/xsl" href="cot.xsl"?>` + ev,
+		"trailing_misc":   ev + "\n<!-- trailer --><?done?>\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			evt, err := UnmarshalXMLEvent(context.Background(), []byte(data))
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			defer ReleaseEvent(evt)
+			if evt.Uid != "C1" {
+				t.Errorf("uid = %q, want C1", evt.Uid)
+			}
+		})
+	}
+
+	t.Run("doctype_after_comment", func(t *testing.T) {
+		data := "<!-- generated by X --><!DOCTYPE event>" + ev
+		if _, err := UnmarshalXMLEvent(context.Background(), []byte(data)); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("expected ErrInvalidInput, got %v", err)
+		}
+	})
+}
+
+func TestNewReply(t *testing.T) {
+	task, err := NewEvent("TASK1", "t-a-r", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new tasking: %v", err)
+	}
+	defer ReleaseEvent(task)
+
+	for _, tt := range []struct{ typ, text string }{
+		{"y-a", "Wilco"},
+		{"y-c", "Tasking complete"},
+	} {
+		t.Run(tt.typ, func(t *testing.T) {
+			reply, err := task.NewReply(tt.typ, tt.text)
+			if err != nil {
+				t.Fatalf("NewReply: %v", err)
+			}
+			defer ReleaseEvent(reply)
+			if reply.Type != tt.typ || reply.HasPoint() {
+				t.Errorf("type = %s, has point = %v", reply.Type, reply.HasPoint())
+			}
+			if len(reply.Links) != 1 || reply.Links[0] != (Link{Uid: "TASK1", Type: "t-a-r", Relation: "p-t"}) {
+				t.Errorf("links = %+v", reply.Links)
+			}
+			if reply.Detail == nil || reply.Detail.Remarks == nil || reply.Detail.Remarks.Text != tt.text {
+				t.Errorf("remarks = %+v", reply.Detail)
+			}
+			if err := reply.Validate(); err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+
+			data, err := reply.ToXML()
+			if err != nil {
+				t.Fatalf("ToXML: %v", err)
+			}
+			out, err := UnmarshalXMLEvent(context.Background(), data)
+			if err != nil {
+				t.Fatalf("unmarshal reply: %v\n%s", err, data)
+			}
+			ReleaseEvent(out)
+		})
+	}
+
+	if _, err := task.NewReply("a-f-G", ""); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("non-reply type: expected ErrInvalidType, got %v", err)
+	}
+	if _, err := task.NewReply("y-zzz", ""); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("unknown reply type: expected ErrInvalidType, got %v", err)
+	}
+
+	unit, err := NewEvent("UNIT1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(unit)
+	if _, err := unit.NewReply("y-a", ""); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("non-tasking event: expected ErrInvalidInput, got %v", err)
+	}
+}
+
+func TestRequireUUID(t *testing.T) {
+	const id = "0c4e1f8a-2b6d-4c3e-9f1a-7d2e5b8c9a10"
+
+	if err := ValidateUIDUUID(id); err != nil {
+		t.Errorf("ValidateUIDUUID(%q): %v", id, err)
+	}
+	for _, bad := range []string{"UNIT-1", "0c4e1f8a2b6d4c3e9f1a7d2e5b8c9a10", "0c4e1f8a-2b6d-0c3e-9f1a-7d2e5b8c9a10", "0c4e1f8a-2b6d-4c3e-cf1a-7d2e5b8c9a10", "zc4e1f8a-2b6d-4c3e-9f1a-7d2e5b8c9a10"} {
+		if err := ValidateUIDUUID(bad); !errors.Is(err, ErrInvalidUID) {
+			t.Errorf("ValidateUIDUUID(%q) = %v, want ErrInvalidUID", bad, err)
+		}
+	}
+
+	evt, err := NewEvent("UNIT-1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+
+	SetRequireUUID(true, "ANDROID-")
+	defer SetRequireUUID(false)
+
+	if err := evt.Validate(); !errors.Is(err, ErrInvalidUID) {
+		t.Errorf("non-UUID uid under strict mode: got %v, want ErrInvalidUID", err)
+	}
+	evt.Uid = id
+	if err := evt.Validate(); err != nil {
+		t.Errorf("UUID uid: %v", err)
+	}
+	evt.Uid = "ANDROID-" + id
+	if err := evt.Validate(); err != nil {
+		t.Errorf("prefixed UUID uid: %v", err)
+	}
+	evt.Uid = "IOS-" + id
+	if err := evt.Validate(); !errors.Is(err, ErrInvalidUID) {
+		t.Errorf("unknown prefix: got %v, want ErrInvalidUID", err)
+	}
+
+	SetRequireUUID(false)
+	evt.Uid = "UNIT-1"
+	if err := evt.Validate(); err != nil {
+		t.Errorf("lenient default: %v", err)
+	}
+}
+
+func TestSetTTL(t *testing.T) {
+	evt, err := NewEvent("TTL1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	base := evt.Time.Time()
+
+	if err := evt.SetTTL(5 * time.Minute); err != nil {
+		t.Fatalf("SetTTL(5m): %v", err)
+	}
+	if got := evt.Stale.Time().Sub(base); got != 5*time.Minute {
+		t.Errorf("stale offset = %v, want 5m", got)
+	}
+
+	if err := evt.SetTTL(time.Second); err != nil {
+		t.Fatalf("SetTTL(1s): %v", err)
+	}
+	if got := evt.Stale.Time().Sub(base); got != minStaleOffset {
+		t.Errorf("short TTL stale offset = %v, want clamp to %v", got, minStaleOffset)
+	}
+	if err := evt.Validate(); err != nil {
+		t.Errorf("Validate after clamped TTL: %v", err)
+	}
+
+	before := evt.Stale
+	if err := evt.SetTTL(8 * 24 * time.Hour); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("long TTL: got %v, want ErrInvalidInput", err)
+	}
+	if evt.Stale != before {
+		t.Error("rejected TTL modified stale time")
+	}
+}
+
+func TestNormalizeLatLon(t *testing.T) {
+	tests := []struct {
+		lat, lon         float64
+		wantLat, wantLon float64
+		changed          bool
+	}{
+		{10, 20, 10, 20, false},
+		{10, 180, 10, 180, false},
+		{10, 190, 10, -170, true},
+		{10, -190, 10, 170, true},
+		{10, 540, 10, -180, true},
+		{95, 10, 85, -170, true},
+		{-95, 10, -85, -170, true},
+	}
+	for _, tt := range tests {
+		lat, lon, changed := NormalizeLatLon(tt.lat, tt.lon)
+		if changed != tt.changed || math.Abs(lat-tt.wantLat) > 1e-9 || math.Abs(lon-tt.wantLon) > 1e-9 {
+			t.Errorf("NormalizeLatLon(%v, %v) = %v, %v, %v; want %v, %v, %v",
+				tt.lat, tt.lon, lat, lon, changed, tt.wantLat, tt.wantLon, tt.changed)
+		}
+	}
+	if _, _, changed := NormalizeLatLon(math.NaN(), 190); changed {
+		t.Error("NaN latitude should not be normalized")
+	}
+
+	now := time.Now().UTC()
+	data := []byte(fmt.Sprintf(`<event version="2.0" uid="WRAP1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="10" lon="190" hae="0" ce="5" le="5"/></event>`,
+		now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat)))
+
+	if _, err := UnmarshalXMLEvent(context.Background(), data); !errors.Is(err, ErrInvalidLongitude) {
+		t.Errorf("strict default: got %v, want ErrInvalidLongitude", err)
+	}
+
+	SetNormalizeCoordinates(true)
+	defer SetNormalizeCoordinates(false)
+	evt, err := UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal with normalization: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	if evt.Point.Lat != 10 || evt.Point.Lon != -170 {
+		t.Errorf("point = %v, %v; want 10, -170", evt.Point.Lat, evt.Point.Lon)
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	now := time.Now().UTC()
+	data := fmt.Sprintf(`<event version="2.0" uid="ANDROID-1234-ping" type="t-x-c-t" how="h-g-i-g-o" time="%s" start="%s" stale="%s"><point lat="0" lon="0" hae="0" ce="9999999" le="9999999"/><detail/></event>`,
+		now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(20*time.Second).Format(CotTimeFormat))
+	ping, err := UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal ping: %v", err)
+	}
+	defer ReleaseEvent(ping)
+	if !ping.IsPing() {
+		t.Fatal("IsPing() = false for t-x-c-t")
+	}
+
+	pong, err := ping.NewPong("SERVER-1")
+	if err != nil {
+		t.Fatalf("NewPong: %v", err)
+	}
+	defer ReleaseEvent(pong)
+	if pong.Type != "t-x-c-t-r" || pong.Uid != "SERVER-1" || pong.IsPing() {
+		t.Errorf("pong = %s %s", pong.Uid, pong.Type)
+	}
+	if len(pong.Links) != 1 || pong.Links[0] != (Link{Uid: "ANDROID-1234-ping", Type: "t-x-c-t", Relation: "p-t"}) {
+		t.Errorf("links = %+v", pong.Links)
+	}
+	out, err := pong.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	decoded, err := UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal pong: %v\n%s", err, out)
+	}
+	ReleaseEvent(decoded)
+
+	if _, err := pong.NewPong("SERVER-1"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("NewPong on non-ping = %v, want ErrInvalidInput", err)
+	}
+	if _, err := ping.NewPong(""); err == nil {
+		t.Error("expected error for empty self uid")
+	}
+}
+
+func TestUnmarshalXMLEventStats(t *testing.T) {
+	now := time.Now().UTC()
+	data := []byte(fmt.Sprintf(`<event version="2.0" uid="ST1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail><contact callsign="ALPHA"/><track course="90" speed="3"/><custom/></detail></event>`,
+		now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat)))
+
+	evt, stats, err := UnmarshalXMLEventStats(context.Background(), data)
+	if err != nil {
+		t.Fatalf("UnmarshalXMLEventStats: %v", err)
+	}
+	ReleaseEvent(evt)
+	if stats.Bytes != len(data) {
+		t.Errorf("Bytes = %d, want %d", stats.Bytes, len(data))
+	}
+	// Durations can read zero on coarse clocks, so only their sign is checked.
+	if stats.DecodeDuration < 0 || stats.ValidateDuration < 0 {
+		t.Errorf("durations = %v, %v", stats.DecodeDuration, stats.ValidateDuration)
+	}
+	if stats.DetailCount != 3 {
+		t.Errorf("DetailCount = %d, want 3", stats.DetailCount)
+	}
+
+	bad := bytes.Replace(data, []byte(`lon="2"`), []byte(`lon="200"`), 1)
+	_, stats, err = UnmarshalXMLEventStats(context.Background(), bad)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if stats.Bytes != len(bad) || stats.DecodeDuration < 0 || stats.DetailCount != 3 {
+		t.Errorf("partial stats = %+v", stats)
+	}
+}
+
+func TestInferHow(t *testing.T) {
+	evt, err := NewEvent("HOW1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.How = ""
+
+	if how, ok := evt.InferHow(); ok {
+		t.Errorf("bare point: InferHow() = %q, true; want false", how)
+	}
+
+	evt.Point.Ce = 9999999.0
+	evt.Detail = &Detail{PrecisionLocation: &PrecisionLocation{Raw: []byte(`<precisionlocation geopointsrc="GPS" altsrc="GPS"/>`)}}
+	if how, ok := evt.InferHow(); ok {
+		t.Errorf("GPS with unknown CE: InferHow() = %q, true; want false", how)
+	}
+
+	evt.Point.Ce = 4.9
+	if how, ok := evt.InferHow(); !ok || how != "h-g-i-g-o" {
+		t.Errorf("GPS precisionlocation: InferHow() = %q, %v; want h-g-i-g-o, true", how, ok)
+	}
+
+	evt.Detail.PrecisionLocation.Raw = []byte(`<precisionlocation altsrc="DTED2" geopointsrc="USER"/>`)
+	if how, ok := evt.InferHow(); !ok || how != "h-e" {
+		t.Errorf("user precisionlocation: InferHow() = %q, %v; want h-e, true", how, ok)
+	}
+
+	evt.Detail.PrecisionLocation.Raw = []byte(`<precisionlocation altsrc="DTED2"/>`)
+	if how, ok := evt.InferHow(); ok {
+		t.Errorf("unknown source: InferHow() = %q, true; want false", how)
+	}
+}
+
+func TestEmptyDetailRoundTrip(t *testing.T) {
+	now := time.Now().UTC()
+	for _, detail := range []string{"<detail/>", "<detail></detail>", "<detail>\n  </detail>"} {
+		data := fmt.Sprintf(`<event version="2.0" uid="ED1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/>%s</event>`,
+			now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat), detail)
+		evt, err := UnmarshalXMLEvent(context.Background(), []byte(data))
+		if err != nil {
+			t.Fatalf("unmarshal %q: %v", detail, err)
+		}
+		out, err := evt.ToXML()
+		ReleaseEvent(evt)
+		if err != nil {
+			t.Fatalf("ToXML: %v", err)
+		}
+		if !bytes.Contains(out, []byte("  <detail/>\n")) || bytes.Contains(out, []byte("</detail>")) {
+			t.Errorf("%q encoded as:\n%s", detail, out)
+		}
+
+		again, err := UnmarshalXMLEvent(context.Background(), out)
+		if err != nil {
+			t.Fatalf("re-unmarshal: %v", err)
+		}
+		out2, err := again.ToXML()
+		ReleaseEvent(again)
+		if err != nil {
+			t.Fatalf("ToXML: %v", err)
+		}
+		if !bytes.Equal(out, out2) {
+			t.Errorf("round trip changed output:\n%s\n---\n%s", out, out2)
+		}
+	}
+
+	evt, err := NewEvent("ED2", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Detail = &Detail{Contact: &Contact{Callsign: "ALPHA"}}
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !bytes.Contains(out, []byte("<detail>\n    <contact")) {
+		t.Errorf("non-empty detail encoded as:\n%s", out)
+	}
+}
+
+func TestEventSummary(t *testing.T) {
+	evt, err := NewEvent("SUM1", "a-f-G", 30, -85, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Detail = &Detail{Contact: &Contact{Callsign: "ALPHA"}}
+	if err := evt.SetTTL(30 * time.Second); err != nil {
+		t.Fatalf("SetTTL: %v", err)
+	}
+
+	s := evt.Summary()
+	for _, want := range []string{"uid=SUM1", "type=a-f-G (Ground)", "callsign=ALPHA", "@30.00000,-85.00000", "stale=30s"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Summary() = %q, missing %q", s, want)
+		}
+	}
+
+	evt.Detail = nil
+	evt.NoPoint = true
+	s = evt.Summary()
+	if strings.Contains(s, "callsign=") || strings.Contains(s, "@") {
+		t.Errorf("Summary() without detail or point = %q", s)
+	}
+	if got := (*Event)(nil).Summary(); got != "<nil>" {
+		t.Errorf("nil Summary() = %q", got)
+	}
+}
+
+func TestStartOffsetAndSortByStart(t *testing.T) {
+	epoch := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := make([]*Event, 5)
+	for i, off := range []int{3, 0, 4, 1, 2} {
+		evt, err := NewEvent(fmt.Sprintf("S%d", off), "a-f-G", 1, 2, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.Start = CoTTime(epoch.Add(time.Duration(off-1) * time.Minute))
+		events[i] = evt
+	}
+
+	SortByStart(events)
+	for i, evt := range events {
+		if want := fmt.Sprintf("S%d", i); evt.Uid != want {
+			t.Errorf("events[%d] = %s, want %s", i, evt.Uid, want)
+		}
+	}
+
+	if got := events[0].StartOffset(epoch); got != -time.Minute {
+		t.Errorf("StartOffset before epoch = %v, want -1m", got)
+	}
+	if got := events[4].StartOffset(epoch); got != 3*time.Minute {
+		t.Errorf("StartOffset after epoch = %v, want 3m", got)
+	}
+	if got := (*Event)(nil).StartOffset(epoch); got != 0 {
+		t.Errorf("nil StartOffset = %v, want 0", got)
+	}
+}
+
+func TestEventAge(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	evt := &Event{
+		Time:  CoTTime(now.Add(-30 * time.Second)),
+		Start: CoTTime(now.Add(-10 * time.Second)),
+		Stale: CoTTime(now.Add(time.Minute)),
+	}
+	if got := evt.Age(now); got != 30*time.Second {
+		t.Errorf("Age = %v, want 30s", got)
+	}
+	if evt.IsStale(now) {
+		t.Error("fresh event reported stale")
+	}
+	if !evt.IsStale(now.Add(time.Minute)) {
+		t.Error("event not stale at its stale time")
+	}
+
+	future := &Event{Time: CoTTime(now.Add(5 * time.Second))}
+	if got := future.Age(now); got != -5*time.Second {
+		t.Errorf("future-dated Age = %v, want -5s", got)
+	}
+	if future.IsStale(now.Add(time.Hour)) {
+		t.Error("event without stale time reported stale")
+	}
+
+	var none *Event
+	if none.Age(now) != 0 || none.IsStale(now) {
+		t.Error("nil event: want zero age and not stale")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	evt, err := NewEvent("ORIG", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.InjectIdentity("ORIG", "Cyan", "Team Member")
+	evt.AddLink(&Link{Uid: "HQ", Type: "a-f-G-U-C", Relation: "p-p"})
+	evt.Detail.Chat = &Chat{
+		ID: "All Chat Rooms", Chatroom: "All Chat Rooms", Sender: "ORIG", SenderCallsign: "ALPHA",
+		ChatGrps: []ChatGrp{{ID: "All Chat Rooms", UID0: "ORIG", UID1: "All Chat Rooms"}},
+		Raw:      RawMessage(`<__chat sender="ORIG"/>`),
+	}
+
+	if err := evt.Rekey("bad uid"); !errors.Is(err, ErrInvalidUID) {
+		t.Fatalf("Rekey(invalid) = %v, want ErrInvalidUID", err)
+	}
+	if evt.Uid != "ORIG" {
+		t.Fatalf("failed Rekey changed uid to %q", evt.Uid)
+	}
+
+	if err := evt.Rekey("CLONE"); err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if evt.Uid != "CLONE" {
+		t.Errorf("Uid = %q, want CLONE", evt.Uid)
+	}
+	if evt.Links[0].Uid != "CLONE" || evt.Links[0].Relation != "p-p" {
+		t.Errorf("self link = %+v, want uid CLONE", evt.Links[0])
+	}
+	if evt.Links[1].Uid != "HQ" {
+		t.Errorf("unrelated link rekeyed: %+v", evt.Links[1])
+	}
+	c := evt.Detail.Chat
+	if c.Sender != "CLONE" || c.ChatGrps[0].UID0 != "CLONE" || c.ChatGrps[0].UID1 != "All Chat Rooms" {
+		t.Errorf("chat = %+v", c)
+	}
+
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if bytes.Contains(out, []byte("ORIG")) {
+		t.Errorf("old uid remains in output:\n%s", out)
+	}
+}
+
+func TestNewTaskingEvent(t *testing.T) {
+	evt, err := NewTaskingEvent("TASK-1", "t-k", "TGT-1", "a-h-G")
+	if err != nil {
+		t.Fatalf("NewTaskingEvent: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	if evt.Type != "t-k" || evt.Uid != "TASK-1" {
+		t.Errorf("event = %s %s", evt.Uid, evt.Type)
+	}
+	if evt.HasPoint() {
+		t.Error("tasking should carry no point")
+	}
+	if len(evt.Links) != 1 || evt.Links[0] != (Link{Uid: "TGT-1", Type: "a-h-G", Relation: "t-o"}) {
+		t.Errorf("links = %+v", evt.Links)
+	}
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !bytes.Contains(out, []byte(`<link uid="TGT-1" type="a-h-G" relation="t-o"/>`)) {
+		t.Errorf("target link missing:\n%s", out)
+	}
+
+	isr, err := NewTaskingEvent("TASK-2", "t-s-i-e", "TGT-1", "a-h-G")
+	if err != nil {
+		t.Fatalf("ISR tasking: %v", err)
+	}
+	ReleaseEvent(isr)
+
+	for _, tc := range []struct {
+		name, taskType, targetUID, targetType string
+	}{
+		{"non-tasking type", "a-h-G", "TGT-1", "a-h-G"},
+		{"unregistered tasking type", "t-zz-q", "TGT-1", "a-h-G"},
+		{"bad target type", "t-k", "TGT-1", "not a type"},
+		{"empty target uid", "t-k", "", "a-h-G"},
+	} {
+		if evt, err := NewTaskingEvent("TASK-3", tc.taskType, tc.targetUID, tc.targetType); err == nil {
+			ReleaseEvent(evt)
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+	if _, err := NewTaskingEvent("TASK-3", "a-h-G", "TGT-1", "a-h-G"); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("non-tasking type error %v does not wrap ErrInvalidType", err)
+	}
+	// TAK control messages are registered "t-" types but not taskings.
+	for _, typ := range []string{"t-x-d-d", "t-x-c-t"} {
+		if evt, err := NewTaskingEvent("TASK-4", typ, "TGT-1", "a-h-G"); !errors.Is(err, ErrInvalidType) {
+			if err == nil {
+				ReleaseEvent(evt)
+			}
+			t.Errorf("NewTaskingEvent(%q) error = %v, want ErrInvalidType", typ, err)
+		}
+	}
+}
+
+func TestNewMinimalEvent(t *testing.T) {
+	for _, typ := range []string{"a-f-G", "b-t-f", "b-e-r", "b-t-i"} {
+		evt, err := NewMinimalEvent(typ)
+		if err != nil {
+			t.Fatalf("NewMinimalEvent(%s): %v", typ, err)
+		}
+		if err := evt.Validate(); err != nil {
+			t.Errorf("%s: Validate: %v", typ, err)
+		}
+		if evt.Type != typ || evt.How != "m-g" || evt.Point != unknownPoint {
+			t.Errorf("%s: event = %+v", typ, evt)
+		}
+		ReleaseEvent(evt)
+	}
+
+	chat, err := NewMinimalEvent("b-t-f")
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	defer ReleaseEvent(chat)
+	if chat.Detail == nil || chat.Detail.Chat == nil || chat.Detail.Chat.Message == "" {
+		t.Errorf("chat detail = %+v", chat.Detail)
+	}
+
+	sos, err := NewMinimalEvent("b-e-r")
+	if err != nil {
+		t.Fatalf("emergency: %v", err)
+	}
+	defer ReleaseEvent(sos)
+	if sos.Detail == nil || sos.Detail.Emergency == nil {
+		t.Errorf("emergency detail = %+v", sos.Detail)
+	}
+
+	if _, err := NewMinimalEvent("a-f-G-zz-q"); !errors.Is(err, ErrTypeNotInCatalog) {
+		t.Errorf("unknown type error = %v, want ErrTypeNotInCatalog", err)
+	}
+	if _, err := NewMinimalEvent("a-f-*"); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("wildcard type error = %v, want ErrInvalidType", err)
+	}
+}
+
+func TestExpectedDetails(t *testing.T) {
+	if got := ExpectedDetails("b-t-f"); len(got) == 0 || got[0] != "__chat" {
+		t.Errorf("ExpectedDetails(b-t-f) = %v, want __chat first", got)
+	}
+	if got := ExpectedDetails("u-d-c-c"); !slices.Contains(got, "shape") || !slices.Contains(got, "strokecolor") {
+		t.Errorf("ExpectedDetails(u-d-c-c) = %v", got)
+	}
+	if got := ExpectedDetails("a-f-G-U-C-I"); !slices.Contains(got, "takv") {
+		t.Errorf("ExpectedDetails(a-f-G-U-C-I) = %v, want self-SA details", got)
+	}
+	if got := ExpectedDetails("a-h-A"); !slices.Equal(got, []string{"contact"}) {
+		t.Errorf("ExpectedDetails(a-h-A) = %v", got)
+	}
+	if got := ExpectedDetails("b-t-fx"); got != nil {
+		t.Errorf("ExpectedDetails(b-t-fx) = %v, want nil", got)
+	}
+
+	evt, err := NewMinimalEvent("b-t-f")
+	if err != nil {
+		t.Fatalf("NewMinimalEvent: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Detail.Remarks = &Remarks{Text: "hello"}
+	want := []string{"link", "__serverdestination", "marti"}
+	if got := evt.MissingDetails(); !slices.Equal(got, want) {
+		t.Errorf("MissingDetails = %v, want %v", got, want)
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		in    string
+		host  string
+		port  int
+		proto string
+	}{
+		{"192.168.1.5:4242:tcp", "192.168.1.5", 4242, "tcp"},
+		{"*:-1:stcp", "*", -1, "stcp"},
+		{"10.0.0.1:0:udp", "10.0.0.1", 0, "udp"},
+		{"[fe80::1]:4242:tcp", "fe80::1", 4242, "tcp"},
+		{"fe80::1:4242:tcp", "fe80::1", 4242, "tcp"},
+	}
+	for _, tt := range tests {
+		host, port, proto, err := ParseEndpoint(tt.in)
+		if err != nil || host != tt.host || port != tt.port || proto != tt.proto {
+			t.Errorf("ParseEndpoint(%q) = %q, %d, %q, %v; want %q, %d, %q", tt.in, host, port, proto, err, tt.host, tt.port, tt.proto)
+		}
+	}
+
+	for _, in := range []string{"", "tcp", "host:tcp", ":4242:tcp", "h:4242:", "h:-2:tcp", "h:65536:tcp", "h:port:tcp", "h:1:t/cp", "a b:1:tcp"} {
+		if _, _, _, err := ParseEndpoint(in); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("ParseEndpoint(%q) error = %v, want ErrInvalidInput", in, err)
+		}
+	}
+
+	c := &Contact{Callsign: "ALPHA", Endpoint: "*:-1:stcp"}
+	if host, port, proto, err := c.EndpointAddr(); err != nil || host != "*" || port != -1 || proto != "stcp" {
+		t.Errorf("EndpointAddr() = %q, %d, %q, %v", host, port, proto, err)
+	}
+	if _, _, _, err := (&Contact{}).EndpointAddr(); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("empty endpoint error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestValidateCoTTypesFile(t *testing.T) {
+	const data = `<?xml version="1.0"?>
+<types>
+  <cot cot="a-f-G-U-C" full="Combat" desc="Combat"/>
+  <cot cot="a-f" desc="typo: missing dimension"/>
+  <cot cot="b-m-p-lint-check" desc="custom marker"/>
+</types>`
+	path := t.TempDir() + "/CoTtypes.xml"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	valid, errs := ValidateCoTTypesFile(path)
+	if valid != 2 {
+		t.Errorf("valid = %d, want 2", valid)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errs = %v, want one error", errs)
+	}
+	if !errors.Is(errs[0], ErrMalformedType) || !strings.Contains(errs[0].Error(), "entry 2") {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+	if _, err := GetTypeFullName("b-m-p-lint-check"); err == nil {
+		t.Error("ValidateCoTTypesFile registered a type")
+	}
+
+	if valid, errs := ValidateCoTTypesFile(t.TempDir() + "/missing.xml"); valid != 0 || len(errs) != 1 {
+		t.Errorf("missing file: valid=%d errs=%v", valid, errs)
+	}
+}
+
+func TestTypeLoadersUseCatalogLimits(t *testing.T) {
+	limit := cottypes.DefaultDecodeLimits().MaxElements
+	var sb strings.Builder
+	sb.WriteString(`<types><cot cot="b-x-loader-limit"/>`)
+	for i := 0; i < limit; i++ {
+		sb.WriteString(`<note/>`)
+	}
+	sb.WriteString(`</types>`)
+	data := sb.String()
+
+	ctx := context.Background()
+	if err := RegisterCoTTypesFromXMLContent(ctx, data); err == nil {
+		t.Fatal("expected default type limits to reject the large document")
+	}
+
+	// Raising only the catalog limits is enough; event limits are untouched.
+	cottypes.SetDecodeLimits(cottypes.DecodeLimits{MaxElements: limit * 2})
+	defer cottypes.SetDecodeLimits(cottypes.DecodeLimits{})
+	if err := RegisterCoTTypesFromXMLContent(ctx, data); err != nil {
+		t.Fatalf("RegisterCoTTypesFromXMLContent: %v", err)
+	}
+	if err := RegisterCoTTypesFromReader(ctx, strings.NewReader(data)); err != nil {
+		t.Fatalf("RegisterCoTTypesFromReader: %v", err)
+	}
+	if err := ValidateType("b-x-loader-limit"); err != nil {
+		t.Errorf("type not registered: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package cottypes
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// cacheMagic starts every catalog cache. The final byte is the format
+// version and is bumped whenever the layout changes, so stale caches are
+// rejected.
+const cacheMagic = "COTC\x01"
+
+// GobEncode implements gob.GobEncoder. It snapshots the catalog's types in
+// a compact binary form so a tool can save a built catalog and restore it
+// with GobDecode or LoadCatalogFromCache. Types are written sorted by name
+// together with their upper-cased search keys, which makes loading a cache
+// little more than slicing strings out of one buffer.
+func (c *Catalog) GobEncode() ([]byte, error) {
+	types := c.GetAllTypes(context.Background())
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+
+	buf := make([]byte, 0, 64*len(types))
+	buf = append(buf, cacheMagic...)
+	buf = binary.AppendUvarint(buf, uint64(len(types)))
+	for _, t := range types {
+		for _, s := range [...]string{t.Name, t.FullName, t.Description, t.fullNameUpper, t.descriptionUpper} {
+			buf = binary.AppendUvarint(buf, uint64(len(s)))
+			buf = append(buf, s...)
+		}
+	}
+	return buf, nil
+}
+
+// GobDecode implements gob.GobDecoder. It replaces the catalog's contents
+// with the types encoded by GobEncode. Truncated caches and caches written
+// by an incompatible version of this package are rejected.
+func (c *Catalog) GobDecode(data []byte) error {
+	if len(data) < len(cacheMagic) || string(data[:len(cacheMagic)]) != cacheMagic {
+		return fmt.Errorf("decode catalog: not a catalog cache or unsupported version")
+	}
+	// Convert once so every field below is a substring of the same backing
+	// string rather than a separate allocation.
+	data = data[len(cacheMagic):]
+	s := string(data)
+	pos := 0
+	next := func() (uint64, bool) {
+		v, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return 0, false
+		}
+		pos += n
+		return v, true
+	}
+	count, ok := next()
+	if !ok || count > uint64(len(s)) {
+		return fmt.Errorf("decode catalog: bad type count")
+	}
+
+	types := make(map[string]Type, count)
+	var fields [5]string
+	for i := uint64(0); i < count; i++ {
+		for f := range fields {
+			n, ok := next()
+			if !ok || n > uint64(len(s)-pos) {
+				return fmt.Errorf("decode catalog: truncated entry %d", i)
+			}
+			fields[f] = s[pos : pos+int(n)]
+			pos += int(n)
+		}
+		if fields[0] == "" {
+			return fmt.Errorf("decode catalog: empty type name in entry %d", i)
+		}
+		types[fields[0]] = Type{
+			Name:             fields[0],
+			FullName:         fields[1],
+			Description:      fields[2],
+			fullNameUpper:    fields[3],
+			descriptionUpper: fields[4],
+		}
+	}
+	if pos != len(s) {
+		return fmt.Errorf("decode catalog: %d trailing bytes", len(s)-pos)
+	}
+
+	c.mu.Lock()
+	c.types = types
+	c.mu.Unlock()
+	return nil
+}
+
+// LoadCatalogFromCache reads a catalog written with Catalog.GobEncode. The
+// returned catalog is independent of the one returned by GetCatalog.
+func LoadCatalogFromCache(r io.Reader) (*Catalog, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read catalog cache: %w", err)
+	}
+	c := NewCatalog()
+	if err := c.GobDecode(data); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -2,7 +2,9 @@ package cottypes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -10,10 +12,13 @@ import (
 )
 
 // Type represents a CoT type with its metadata.
+//
+// Only the exported fields are part of the JSON encoding; the search caches
+// are rebuilt when a Type is added to a catalog.
 type Type struct {
-	Name        string // The CoT type code (e.g., "a-f-G-E-X-N")
-	FullName    string // The full name (e.g., "Gnd/Equip/Nbc Equipment")
-	Description string // The description (e.g., "NBC EQUIPMENT")
+	Name        string `json:"name"`        // The CoT type code (e.g., "a-f-G-E-X-N")
+	FullName    string `json:"full_name"`   // The full name (e.g., "Gnd/Equip/Nbc Equipment")
+	Description string `json:"description"` // The description (e.g., "NBC EQUIPMENT")
 
 	fullNameUpper    string
 	descriptionUpper string
@@ -105,6 +110,36 @@ func (c *Catalog) GetAllTypes(ctx context.Context) []Type {
 	return types
 }
 
+// MarshalJSON encodes the catalog as a JSON array of its types sorted by
+// name, suitable for serving from a /types endpoint.
+func (c *Catalog) MarshalJSON() ([]byte, error) {
+	types := c.GetAllTypes(context.Background())
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return json.Marshal(types)
+}
+
+// GroupByPrefix returns the catalog's types bucketed by the first segment
+// of their name, such as "a" for atoms or "y" for replies. Each bucket is
+// sorted by name.
+func (c *Catalog) GroupByPrefix(ctx context.Context) map[string][]Type {
+	logger := ctxlog.LoggerFromContext(ctx)
+
+	c.mu.RLock()
+	groups := make(map[string][]Type)
+	for _, t := range c.types {
+		family, _, _ := strings.Cut(t.Name, "-")
+		groups[family] = append(groups[family], t)
+	}
+	c.mu.RUnlock()
+
+	for _, types := range groups {
+		sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	}
+
+	logger.Debug("Grouped types by prefix", "families", len(groups))
+	return groups
+}
+
 // FindByDescription searches for types matching the given description (case-insensitive, partial match).
 // If desc is empty, returns all types.
 func (c *Catalog) FindByDescription(ctx context.Context, desc string) []Type {
@@ -192,6 +227,37 @@ func (c *Catalog) Upsert(ctx context.Context, name string, t Type) error {
 	return nil
 }
 
+// Merge copies every type from other into c, for layering a site-specific
+// overlay on a base catalog. Types already present in c are replaced only
+// if overwrite is true. It returns the number of types added or replaced.
+func (c *Catalog) Merge(ctx context.Context, other *Catalog, overwrite bool) int {
+	logger := ctxlog.LoggerFromContext(ctx)
+	if other == nil || other == c {
+		return 0
+	}
+
+	// Snapshot other first so the two catalogs are never locked together.
+	types := other.GetAllTypes(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	applied := 0
+	for _, t := range types {
+		if _, exists := c.types[t.Name]; exists && !overwrite {
+			continue
+		}
+		c.types[t.Name] = t
+		applied++
+	}
+
+	logger.Debug("Merged catalog",
+		"types", len(types),
+		"applied", applied,
+		"overwrite", overwrite)
+	return applied
+}
+
 // Find returns all types that match the given pattern (exact or prefix match).
 func (c *Catalog) Find(ctx context.Context, pattern string) []Type {
 	logger := ctxlog.LoggerFromContext(ctx)
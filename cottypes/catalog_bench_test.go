@@ -1,6 +1,7 @@
 package cottypes
 
 import (
+	"bytes"
 	"context"
 	"testing"
 )
@@ -36,3 +37,30 @@ func BenchmarkCatalogFindByDescription(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkCatalogColdInit builds a catalog from the generated type table,
+// as GetCatalog does on first use.
+func BenchmarkCatalogColdInit(b *testing.B) {
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		cat := NewCatalog()
+		for _, t := range expandedTypes {
+			if err := cat.Upsert(ctx, t.Name, Type{Name: t.Name, FullName: t.FullName, Description: t.Description}); err != nil {
+				b.Fatalf("Upsert: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkCatalogCacheLoad(b *testing.B) {
+	data, err := GetCatalog().GobEncode()
+	if err != nil {
+		b.Fatalf("GobEncode: %v", err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadCatalogFromCache(bytes.NewReader(data)); err != nil {
+			b.Fatalf("LoadCatalogFromCache: %v", err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cottypes_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -628,3 +629,154 @@ func TestTAKNamespaceIntegrity(t *testing.T) {
 		}
 	})
 }
+
+func TestTypeJSON(t *testing.T) {
+	typ := cottypes.Type{Name: "a-f-G-E-X-N", FullName: "Gnd/Equip/Nbc Equipment", Description: "NBC EQUIPMENT"}
+	data, err := json.Marshal(typ)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"name":"a-f-G-E-X-N","full_name":"Gnd/Equip/Nbc Equipment","description":"NBC EQUIPMENT"}`
+	if string(data) != want {
+		t.Errorf("marshal = %s, want %s", data, want)
+	}
+	var out cottypes.Type
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out != typ {
+		t.Errorf("round trip = %+v, want %+v", out, typ)
+	}
+
+	// Catalog entries carry search caches that must not leak into JSON.
+	cat := cottypes.NewCatalog()
+	ctx := context.Background()
+	for _, name := range []string{"b-t-f", "a-f-G"} {
+		if err := cat.Upsert(ctx, name, cottypes.Type{Name: name, FullName: "Full " + name, Description: "Desc " + name}); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	data, err = json.Marshal(cat)
+	if err != nil {
+		t.Fatalf("marshal catalog: %v", err)
+	}
+	want = `[{"name":"a-f-G","full_name":"Full a-f-G","description":"Desc a-f-G"},{"name":"b-t-f","full_name":"Full b-t-f","description":"Desc b-t-f"}]`
+	if string(data) != want {
+		t.Errorf("catalog JSON = %s, want %s", data, want)
+	}
+}
+
+func TestGroupByPrefix(t *testing.T) {
+	groups := cottypes.GetCatalog().GroupByPrefix(context.Background())
+
+	largest, size := "", 0
+	total := 0
+	for family, types := range groups {
+		total += len(types)
+		if len(types) > size {
+			largest, size = family, len(types)
+		}
+		for i := 1; i < len(types); i++ {
+			if types[i-1].Name >= types[i].Name {
+				t.Errorf("bucket %q not sorted at %d: %s >= %s", family, i, types[i-1].Name, types[i].Name)
+				break
+			}
+		}
+		for _, typ := range types {
+			if !strings.HasPrefix(typ.Name+"-", family+"-") {
+				t.Errorf("type %s in bucket %q", typ.Name, family)
+				break
+			}
+		}
+	}
+	if largest != "a" {
+		t.Errorf("largest bucket = %q (%d types), want a", largest, size)
+	}
+	if len(groups["y"]) == 0 {
+		t.Error("y bucket is empty")
+	}
+	if all := cottypes.GetCatalog().GetAllTypes(context.Background()); total != len(all) {
+		t.Errorf("grouped %d types, catalog has %d", total, len(all))
+	}
+}
+
+func TestCatalogMerge(t *testing.T) {
+	ctx := context.Background()
+	base := cottypes.NewCatalog()
+	overlay := cottypes.NewCatalog()
+	for _, typ := range []cottypes.Type{
+		{Name: "a-f-G", FullName: "Gnd", Description: "GROUND"},
+		{Name: "a-f-A", FullName: "Air", Description: "AIR"},
+	} {
+		if err := base.Upsert(ctx, typ.Name, typ); err != nil {
+			t.Fatalf("upsert base: %v", err)
+		}
+	}
+	for _, typ := range []cottypes.Type{
+		{Name: "a-f-G", FullName: "Gnd", Description: "SITE GROUND"},
+		{Name: "a-f-G-X-site", FullName: "Gnd/Site", Description: "SITE MARKER"},
+	} {
+		if err := overlay.Upsert(ctx, typ.Name, typ); err != nil {
+			t.Fatalf("upsert overlay: %v", err)
+		}
+	}
+
+	if n := base.Merge(ctx, overlay, false); n != 1 {
+		t.Errorf("Merge without overwrite applied %d, want 1", n)
+	}
+	if desc, _ := base.GetDescription(ctx, "a-f-G"); desc != "GROUND" {
+		t.Errorf("description overwritten without overwrite: %q", desc)
+	}
+
+	if n := base.Merge(ctx, overlay, true); n != 2 {
+		t.Errorf("Merge with overwrite applied %d, want 2", n)
+	}
+	if desc, _ := base.GetDescription(ctx, "a-f-G"); desc != "SITE GROUND" {
+		t.Errorf("description = %q, want overlay value", desc)
+	}
+	if desc, _ := base.GetDescription(ctx, "a-f-G-X-site"); desc != "SITE MARKER" {
+		t.Errorf("new type description = %q", desc)
+	}
+	if got := base.FindByDescription(ctx, "site marker"); len(got) != 1 {
+		t.Errorf("merged type not searchable: %v", got)
+	}
+	if n := len(base.GetAllTypes(ctx)); n != 3 {
+		t.Errorf("merged catalog has %d types, want 3", n)
+	}
+
+	if n := base.Merge(ctx, base, true); n != 0 {
+		t.Errorf("self merge applied %d", n)
+	}
+	if n := base.Merge(ctx, nil, true); n != 0 {
+		t.Errorf("nil merge applied %d", n)
+	}
+}
+
+func TestCatalogCache(t *testing.T) {
+	ctx := context.Background()
+	cat := cottypes.GetCatalog()
+	data, err := cat.GobEncode()
+	if err != nil {
+		t.Fatalf("GobEncode: %v", err)
+	}
+
+	loaded, err := cottypes.LoadCatalogFromCache(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("LoadCatalogFromCache: %v", err)
+	}
+	if got, want := len(loaded.GetAllTypes(ctx)), len(cat.GetAllTypes(ctx)); got != want {
+		t.Errorf("loaded %d types, want %d", got, want)
+	}
+	want, _ := cat.GetType(ctx, "a-f-G-E-X-N")
+	got, err := loaded.GetType(ctx, "a-f-G-E-X-N")
+	if err != nil || got.FullName != want.FullName || got.Description != want.Description {
+		t.Errorf("GetType = %+v, %v, want %+v", got, err, want)
+	}
+	if len(loaded.FindByDescription(ctx, "nbc equipment")) == 0 {
+		t.Error("FindByDescription found nothing in the loaded catalog")
+	}
+
+	if _, err := cottypes.LoadCatalogFromCache(strings.NewReader("not a cache")); err == nil {
+		t.Error("expected error for corrupt cache")
+	}
+}
@@ -5254,6 +5254,7 @@ var expandedTypes = []TypeInfo{
 	{Name: "b-t-f", FullName: "TAK/Chat/FreeText", Description: "GeoChat text message"},
 	{Name: "t-x-c", FullName: "TAK/Chat/Client", Description: "Client chat message"},
 	{Name: "t-x-c-t", FullName: "TAK/Chat/Text", Description: "Plain-text chat"},
+	{Name: "t-x-c-t-r", FullName: "TAK/Control/PingReply", Description: "Keepalive reply to a t-x-c-t ping (pong)"},
 	{Name: "t-x-m", FullName: "TAK/Message/General", Description: "General system message"},
 	{Name: "y-c-r", FullName: "TAK/Reply/Chat", Description: "Chat reply"},
 	{Name: "y-m-r", FullName: "TAK/Reply/Message", Description: "Message reply"},
@@ -5261,6 +5262,7 @@ var expandedTypes = []TypeInfo{
 	{Name: "t-x-t-a", FullName: "TAK/Task/Assignment", Description: "Task assignment"},
 	{Name: "t-x-t-s", FullName: "TAK/Task/Status", Description: "Task status"},
 	{Name: "y-t-r", FullName: "TAK/Reply/Task", Description: "Task reply"},
+	{Name: "t-x-d-d", FullName: "TAK/Task/Delete", Description: "Delete marker tasking"},
 	{Name: "t-x-takp-v", FullName: "TAK/Presence/Version", Description: "Presence version broadcast"},
 	{Name: "t-x-takp-q", FullName: "TAK/Presence/Query", Description: "Presence query"},
 	{Name: "t-x-takp-r", FullName: "TAK/Presence/Response", Description: "Presence response"},
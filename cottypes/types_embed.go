@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"log/slog"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/NERVsystems/cotlib/ctxlog"
 )
@@ -21,11 +23,75 @@ var (
 
 var doctypePattern = regexp.MustCompile(`(?i)<!\s*DOCTYPE`)
 
+// DecodeLimits bounds the XML accepted when parsing type definitions with
+// RegisterXML or DecodeXML. The limits are independent of the cotlib event
+// decoding limits so that loading a large custom catalog does not require
+// relaxing the limits applied to untrusted events.
+type DecodeLimits struct {
+	// MaxDepth is the maximum element nesting depth.
+	MaxDepth int
+	// MaxElements is the maximum number of elements in a document.
+	MaxElements int
+	// MaxAttributes is the maximum number of attributes on one element.
+	MaxAttributes int
+	// MaxValueLen is the maximum length of an attribute value or text node.
+	MaxValueLen int
+	// MaxTokenLen is the maximum size in bytes of a single XML token.
+	MaxTokenLen int
+}
+
+// defaultDecodeLimits are the secure limits used unless SetDecodeLimits
+// changes them.
+var defaultDecodeLimits = DecodeLimits{
+	MaxDepth:      32,
+	MaxElements:   10000,
+	MaxAttributes: 256,
+	MaxValueLen:   512 * 1024,
+	MaxTokenLen:   4096,
+}
+
+var decodeLimits atomic.Pointer[DecodeLimits]
+
+func init() {
+	decodeLimits.Store(&defaultDecodeLimits)
+}
+
+// DefaultDecodeLimits returns the default limits used by RegisterXML and
+// DecodeXML.
+func DefaultDecodeLimits() DecodeLimits {
+	return defaultDecodeLimits
+}
+
+// SetDecodeLimits replaces the limits used by RegisterXML and DecodeXML,
+// and so by the cotlib type file loaders, for example to raise MaxElements
+// before loading a large custom CoTtypes.xml. Fields that are zero or
+// negative use their default value, so SetDecodeLimits(DecodeLimits{})
+// restores the defaults.
+func SetDecodeLimits(l DecodeLimits) {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = defaultDecodeLimits.MaxDepth
+	}
+	if l.MaxElements <= 0 {
+		l.MaxElements = defaultDecodeLimits.MaxElements
+	}
+	if l.MaxAttributes <= 0 {
+		l.MaxAttributes = defaultDecodeLimits.MaxAttributes
+	}
+	if l.MaxValueLen <= 0 {
+		l.MaxValueLen = defaultDecodeLimits.MaxValueLen
+	}
+	if l.MaxTokenLen <= 0 {
+		l.MaxTokenLen = defaultDecodeLimits.MaxTokenLen
+	}
+	decodeLimits.Store(&l)
+}
+
 // limitTokenReader enforces basic XML token limits during decoding.
 type limitTokenReader struct {
-	dec   *xml.Decoder
-	depth int
-	count int
+	dec    *xml.Decoder
+	limits *DecodeLimits
+	depth  int
+	count  int
 }
 
 func (l *limitTokenReader) Token() (xml.Token, error) {
@@ -34,18 +100,18 @@ func (l *limitTokenReader) Token() (xml.Token, error) {
 	if err != nil {
 		return tok, err
 	}
-	if l.dec.InputOffset()-off > 4096 {
+	if l.dec.InputOffset()-off > int64(l.limits.MaxTokenLen) {
 		return nil, fmt.Errorf("invalid input")
 	}
 	switch t := tok.(type) {
 	case xml.StartElement:
 		l.depth++
 		l.count++
-		if l.depth > 32 || l.count > 10000 {
+		if l.depth > l.limits.MaxDepth || l.count > l.limits.MaxElements || len(t.Attr) > l.limits.MaxAttributes {
 			return nil, fmt.Errorf("invalid input")
 		}
 		for _, a := range t.Attr {
-			if len(a.Value) > 512*1024 {
+			if len(a.Value) > l.limits.MaxValueLen {
 				return nil, fmt.Errorf("invalid input")
 			}
 		}
@@ -54,18 +120,39 @@ func (l *limitTokenReader) Token() (xml.Token, error) {
 			l.depth--
 		}
 	case xml.CharData:
-		if len(t) > 512*1024 {
+		if len(t) > l.limits.MaxValueLen {
 			return nil, fmt.Errorf("invalid input")
 		}
 	}
 	return tok, nil
 }
 
+// NewSecureDecoder returns a decoder reading r with the hardening applied
+// to all XML parsed by this module: no custom entity map, so only the
+// predefined XML entities are expanded and external entities are never
+// resolved, and no CharsetReader, so input in charsets other than UTF-8 is
+// rejected. Both this package and cotlib construct every decoder here so
+// the settings cannot drift between entry points.
+func NewSecureDecoder(r io.Reader) *xml.Decoder {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = nil
+	dec.Entity = nil
+	return dec
+}
+
 func decodeWithLimits(dec *xml.Decoder, v any) error {
-	ltd := &limitTokenReader{dec: dec}
+	ltd := &limitTokenReader{dec: dec, limits: decodeLimits.Load()}
 	return xml.NewTokenDecoder(ltd).Decode(v)
 }
 
+// DecodeXML decodes a type definition document in data into v with a
+// hardened decoder, enforcing the limits set by SetDecodeLimits. It is
+// used by RegisterXML and by the cotlib functions that load custom
+// CoTtypes.xml files, so all type definitions share one set of limits.
+func DecodeXML(data []byte, v any) error {
+	return decodeWithLimits(NewSecureDecoder(bytes.NewReader(data)), v)
+}
+
 // SetLogger sets the logger for the catalog package.
 func SetLogger(l *slog.Logger) {
 	logger = l
@@ -163,10 +250,7 @@ func RegisterXML(ctx context.Context, data []byte) error {
 		} `xml:"cot"`
 	}
 
-	dec := xml.NewDecoder(bytes.NewReader(data))
-	dec.CharsetReader = nil
-	dec.Entity = nil
-	if err := decodeWithLimits(dec, &types); err != nil {
+	if err := DecodeXML(data, &types); err != nil {
 		return fmt.Errorf("failed to decode XML: %w", err)
 	}
 
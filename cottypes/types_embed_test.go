@@ -3,6 +3,7 @@ package cottypes_test
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"strings"
@@ -99,3 +100,70 @@ func TestRealWorldTypeRegistration(t *testing.T) {
 		t.Errorf("Found %d 'Added new type' messages, but expected no more than 5", addedTypeCount)
 	}
 }
+
+func TestSetDecodeLimits(t *testing.T) {
+	// A types file with more elements than the default limit allows.
+	limit := cottypes.DefaultDecodeLimits().MaxElements
+	var sb strings.Builder
+	sb.WriteString(`<types>`)
+	sb.WriteString(`<cot cot="b-x-limit-1" full="Test/Limit/One" desc="Limit test one"/>`)
+	sb.WriteString(`<cot cot="b-x-limit-2" full="Test/Limit/Two" desc="Limit test two"/>`)
+	for i := 0; i < limit; i++ {
+		sb.WriteString(`<note/>`)
+	}
+	sb.WriteString(`</types>`)
+	data := []byte(sb.String())
+
+	ctx := context.Background()
+	if err := cottypes.RegisterXML(ctx, data); err == nil {
+		t.Fatal("expected default limits to reject the large file")
+	}
+	if _, err := cottypes.GetCatalog().GetType(ctx, "b-x-limit-1"); err == nil {
+		t.Fatal("type registered despite limit error")
+	}
+
+	cottypes.SetDecodeLimits(cottypes.DecodeLimits{MaxElements: limit * 2})
+	defer cottypes.SetDecodeLimits(cottypes.DecodeLimits{})
+	if err := cottypes.RegisterXML(ctx, data); err != nil {
+		t.Fatalf("RegisterXML with raised limit: %v", err)
+	}
+	for _, name := range []string{"b-x-limit-1", "b-x-limit-2"} {
+		if _, err := cottypes.GetCatalog().GetType(ctx, name); err != nil {
+			t.Errorf("type %s not registered: %v", name, err)
+		}
+	}
+
+	cottypes.SetDecodeLimits(cottypes.DecodeLimits{})
+	if err := cottypes.RegisterXML(ctx, data); err == nil {
+		t.Error("expected restored defaults to reject the large file")
+	}
+}
+
+func TestDecodeLimitsMaxAttributes(t *testing.T) {
+	if got := cottypes.DefaultDecodeLimits().MaxAttributes; got != 256 {
+		t.Fatalf("default MaxAttributes = %d, want 256", got)
+	}
+	var sb strings.Builder
+	sb.WriteString(`<types><cot cot="b-x-attrs-1" full="Test/Attrs" desc="Attribute flood"`)
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&sb, ` a%d="x"`, i)
+	}
+	sb.WriteString(`/></types>`)
+	data := []byte(sb.String())
+
+	// Raise the token limit so only the attribute count applies.
+	cottypes.SetDecodeLimits(cottypes.DecodeLimits{MaxTokenLen: 1 << 20})
+	defer cottypes.SetDecodeLimits(cottypes.DecodeLimits{})
+	ctx := context.Background()
+	if err := cottypes.RegisterXML(ctx, data); err == nil {
+		t.Fatal("expected element with 5000 attributes to be rejected")
+	}
+	if _, err := cottypes.GetCatalog().GetType(ctx, "b-x-attrs-1"); err == nil {
+		t.Fatal("type registered despite attribute limit")
+	}
+
+	cottypes.SetDecodeLimits(cottypes.DecodeLimits{MaxTokenLen: 1 << 20, MaxAttributes: 10000})
+	if err := cottypes.RegisterXML(ctx, data); err != nil {
+		t.Fatalf("RegisterXML with raised attribute limit: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package cotlib
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dataPackageType is the catalog's mission-package type, one of the file
+// transfer types ExpectedDetails and NewMinimalEvent pair with <fileshare>.
+const dataPackageType = "b-t-b"
+
+// NewDataPackageEvent builds the b-t-b event TAK clients send to share a
+// data package. The <fileshare> detail advertises the package name, the
+// downloadURL, its SHA-256 hash (64 hex digits) and size, and the sender.
+// A ".zip" suffix on name is moved to the filename attribute, which always
+// carries it. senderUid and senderCallsign must be XML names, as the
+// fileshare schema requires.
+//
+// It returns an error wrapping ErrInvalidInput for a malformed url, hash or
+// size. The event is validated before it is returned; it is obtained from
+// the internal pool and may be released with ReleaseEvent.
+func NewDataPackageEvent(uid, name, downloadURL, sha256 string, sizeBytes int64, senderUid, senderCallsign string) (*Event, error) {
+	if u, err := url.Parse(downloadURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid package url %q: %w", downloadURL, ErrInvalidInput)
+	}
+	if b, err := hex.DecodeString(sha256); err != nil || len(b) != 32 {
+		return nil, fmt.Errorf("invalid sha256 %q: %w", sha256, ErrInvalidInput)
+	}
+	if sizeBytes < 0 {
+		return nil, fmt.Errorf("negative package size %d: %w", sizeBytes, ErrInvalidInput)
+	}
+	name = strings.TrimSuffix(name, ".zip")
+	if name == "" {
+		return nil, fmt.Errorf("empty package name: %w", ErrInvalidInput)
+	}
+
+	share := struct {
+		XMLName        xml.Name `xml:"fileshare"`
+		Filename       string   `xml:"filename,attr"`
+		Name           string   `xml:"name,attr"`
+		SenderCallsign string   `xml:"senderCallsign,attr"`
+		SenderUid      string   `xml:"senderUid,attr"`
+		SenderUrl      string   `xml:"senderUrl,attr"`
+		Sha256         string   `xml:"sha256,attr"`
+		SizeInBytes    string   `xml:"sizeInBytes,attr"`
+	}{
+		Filename:       name + ".zip",
+		Name:           name,
+		SenderCallsign: senderCallsign,
+		SenderUid:      senderUid,
+		SenderUrl:      downloadURL,
+		Sha256:         strings.ToLower(sha256),
+		SizeInBytes:    strconv.FormatInt(sizeBytes, 10),
+	}
+	raw, err := xml.Marshal(share)
+	if err != nil {
+		return nil, fmt.Errorf("marshal fileshare: %w", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	evt := getEvent()
+	*evt = Event{
+		Version: "2.0",
+		Uid:     uid,
+		Type:    dataPackageType,
+		How:     "h-e",
+		Time:    CoTTime(now),
+		Start:   CoTTime(now),
+		Stale:   CoTTime(now.Add(defaultStaleOffset)),
+		Point:   unknownPoint,
+		Detail:  &Detail{FileShare: &FileShare{Raw: raw}},
+	}
+	if err := evt.ValidateAt(now); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	return evt, nil
+}
+
+// DataPackageURL returns the download URL advertised by the event's
+// <fileshare> detail. ok is false if the event has no fileshare or it
+// carries no senderUrl.
+func (e *Event) DataPackageURL() (string, bool) {
+	if e == nil || e.Detail == nil || e.Detail.FileShare == nil {
+		return "", false
+	}
+	var helper struct {
+		SenderUrl string `xml:"senderUrl,attr"`
+	}
+	if err := xml.Unmarshal(e.Detail.FileShare.Raw, &helper); err != nil || helper.SenderUrl == "" {
+		return "", false
+	}
+	return helper.SenderUrl, true
+}
@@ -3,8 +3,9 @@ package cotlib
 import "encoding/xml"
 
 // limitTokenReader wraps an xml.Decoder and enforces XML security limits
-// while streaming tokens. It checks element depth, element count,
-// attribute/character data length, and token length as tokens are read.
+// while streaming tokens. It checks element depth, element count, the
+// number of attributes per element, attribute/character data length, and
+// token length as tokens are read.
 type limitTokenReader struct {
 	dec   *xml.Decoder
 	depth int
@@ -27,6 +28,9 @@ func (l *limitTokenReader) Token() (xml.Token, error) {
 		if l.depth > int(currentMaxElementDepth()) || l.count > int(currentMaxElementCount()) {
 			return nil, ErrInvalidInput
 		}
+		if len(t.Attr) > int(currentMaxAttrCount()) {
+			return nil, ErrInvalidInput
+		}
 		for _, a := range t.Attr {
 			if len(a.Value) > int(currentMaxValueLen()) {
 				return nil, ErrInvalidInput
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/xml"
 	"sync"
+
+	"github.com/NERVsystems/cotlib/cottypes"
 )
 
 // pooledDecoder wraps an xml.Decoder with a reusable bytes.Reader.
@@ -15,16 +17,14 @@ type pooledDecoder struct {
 var decoderPool = sync.Pool{
 	New: func() any {
 		br := bytes.NewReader(nil)
-		return &pooledDecoder{dec: xml.NewDecoder(br), br: br}
+		return &pooledDecoder{dec: cottypes.NewSecureDecoder(br), br: br}
 	},
 }
 
 func getDecoder(data []byte) *pooledDecoder {
 	pd := decoderPool.Get().(*pooledDecoder)
 	pd.br.Reset(data)
-	pd.dec = xml.NewDecoder(pd.br)
-	pd.dec.CharsetReader = nil
-	pd.dec.Entity = nil
+	pd.dec = cottypes.NewSecureDecoder(pd.br)
 	return pd
 }
 
@@ -6,7 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
+	"github.com/NERVsystems/cotlib/cottypes"
 	"github.com/NERVsystems/cotlib/validator"
 )
 
@@ -37,8 +41,20 @@ type Chat struct {
 	ChatGrps       []ChatGrp  `xml:"chatgrp,omitempty"`
 	Hierarchy      *Hierarchy `xml:"hierarchy,omitempty"`
 	Raw            RawMessage `xml:"-"`
+	// SchemaVariant records which schema accepted the chat when it was
+	// unmarshalled: ChatVariantCoT or ChatVariantTAK. It is empty for
+	// chats constructed in code.
+	SchemaVariant string `xml:"-"`
 }
 
+// Chat schema variants reported by Chat.SchemaVariant.
+const (
+	// ChatVariantCoT indicates the chat matched the strict chat schema.
+	ChatVariantCoT = "cot"
+	// ChatVariantTAK indicates the chat only matched the TAK __chat schema.
+	ChatVariantTAK = "tak"
+)
+
 // IsGroupChat reports whether the chat message targets a group.
 // A chat is considered a group chat when the Chatroom or GroupOwner
 // fields are non-empty or when any ChatGrp elements are present.
@@ -64,8 +80,95 @@ type ChatReceipt struct {
 }
 
 // Geofence represents the TAK __geofence extension.
+// The monitor and trigger attributes are parsed during unmarshalling and
+// are available through Monitor and Trigger.
 type Geofence struct {
-	Raw RawMessage
+	Raw     RawMessage
+	monitor string
+	trigger string
+}
+
+// Allowed values for the __geofence monitor and trigger attributes.
+var (
+	geofenceMonitors = map[string]bool{"in": true, "out": true, "both": true}
+	geofenceTriggers = map[string]bool{"enter": true, "entry": true, "exit": true}
+)
+
+// NewGeofence returns a geofence extension describing a sphere of radiusM
+// meters around center. The event carrying the extension should use center
+// as its point. Elevation monitoring is disabled; the elevation bounds are
+// set to the extent of the sphere. The monitor and trigger values are
+// checked when the event is validated.
+func NewGeofence(center Point, radiusM float64, monitor, trigger string) *Geofence {
+	ff := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	var buf bytes.Buffer
+	buf.WriteString(`<__geofence elevationMonitored="false" minElevation="`)
+	buf.WriteString(ff(center.Hae - radiusM))
+	buf.WriteString(`" monitor="`)
+	xml.EscapeText(&buf, []byte(monitor))
+	buf.WriteString(`" trigger="`)
+	xml.EscapeText(&buf, []byte(trigger))
+	buf.WriteString(`" tracking="true" maxElevation="`)
+	buf.WriteString(ff(center.Hae + radiusM))
+	buf.WriteString(`" boundingSphere="`)
+	buf.WriteString(ff(radiusM))
+	buf.WriteString(`"/>`)
+	return &Geofence{Raw: buf.Bytes(), monitor: monitor, trigger: trigger}
+}
+
+// Monitor returns the monitor attribute of the geofence.
+func (g *Geofence) Monitor() string {
+	if g == nil {
+		return ""
+	}
+	if g.monitor == "" && len(g.Raw) > 0 {
+		monitor, _, _ := parseGeofence(g.Raw)
+		return monitor
+	}
+	return g.monitor
+}
+
+// Trigger returns the trigger attribute of the geofence.
+func (g *Geofence) Trigger() string {
+	if g == nil {
+		return ""
+	}
+	if g.trigger == "" && len(g.Raw) > 0 {
+		_, trigger, _ := parseGeofence(g.Raw)
+		return trigger
+	}
+	return g.trigger
+}
+
+// parseGeofence returns the monitor and trigger attributes of a raw
+// __geofence element. It does not modify any Geofence, so the accessors
+// and validation are safe to call concurrently.
+func parseGeofence(raw RawMessage) (monitor, trigger string, err error) {
+	var helper struct {
+		XMLName xml.Name `xml:"__geofence"`
+		Monitor string   `xml:"monitor,attr"`
+		Trigger string   `xml:"trigger,attr"`
+	}
+	if err := xml.Unmarshal(raw, &helper); err != nil {
+		return "", "", err
+	}
+	return helper.Monitor, helper.Trigger, nil
+}
+
+// validateEnums checks the monitor and trigger attributes against the
+// values understood by TAK clients.
+func (g *Geofence) validateEnums() error {
+	monitor, trigger, err := parseGeofence(g.Raw)
+	if err != nil {
+		return err
+	}
+	if !geofenceMonitors[monitor] {
+		return fmt.Errorf("invalid monitor %q", monitor)
+	}
+	if !geofenceTriggers[trigger] {
+		return fmt.Errorf("invalid trigger %q", trigger)
+	}
+	return nil
 }
 
 // ServerDestination represents the TAK __serverdestination extension.
@@ -88,11 +191,43 @@ type Archive struct {
 	Raw RawMessage
 }
 
-// AttachmentList represents the TAK attachmentList extension.
+// AttachmentList represents the TAK attachment_list extension, which
+// references data package attachments by file hash.
 type AttachmentList struct {
 	Raw RawMessage
 }
 
+// NewAttachmentList returns an attachment_list extension referencing the
+// given file hashes.
+func NewAttachmentList(hashes ...string) *AttachmentList {
+	var buf bytes.Buffer
+	buf.WriteString(`<attachment_list hashes="`)
+	xml.EscapeText(&buf, []byte(strings.Join(hashes, ",")))
+	buf.WriteString(`"/>`)
+	return &AttachmentList{Raw: buf.Bytes()}
+}
+
+// Hashes returns the file hashes listed in the hashes attribute. It
+// returns nil if the attribute is empty or Raw cannot be parsed.
+func (a *AttachmentList) Hashes() []string {
+	if a == nil || len(a.Raw) == 0 {
+		return nil
+	}
+	var helper struct {
+		Hashes string `xml:"hashes,attr"`
+	}
+	if err := xml.Unmarshal(a.Raw, &helper); err != nil {
+		return nil
+	}
+	var hashes []string
+	for _, h := range strings.Split(helper.Hashes, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
+
 // Environment represents the TAK environment extension.
 type Environment struct {
 	Raw RawMessage
@@ -148,9 +283,27 @@ type FillColor struct {
 	Raw RawMessage
 }
 
-// LabelsOn represents the TAK labelson extension.
+// LabelsOn represents the TAK labelson extension, which toggles the labels
+// drawn on shapes and routes. Value is decoded from the value attribute.
+// Raw preserves the original element and is emitted when set; otherwise the
+// element is rebuilt from Value.
 type LabelsOn struct {
-	Raw RawMessage
+	Value bool
+	Raw   RawMessage
+}
+
+// NewLabelsOn returns a labelson extension with labels shown or hidden.
+func NewLabelsOn(on bool) *LabelsOn {
+	return &LabelsOn{Value: on}
+}
+
+// LabelsVisible reports whether the event's labelson detail shows labels.
+// ok is false if the event has no labelson detail.
+func (e *Event) LabelsVisible() (visible, ok bool) {
+	if e == nil || e.Detail == nil || e.Detail.LabelsOn == nil {
+		return false, false
+	}
+	return e.Detail.LabelsOn.Value, true
 }
 
 // ColorExtension represents the TAK color extension.
@@ -163,6 +316,31 @@ type UserIcon struct {
 	Raw RawMessage
 }
 
+// NewUserIcon returns a usericon extension referencing the given iconset
+// path, for example "f7f71666-8b28-4b57-9fbb-e38e61d33b79/Google/hiker.png".
+func NewUserIcon(iconsetPath string) *UserIcon {
+	var buf bytes.Buffer
+	buf.WriteString(`<usericon iconsetpath="`)
+	xml.EscapeText(&buf, []byte(iconsetPath))
+	buf.WriteString(`"/>`)
+	return &UserIcon{Raw: buf.Bytes()}
+}
+
+// IconsetPath returns the iconsetpath attribute. It returns "" if the
+// attribute is missing or Raw cannot be parsed.
+func (ui *UserIcon) IconsetPath() string {
+	if ui == nil || len(ui.Raw) == 0 {
+		return ""
+	}
+	var helper struct {
+		IconsetPath string `xml:"iconsetpath,attr"`
+	}
+	if err := xml.Unmarshal(ui.Raw, &helper); err != nil {
+		return ""
+	}
+	return helper.IconsetPath
+}
+
 // UID represents the TAK uid extension.
 type UID struct {
 	Raw RawMessage
@@ -188,13 +366,62 @@ type Hierarchy struct {
 	Raw RawMessage
 }
 
-// DetailLink represents the TAK link detail extension.
-type DetailLink struct {
-	Raw RawMessage
+// HierarchyNode is a node in the organisational tree described by a
+// hierarchy extension. Groups and contacts are both represented as nodes;
+// contacts never have children.
+type HierarchyNode struct {
+	UID      string
+	Name     string
+	Children []HierarchyNode
+}
+
+// hierarchyGroup mirrors a <group> element within a hierarchy extension.
+type hierarchyGroup struct {
+	UID      string `xml:"uid,attr"`
+	Name     string `xml:"name,attr"`
+	Contacts []struct {
+		UID  string `xml:"uid,attr"`
+		Name string `xml:"name,attr"`
+	} `xml:"contact"`
+	Groups []hierarchyGroup `xml:"group"`
+}
+
+// node converts the group and its descendants into a HierarchyNode.
+func (g hierarchyGroup) node() HierarchyNode {
+	n := HierarchyNode{UID: g.UID, Name: g.Name}
+	for _, c := range g.Contacts {
+		n.Children = append(n.Children, HierarchyNode{UID: c.UID, Name: c.Name})
+	}
+	for _, sub := range g.Groups {
+		n.Children = append(n.Children, sub.node())
+	}
+	return n
+}
+
+// Tree validates the raw hierarchy against the tak-details-hierarchy schema
+// and returns the root group as a tree of HierarchyNode values.
+func (h *Hierarchy) Tree() (HierarchyNode, error) {
+	if h == nil || len(h.Raw) == 0 {
+		return HierarchyNode{}, fmt.Errorf("empty hierarchy")
+	}
+	if err := validator.ValidateAgainstSchema("tak-details-hierarchy", h.Raw); err != nil {
+		return HierarchyNode{}, fmt.Errorf("invalid hierarchy: %w", err)
+	}
+	var helper struct {
+		XMLName xml.Name         `xml:"hierarchy"`
+		Groups  []hierarchyGroup `xml:"group"`
+	}
+	if err := xml.Unmarshal(h.Raw, &helper); err != nil {
+		return HierarchyNode{}, fmt.Errorf("parse hierarchy: %w", err)
+	}
+	if len(helper.Groups) == 0 {
+		return HierarchyNode{}, fmt.Errorf("hierarchy has no root group")
+	}
+	return helper.Groups[0].node(), nil
 }
 
-// Bullseye represents the TAK bullseye extension.
-type Bullseye struct {
+// DetailLink represents the TAK link detail extension.
+type DetailLink struct {
 	Raw RawMessage
 }
 
@@ -265,17 +492,125 @@ type LinkAttr struct {
 	Raw            RawMessage          `xml:"-"`
 }
 
-// MartiDest represents a destination callsign within a Marti extension.
+// MartiDest represents a routing destination within a Marti extension. A
+// destination names a contact by callsign or uid, a group, or a mission;
+// After optionally limits mission routing to changes after the given
+// mission change. Raw preserves a decoded <dest> element and is emitted
+// while the fields still match it, so the source attribute order and
+// attributes without a field survive a round trip.
 type MartiDest struct {
-	Callsign string `xml:"callsign,attr,omitempty"`
+	Callsign string     `xml:"callsign,attr,omitempty"`
+	UID      string     `xml:"uid,attr,omitempty"`
+	Group    string     `xml:"group,attr,omitempty"`
+	Mission  string     `xml:"mission,attr,omitempty"`
+	After    string     `xml:"after,attr,omitempty"`
+	Raw      RawMessage `xml:"-"`
+}
+
+// UnmarshalXML captures the raw element and decodes its attributes.
+func (d *MartiDest) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	raw, err := captureRaw(dec, start)
+	if err != nil {
+		return err
+	}
+	type alias MartiDest
+	var a alias
+	if err := xml.Unmarshal(raw, &a); err != nil {
+		return err
+	}
+	*d = MartiDest(a)
+	d.Raw = raw
+	return nil
 }
 
-// Marti represents the TAK marti extension containing destination callsigns.
+// rawCurrent reports whether Raw is set and decodes to the current fields,
+// so that it can be emitted in their place.
+func (d *MartiDest) rawCurrent() bool {
+	if len(d.Raw) == 0 {
+		return false
+	}
+	type alias MartiDest
+	var a alias
+	if err := xml.Unmarshal(d.Raw, &a); err != nil {
+		return false
+	}
+	return a.Callsign == d.Callsign && a.UID == d.UID && a.Group == d.Group &&
+		a.Mission == d.Mission && a.After == d.After
+}
+
+// MarshalXML writes Raw while it matches the fields and the fields otherwise.
+func (d MartiDest) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if d.rawCurrent() {
+		return encodeRaw(enc, d.Raw)
+	}
+	start.Name = xml.Name{Local: "dest"}
+	type alias MartiDest
+	return enc.EncodeElement(alias(d), start)
+}
+
+// Marti represents the TAK marti extension listing the destinations a
+// TAK server routes the event to.
 type Marti struct {
 	XMLName xml.Name    `xml:"marti"`
 	Dest    []MartiDest `xml:"dest"`
 }
 
+// validateDests checks that every destination names a recipient.
+func (m *Marti) validateDests() error {
+	for i, d := range m.Dest {
+		if d.Callsign == "" && d.UID == "" && d.Group == "" && d.Mission == "" {
+			return fmt.Errorf("dest %d has no callsign, uid, group or mission", i)
+		}
+		if d.After != "" && d.Mission == "" {
+			return fmt.Errorf("dest %d has after without mission", i)
+		}
+	}
+	return nil
+}
+
+// allChatRooms is the chatroom of GeoChat messages broadcast to everyone.
+const allChatRooms = "All Chat Rooms"
+
+// checkRouting reports an error wrapping validator.ErrInvalidChat if m
+// routes the chat to a uid or callsign that is not one of its
+// participants. Participants are the chatgrp uids, the uids and names of
+// the contacts in the chat hierarchy, and, for direct messages, the
+// chatroom (the recipient's callsign). Destinations addressed only by
+// group or mission, and chats to All Chat Rooms, are not checked.
+func (c *Chat) checkRouting(m *Marti) error {
+	if c == nil || m == nil || c.Chatroom == allChatRooms || c.ID == allChatRooms {
+		return nil
+	}
+	uids := make(map[string]bool)
+	callsigns := map[string]bool{c.Chatroom: true, c.SenderCallsign: true}
+	for _, g := range c.ChatGrps {
+		uids[g.UID0], uids[g.UID1], uids[g.UID2] = true, true, true
+	}
+	if c.Hierarchy != nil {
+		if root, err := c.Hierarchy.Tree(); err == nil {
+			var walk func(n HierarchyNode)
+			walk = func(n HierarchyNode) {
+				uids[n.UID], callsigns[n.Name] = true, true
+				for _, child := range n.Children {
+					walk(child)
+				}
+			}
+			walk(root)
+		}
+	}
+	delete(uids, "")
+	delete(callsigns, "")
+	for i, d := range m.Dest {
+		switch {
+		case d.UID != "" && !uids[d.UID]:
+			return fmt.Errorf("marti dest %d uid %q is not a chat participant: %w", i, d.UID, validator.ErrInvalidChat)
+		case d.UID == "" && d.Callsign != "" && !callsigns[d.Callsign]:
+			return fmt.Errorf("marti dest %d callsign %q is not a chat participant: %w", i, d.Callsign, validator.ErrInvalidChat)
+		}
+	}
+	return nil
+}
+
 // Remarks represents the TAK remarks extension.
 // Remarks represents the TAK remarks extension.
 // It preserves the original XML while also allowing
@@ -287,10 +622,19 @@ type Remarks struct {
 	SourceID string     `xml:"sourceID,attr,omitempty"`
 	To       string     `xml:"to,attr,omitempty"`
 	Time     CoTTime    `xml:"time,attr,omitempty"`
+	Keywords string     `xml:"keywords,attr,omitempty"` // Comma-separated tags
+	Color    string     `xml:"color,attr,omitempty"`    // Display color, typically signed ARGB
 	Text     string     `xml:",chardata"`
 	Raw      RawMessage `xml:"-"`
 }
 
+// rawOnly reports whether r carries only Raw, with no parsed fields set, so
+// that Raw should be emitted as-is.
+func (r *Remarks) rawOnly() bool {
+	return len(r.Raw) > 0 && r.Source == "" && r.SourceID == "" && r.To == "" &&
+		r.Time.Time().IsZero() && r.Keywords == "" && r.Color == "" && r.Text == ""
+}
+
 // Parse fills the Remarks fields from Raw if present.
 // It is safe to call multiple times.
 func (r *Remarks) Parse() error {
@@ -303,6 +647,8 @@ func (r *Remarks) Parse() error {
 		SourceID string   `xml:"sourceID,attr,omitempty"`
 		To       string   `xml:"to,attr,omitempty"`
 		Time     CoTTime  `xml:"time,attr,omitempty"`
+		Keywords string   `xml:"keywords,attr,omitempty"`
+		Color    string   `xml:"color,attr,omitempty"`
 		Text     string   `xml:",chardata"`
 	}
 	if err := xml.Unmarshal(r.Raw, &helper); err != nil {
@@ -313,10 +659,25 @@ func (r *Remarks) Parse() error {
 	r.SourceID = helper.SourceID
 	r.To = helper.To
 	r.Time = helper.Time
+	r.Keywords = helper.Keywords
+	r.Color = helper.Color
 	r.Text = helper.Text
 	return nil
 }
 
+// trimRawWhitespace makes captureRaw drop whitespace between elements.
+var trimRawWhitespace atomic.Bool
+
+// SetTrimRawWhitespace controls whether detail elements captured as raw
+// XML keep the indentation of pretty-printed input. When enabled,
+// whitespace-only text between elements is dropped, so a captured element
+// is the same whether it arrived indented or compact. Text content, such
+// as the body of <remarks>, is kept, including an element whose only
+// content is whitespace. By default raw XML is captured verbatim.
+func SetTrimRawWhitespace(enabled bool) {
+	trimRawWhitespace.Store(enabled)
+}
+
 // captureRaw reads an element starting from start and returns its raw XML
 // representation.
 func captureRaw(dec *xml.Decoder, start xml.StartElement) (RawMessage, error) {
@@ -325,12 +686,42 @@ func captureRaw(dec *xml.Decoder, start xml.StartElement) (RawMessage, error) {
 	if err := enc.EncodeToken(start); err != nil {
 		return nil, err
 	}
+	trim := trimRawWhitespace.Load()
+	// With trimming, whitespace-only text is held in pending until the
+	// next token shows whether it sits between elements. hasChild records,
+	// per open element, whether it has child elements.
+	var pending xml.CharData
+	hasChild := []bool{false}
 	depth := 1
 	for depth > 0 {
 		tok, err := dec.Token()
 		if err != nil {
 			return nil, err
 		}
+		if trim {
+			switch t := tok.(type) {
+			case xml.CharData:
+				if len(bytes.TrimSpace(t)) == 0 {
+					pending = append(pending, t...)
+					continue
+				}
+			case xml.StartElement:
+				pending = pending[:0]
+				hasChild[len(hasChild)-1] = true
+				hasChild = append(hasChild, false)
+			case xml.EndElement:
+				if hasChild[len(hasChild)-1] {
+					pending = pending[:0]
+				}
+				hasChild = hasChild[:len(hasChild)-1]
+			}
+			if len(pending) > 0 {
+				if err := enc.EncodeToken(pending); err != nil {
+					return nil, err
+				}
+				pending = pending[:0]
+			}
+		}
 		switch tok.(type) {
 		case xml.StartElement:
 			depth++
@@ -357,10 +748,12 @@ func (c *Chat) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 		if err2 := validator.ValidateAgainstSchema("tak-details-__chat", raw); err2 != nil {
 			return errors.Join(err, err2)
 		}
+		c.SchemaVariant = ChatVariantTAK
 	} else {
 		if err := validator.ValidateChat(raw); err != nil {
 			return err
 		}
+		c.SchemaVariant = ChatVariantCoT
 	}
 
 	var helper struct {
@@ -470,7 +863,11 @@ func (g *Geofence) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error
 	if err != nil {
 		return err
 	}
-	g.Raw = raw
+	monitor, trigger, err := parseGeofence(raw)
+	if err != nil {
+		return err
+	}
+	g.Raw, g.monitor, g.trigger = raw, monitor, trigger
 	return nil
 }
 
@@ -686,17 +1083,37 @@ func (fc FillColor) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
 	return encodeRaw(enc, fc.Raw)
 }
 
+// UnmarshalXML captures the raw element and decodes the value attribute.
+// A value that is not a boolean leaves Value false; schema validation
+// reports it.
 func (lo *LabelsOn) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 	raw, err := captureRaw(dec, start)
 	if err != nil {
 		return err
 	}
 	lo.Raw = raw
+	lo.Value = false
+	for _, a := range start.Attr {
+		if a.Name.Space == "" && a.Name.Local == "value" {
+			lo.Value, _ = strconv.ParseBool(strings.TrimSpace(a.Value))
+		}
+	}
 	return nil
 }
 
+// MarshalXML writes Raw when present and the value attribute otherwise.
 func (lo LabelsOn) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
-	return encodeRaw(enc, lo.Raw)
+	if len(lo.Raw) > 0 {
+		return encodeRaw(enc, lo.Raw)
+	}
+	start = xml.StartElement{
+		Name: xml.Name{Local: "labelson"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "value"}, Value: strconv.FormatBool(lo.Value)}},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
 }
 
 func (c *ColorExtension) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
@@ -803,19 +1220,6 @@ func (dl DetailLink) MarshalXML(enc *xml.Encoder, start xml.StartElement) error
 	return encodeRaw(enc, dl.Raw)
 }
 
-func (b *Bullseye) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
-	raw, err := captureRaw(dec, start)
-	if err != nil {
-		return err
-	}
-	b.Raw = raw
-	return nil
-}
-
-func (b Bullseye) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
-	return encodeRaw(enc, b.Raw)
-}
-
 func (ri *RouteInfo) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 	raw, err := captureRaw(dec, start)
 	if err != nil {
@@ -842,17 +1246,19 @@ func (r *Remarks) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
 }
 
 func (r Remarks) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
-	if len(r.Raw) > 0 && r.Source == "" && r.SourceID == "" &&
-		r.To == "" && r.Time.Time().IsZero() && r.Text == "" {
+	if r.rawOnly() {
 		return encodeRaw(enc, r.Raw)
 	}
+	// encoding/xml names the start element after the Go type for
+	// marshalers, so set the CoT element name explicitly.
+	start.Name = xml.Name{Local: "remarks"}
 	type alias Remarks
 	return enc.EncodeElement(alias(r), start)
 }
 
 // encodeRaw writes pre-encoded XML directly to the encoder.
 func encodeRaw(enc *xml.Encoder, raw RawMessage) error {
-	dec := xml.NewDecoder(bytes.NewReader(raw))
+	dec := cottypes.NewSecureDecoder(bytes.NewReader(raw))
 	for {
 		tok, err := dec.Token()
 		if err == io.EOF {
@@ -0,0 +1,37 @@
+package cotlib
+
+import "sync"
+
+// DetailHook inspects or rewrites an unrecognised detail element during
+// decoding. It receives the raw element and returns the element to keep in
+// Detail.Unknown, or nil to drop it. Returning an error fails the decode.
+type DetailHook func(raw RawMessage) (RawMessage, error)
+
+var (
+	detailHooksMu sync.RWMutex
+	detailHooks   = map[string]DetailHook{}
+)
+
+// RegisterDetailHook registers fn to be called by Detail.UnmarshalXML for
+// unrecognised detail elements with the given local name, before they are
+// captured in Detail.Unknown. Elements handled by a hook are not subject to
+// SetRejectUnknownDetails. Registering a nil fn removes the hook.
+//
+// Hooks apply only to elements that are not modelled by Detail; the
+// returned XML is stored in Detail.Unknown as is.
+func RegisterDetailHook(element string, fn func(raw RawMessage) (RawMessage, error)) {
+	detailHooksMu.Lock()
+	defer detailHooksMu.Unlock()
+	if fn == nil {
+		delete(detailHooks, element)
+		return
+	}
+	detailHooks[element] = fn
+}
+
+// detailHook returns the hook registered for element, if any.
+func detailHook(element string) DetailHook {
+	detailHooksMu.RLock()
+	defer detailHooksMu.RUnlock()
+	return detailHooks[element]
+}
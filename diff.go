@@ -0,0 +1,308 @@
+package cotlib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/NERVsystems/cotlib/cottypes"
+)
+
+// diffEpsilon is the tolerance used when comparing point fields. It is
+// about a centimetre of latitude and well below any meaningful CE/LE.
+const diffEpsilon = 1e-7
+
+// FieldChange describes a single difference reported by Event.Diff.
+//
+// Path names the changed field: an event attribute such as "type" or
+// "time", a point field such as "point/lat", a detail extension such as
+// "detail/track", or "link" for event-level links. Old and New hold the
+// values from the receiver and the other event; an empty value means the
+// field was absent on that side.
+type FieldChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+// Diff reports the fields that differ between e and other. Event attributes
+// are compared as strings, point fields within a small epsilon, detail
+// extensions by their canonical XML encoding and links as an unordered set,
+// with one change per added or removed link. A nil event is treated as an
+// empty one. The result is nil when the events are equivalent.
+func (e *Event) Diff(other *Event) []FieldChange {
+	a, b := e, other
+	if a == nil {
+		a = &Event{}
+	}
+	if b == nil {
+		b = &Event{}
+	}
+
+	var changes []FieldChange
+	add := func(path, from, to string) {
+		if from != to {
+			changes = append(changes, FieldChange{Path: path, Old: from, New: to})
+		}
+	}
+	ts := func(t CoTTime) string {
+		if t.Time().IsZero() {
+			return ""
+		}
+		return t.Time().UTC().Format(CotTimeFormat)
+	}
+
+	add("version", a.Version, b.Version)
+	add("uid", a.Uid, b.Uid)
+	add("type", a.Type, b.Type)
+	add("how", a.How, b.How)
+	add("time", ts(a.Time), ts(b.Time))
+	add("start", ts(a.Start), ts(b.Start))
+	add("stale", ts(a.Stale), ts(b.Stale))
+	add("strokeColor", a.StrokeColor, b.StrokeColor)
+	add("usericon", a.UserIcon, b.UserIcon)
+	add("caveat", a.Caveat, b.Caveat)
+	add("releasableTo", a.ReleasableTo, b.ReleasableTo)
+	diffSorted(&changes, "", attrMap(a.UnknownAttrs), attrMap(b.UnknownAttrs))
+
+	for _, f := range []struct {
+		name string
+		a, b float64
+	}{
+		{"lat", a.Point.Lat, b.Point.Lat},
+		{"lon", a.Point.Lon, b.Point.Lon},
+		{"hae", a.Point.Hae, b.Point.Hae},
+		{"ce", a.Point.Ce, b.Point.Ce},
+		{"le", a.Point.Le, b.Point.Le},
+	} {
+		if math.Abs(f.a-f.b) > diffEpsilon {
+			add("point/"+f.name, strconv.FormatFloat(f.a, 'f', -1, 64), strconv.FormatFloat(f.b, 'f', -1, 64))
+		}
+	}
+
+	diffSorted(&changes, "detail/", detailParts(a.Detail), detailParts(b.Detail))
+
+	oldLinks, newLinks := linkSet(a.Links), linkSet(b.Links)
+	var keys []string
+	for k := range oldLinks {
+		if !newLinks[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		changes = append(changes, FieldChange{Path: "link", Old: k})
+	}
+	keys = keys[:0]
+	for k := range newLinks {
+		if !oldLinks[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		changes = append(changes, FieldChange{Path: "link", New: k})
+	}
+
+	return changes
+}
+
+// diffSorted appends a change for every key whose value differs between
+// from and to, in key order, prefixing each path with prefix.
+func diffSorted(changes *[]FieldChange, prefix string, from, to map[string]string) {
+	keys := make([]string, 0, len(from)+len(to))
+	for k := range from {
+		keys = append(keys, k)
+	}
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if from[k] != to[k] {
+			*changes = append(*changes, FieldChange{Path: prefix + k, Old: from[k], New: to[k]})
+		}
+	}
+}
+
+// attrMap keys unknown event attributes by their (prefixed) name.
+func attrMap(attrs []xml.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		name := a.Name.Local
+		if a.Name.Space != "" {
+			name = a.Name.Space + ":" + name
+		}
+		m[name] = a.Value
+	}
+	return m
+}
+
+// linkSet returns the event-level links as "uid,type,relation" keys,
+// followed by ",children" for links with child elements.
+func linkSet(links []Link) map[string]bool {
+	m := make(map[string]bool, len(links))
+	for _, l := range links {
+		key := l.Uid + "," + l.Type + "," + l.Relation
+		if l.Children != "" {
+			key += "," + l.Children
+		}
+		m[key] = true
+	}
+	return m
+}
+
+// detailParts encodes d and returns the canonical XML of each child
+// element keyed by element name. Repeated elements such as route links are
+// concatenated in document order.
+func detailParts(d *Detail) map[string]string {
+	parts := make(map[string]string)
+	if d == nil {
+		return parts
+	}
+	data, err := xml.Marshal(d)
+	if err != nil {
+		parts[""] = fmt.Sprintf("unencodable detail: %v", err)
+		return parts
+	}
+	dec := cottypes.NewSecureDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 1 {
+				raw, err := captureRaw(dec, t)
+				if err != nil {
+					return parts
+				}
+				parts[t.Name.Local] += string(raw)
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return parts
+}
+
+// RoundTripStable reports whether the event survives a ToXML, decode,
+// ToXML cycle byte for byte. Raw details are re-encoded when decoded, which
+// for example expands self-closing elements and may reorder attributes, so
+// an event built in code can serialize differently after one trip. changed
+// lists, in document order, the names of the detail elements whose
+// serialization differed; it may be empty when only the event attributes,
+// point or links changed. Validation is not applied to the decoded copy.
+// It returns false and nil if the event cannot be serialized or its output
+// cannot be decoded.
+func (e *Event) RoundTripStable() (stable bool, changed []string) {
+	first, err := e.ToXML()
+	if err != nil {
+		return false, nil
+	}
+	pd := getDecoder(first)
+	again := getEvent()
+	err = decodeWithLimits(pd.dec, again)
+	putDecoder(pd)
+	if err != nil {
+		ReleaseEvent(again)
+		return false, nil
+	}
+	second, err := again.ToXML()
+	ReleaseEvent(again)
+	if err != nil {
+		return false, nil
+	}
+	if bytes.Equal(first, second) {
+		return true, nil
+	}
+
+	before, after := detailSegments(first), detailSegments(second)
+	seen := make(map[string]bool)
+	for _, segs := range [][]detailSegment{before, after} {
+		for _, s := range segs {
+			if seen[s.name] {
+				continue
+			}
+			seen[s.name] = true
+			if !equalSegments(s.name, before, after) {
+				changed = append(changed, s.name)
+			}
+		}
+	}
+	return false, changed
+}
+
+// detailSegment is the serialized form of one child of <detail>.
+type detailSegment struct {
+	name string
+	data []byte
+}
+
+// detailSegments splits the <detail> children out of a serialized event.
+func detailSegments(data []byte) []detailSegment {
+	dec := cottypes.NewSecureDecoder(bytes.NewReader(data))
+	var segs []detailSegment
+	depth, start := 0, int64(0)
+	inDetail := false
+	name := ""
+	for {
+		off := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return segs
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case depth == 1 && t.Name.Local == "detail":
+				inDetail = true
+			case depth == 2 && inDetail:
+				start, name = off, t.Name.Local
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			switch {
+			case depth == 2 && inDetail:
+				segs = append(segs, detailSegment{name: name, data: data[start:dec.InputOffset()]})
+			case depth == 1:
+				inDetail = false
+			}
+		}
+	}
+}
+
+// equalSegments reports whether the elements called name appear the same
+// number of times with the same bytes in a and b.
+func equalSegments(name string, a, b []detailSegment) bool {
+	var x, y [][]byte
+	for _, s := range a {
+		if s.name == name {
+			x = append(x, s.data)
+		}
+	}
+	for _, s := range b {
+		if s.name == name {
+			y = append(y, s.data)
+		}
+	}
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !bytes.Equal(x[i], y[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package cotlib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEventDiff(t *testing.T) {
+	base, err := NewEvent("D1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(base)
+	base.Detail = &Detail{
+		Contact: &Contact{Callsign: "ALPHA"},
+		Track:   &Track{Raw: RawMessage(`<track course="90" speed="10"/>`)},
+	}
+	base.Links = []Link{{Uid: "P1", Type: "a-f-G", Relation: "p-p"}}
+
+	clone := func() *Event {
+		c := *base
+		d := *base.Detail
+		c.Detail = &d
+		c.Links = append([]Link(nil), base.Links...)
+		return &c
+	}
+
+	if got := base.Diff(clone()); got != nil {
+		t.Errorf("identical events: got %+v", got)
+	}
+
+	t.Run("position_only", func(t *testing.T) {
+		moved := clone()
+		moved.Point.Lat = 10.5
+		moved.Point.Lon += 1e-9 // below epsilon
+		want := []FieldChange{{Path: "point/lat", Old: "10", New: "10.5"}}
+		if got := base.Diff(moved); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("detail_only", func(t *testing.T) {
+		changed := clone()
+		changed.Detail.Track = &Track{Raw: RawMessage(`<track course="180" speed="10"/>`)}
+		got := base.Diff(changed)
+		if len(got) != 1 || got[0].Path != "detail/track" {
+			t.Fatalf("got %+v, want a single detail/track change", got)
+		}
+		if got[0].Old != `<track course="90" speed="10"></track>` || got[0].New != `<track course="180" speed="10"></track>` {
+			t.Errorf("unexpected track values: %+v", got[0])
+		}
+	})
+
+	t.Run("links_as_set", func(t *testing.T) {
+		changed := clone()
+		changed.Links = []Link{{Uid: "P2", Type: "a-f-G", Relation: "p-p"}, base.Links[0]}
+		want := []FieldChange{{Path: "link", New: "P2,a-f-G,p-p"}}
+		if got := base.Diff(changed); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("link_children", func(t *testing.T) {
+		changed := clone()
+		changed.Links[0].Children = "<Style/>"
+		want := []FieldChange{
+			{Path: "link", Old: "P1,a-f-G,p-p"},
+			{Path: "link", New: "P1,a-f-G,p-p,<Style/>"},
+		}
+		if got := base.Diff(changed); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestRoundTripStable(t *testing.T) {
+	evt, err := NewEvent("RT-1", "a-f-G", 30, -85, 0)
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Detail = &Detail{
+		Contact: &Contact{Callsign: "ALPHA"},
+		Remarks: &Remarks{Text: "steady"},
+	}
+	if stable, changed := evt.RoundTripStable(); !stable || changed != nil {
+		t.Errorf("RoundTripStable = %v, %v, want stable", stable, changed)
+	}
+
+	evt.Detail.Unknown = []RawMessage{RawMessage(`<__forcedelete/>`)}
+	stable, changed := evt.RoundTripStable()
+	if stable {
+		t.Fatal("self-closing raw detail reported stable")
+	}
+	if len(changed) != 1 || changed[0] != "__forcedelete" {
+		t.Errorf("changed = %v, want [__forcedelete]", changed)
+	}
+}
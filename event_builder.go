@@ -6,6 +6,9 @@ import "time"
 type EventBuilder struct {
 	evt *Event
 	err error
+	// typeErr holds the result of the last WithValidatedType call. It is
+	// kept apart from err so a later call with a corrected type clears it.
+	typeErr error
 }
 
 // NewEventBuilder creates a new EventBuilder with the basic event fields set.
@@ -19,7 +22,7 @@ func NewEventBuilder(uid, typ string, lat, lon, hae float64) *EventBuilder {
 		How:     "m-g",
 		Time:    CoTTime(now),
 		Start:   CoTTime(now),
-		Stale:   CoTTime(now.Add(6 * time.Second)),
+		Stale:   CoTTime(now.Add(defaultStaleOffset)),
 		Point: Point{
 			Lat: lat,
 			Lon: lon,
@@ -31,6 +34,28 @@ func NewEventBuilder(uid, typ string, lat, lon, hae float64) *EventBuilder {
 	return &EventBuilder{evt: e}
 }
 
+// WithValidatedType sets the event type and validates it immediately with
+// ValidateType. A validation error is reported by Err and returned by
+// Build. Each call replaces the result of the previous one, so a form can
+// call it on every edit of a type field and check Err as the user types.
+func (b *EventBuilder) WithValidatedType(typ string) *EventBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.evt.Type = typ
+	b.typeErr = ValidateType(typ)
+	return b
+}
+
+// Err returns the first error recorded by the builder, including a type
+// rejected by WithValidatedType, or nil if Build may still succeed.
+func (b *EventBuilder) Err() error {
+	if b.err != nil {
+		return b.err
+	}
+	return b.typeErr
+}
+
 // WithContact sets the contact detail on the event.
 func (b *EventBuilder) WithContact(c *Contact) *EventBuilder {
 	if b.err != nil {
@@ -170,9 +195,9 @@ func (b *EventBuilder) WithLinkAttr(la *LinkAttr) *EventBuilder {
 
 // Build validates and returns the constructed Event.
 func (b *EventBuilder) Build() (*Event, error) {
-	if b.err != nil {
+	if err := b.Err(); err != nil {
 		ReleaseEvent(b.evt)
-		return nil, b.err
+		return nil, err
 	}
 	if err := b.evt.ValidateAt(time.Now().UTC()); err != nil {
 		ReleaseEvent(b.evt)
@@ -9,16 +9,23 @@ var eventPool = sync.Pool{
 	New: func() any { return new(Event) },
 }
 
-func getEvent() *Event { return eventPool.Get().(*Event) }
+func getEvent() *Event {
+	e := eventPool.Get().(*Event)
+	e.released = false
+	return e
+}
 
 // ReleaseEvent returns an Event to the internal pool after resetting all fields.
 //
 // The provided pointer should no longer be used after calling this function.
+// Releasing a nil Event is a no-op, and releasing the same Event more than
+// once before it is reacquired is ignored so the pool never hands out the
+// same Event twice.
 func ReleaseEvent(e *Event) {
-	if e == nil {
+	if e == nil || e.released {
 		return
 	}
-	*e = Event{}
+	*e = Event{released: true}
 	eventPool.Put(e)
 }
 
@@ -3,9 +3,12 @@ package cotlib
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -111,3 +114,117 @@ func TestNewEventPoolReuseOnValidationError(t *testing.T) {
 		t.Error("event was not returned to pool after validation failure")
 	}
 }
+
+func TestReleaseEventNilAndDoubleRelease(t *testing.T) {
+	pct := debug.SetGCPercent(-1)
+	defer debug.SetGCPercent(pct)
+
+	ReleaseEvent(nil)
+
+	e := getEvent()
+	ReleaseEvent(e)
+	ReleaseEvent(e)
+
+	a := getEvent()
+	b := getEvent()
+	if a == b {
+		t.Fatal("pool handed out the same event twice after double release")
+	}
+
+	a.Uid = "a"
+	b.Uid = "b"
+	if a.Uid != "a" || b.Uid != "b" {
+		t.Fatalf("event data corrupted: a=%q b=%q", a.Uid, b.Uid)
+	}
+	ReleaseEvent(a)
+	ReleaseEvent(b)
+
+	// Double-release a batch of events, then acquire concurrently and make
+	// sure every goroutine received its own event.
+	for i := 0; i < 16; i++ {
+		evt := getEvent()
+		ReleaseEvent(evt)
+		ReleaseEvent(evt)
+	}
+
+	const workers = 32
+	events := make([]*Event, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			evt := getEvent()
+			evt.Uid = fmt.Sprintf("worker-%d", id)
+			events[id] = evt
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[*Event]bool, workers)
+	for i, evt := range events {
+		if seen[evt] {
+			t.Fatalf("event handed out to more than one worker")
+		}
+		seen[evt] = true
+		if want := fmt.Sprintf("worker-%d", i); evt.Uid != want {
+			t.Errorf("event data corrupted: got %q want %q", evt.Uid, want)
+		}
+	}
+	for _, evt := range events {
+		ReleaseEvent(evt)
+	}
+}
+
+func TestParseEventReleases(t *testing.T) {
+	evt, err := NewEvent("PE1", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	data, err := evt.ToXML()
+	ReleaseEvent(evt)
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	useErr := errors.New("use failed")
+
+	var seen *Event
+	err = ParseEvent(context.Background(), data, func(e *Event) error {
+		if e.Uid != "PE1" {
+			t.Errorf("uid = %q", e.Uid)
+		}
+		seen = e
+		return useErr
+	})
+	if !errors.Is(err, useErr) {
+		t.Fatalf("ParseEvent() = %v, want use error", err)
+	}
+	if seen == nil || !seen.released {
+		t.Error("event not released after use returned an error")
+	}
+
+	seen = nil
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want boom", r)
+			}
+		}()
+		_ = ParseEvent(context.Background(), data, func(e *Event) error {
+			seen = e
+			panic("boom")
+		})
+	}()
+	if seen == nil || !seen.released {
+		t.Error("event not released after use panicked")
+	}
+
+	called := false
+	err = ParseEvent(context.Background(), []byte("<event><bad></event>"), func(*Event) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("invalid input: err = %v, called = %v", err, called)
+	}
+}
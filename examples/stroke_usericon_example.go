@@ -21,7 +21,7 @@ func main() {
 		logger.Error("new event", "err", err)
 		return
 	}
-	evt.StrokeColor = "#ff0000ff"
+	evt.StrokeColor = "ff0000ff"
 	evt.UserIcon = "http://example.com/icon.png"
 
 	xmlData, err := evt.ToXML()
@@ -0,0 +1,98 @@
+package cotlib
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	"github.com/NERVsystems/cotlib/cottypes"
+)
+
+// expectedDetails lists the detail elements TAK clients conventionally send
+// with each type family, keyed by type prefix. The longest matching prefix
+// wins. Element names are those used by Detail when encoding.
+var expectedDetails = map[string][]string{
+	"a-":        {"contact"},
+	"a-f-G-U-C": {"contact", "__group", "status", "takv", "track"},
+	"b-t-f":     {"__chat", "link", "remarks", "__serverdestination", "marti"},
+	"b-e-r":     {"emergency", "contact", "link"},
+	"b-e-a":     {"emergency", "contact", "link"},
+	"b-e-s":     {"emergency", "contact", "link"},
+	"b-t-b":     {"fileshare"},
+	"b-t-i":     {"fileshare"},
+	"b-t-v":     {"fileshare"},
+	"b-t-a":     {"fileshare"},
+	"b-t-d":     {"fileshare"},
+	"b-r-f-h-c": {"_medevac_", "contact", "link"},
+	"b-m-r":     {"link", "routeInfo", "contact"},
+	"b-m-p-s":   {"contact", "color", "usericon"},
+	"u-d-c":     {"shape", "strokecolor", "strokeweight", "fillcolor", "labelson", "contact"},
+	"u-d-k":     {"shape", "strokecolor", "strokeweight", "fillcolor", "labelson", "contact"},
+	"u-d-r":     {"link", "strokecolor", "strokeweight", "fillcolor", "labelson", "contact"},
+	"u-d-p":     {"link", "strokecolor", "strokeweight", "fillcolor", "labelson", "contact"},
+	"u-d-f":     {"link", "strokecolor", "strokeweight", "labelson", "contact"},
+	"u-d-l":     {"link", "strokecolor", "strokeweight", "labelson", "contact"},
+}
+
+// ExpectedDetails returns the detail element names TAK clients
+// conventionally attach to events of type typ, such as __chat, remarks and
+// marti for GeoChat (b-t-f) or shape and the style details for drawn
+// circles (u-d-c-c). The list is matched on the longest known type prefix
+// and is advisory only: the details are not required by the CoT schema and
+// producers routinely omit some of them. It returns nil for types without
+// a known convention. The returned slice is a copy.
+func ExpectedDetails(typ string) []string {
+	best := ""
+	for prefix := range expectedDetails {
+		if len(prefix) > len(best) && (typ == prefix || (strings.HasPrefix(typ, prefix) &&
+			(strings.HasSuffix(prefix, "-") || typ[len(prefix)] == '-'))) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return nil
+	}
+	return append([]string(nil), expectedDetails[best]...)
+}
+
+// MissingDetails returns the names from ExpectedDetails for the event's
+// type that are not present in its detail, in the same order. Like
+// ExpectedDetails it is advisory; validation does not consult it.
+func (e *Event) MissingDetails() []string {
+	if e == nil {
+		return nil
+	}
+	want := ExpectedDetails(e.Type)
+	if len(want) == 0 {
+		return nil
+	}
+	have := make(map[string]bool)
+	if e.Detail != nil {
+		if data, err := xml.Marshal(e.Detail); err == nil {
+			dec := cottypes.NewSecureDecoder(bytes.NewReader(data))
+			depth := 0
+			for {
+				tok, err := dec.Token()
+				if err != nil {
+					break
+				}
+				switch t := tok.(type) {
+				case xml.StartElement:
+					if depth == 1 {
+						have[t.Name.Local] = true
+					}
+					depth++
+				case xml.EndElement:
+					depth--
+				}
+			}
+		}
+	}
+	var missing []string
+	for _, name := range want {
+		if !have[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
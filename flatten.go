@@ -0,0 +1,111 @@
+package cotlib
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// flattenMaxDepth bounds how many levels of links FlattenLinked follows,
+// keeping the nested detail well inside the decoder's element depth limit.
+const flattenMaxDepth = 8
+
+// FlattenLinked returns a copy of root with the events it links to
+// embedded in a <linkedEvents> detail, producing a self-contained document
+// for offline mission packages. Each link uid is resolved with lookup and
+// written as a compact <linkedEvent> element carrying the relation, uid,
+// type, how, times and point of the linked event. Links of linked events
+// are followed recursively, nested inside their parent, up to eight levels.
+//
+// Each event is embedded at most once: links back to root or to an event
+// already embedded, including self links, are skipped, so cycles terminate.
+// A lookup that returns a nil event skips the link; a lookup error aborts
+// flattening and is returned. Any existing <linkedEvents> detail on root is
+// replaced. The returned Event is obtained from the internal pool and may
+// be released with ReleaseEvent; root and the looked-up events are not
+// modified.
+func FlattenLinked(root *Event, lookup func(uid string) (*Event, error)) (*Event, error) {
+	if root == nil {
+		return nil, fmt.Errorf("nil event")
+	}
+	if lookup == nil {
+		return nil, fmt.Errorf("nil lookup: %w", ErrInvalidInput)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<linkedEvents>")
+	visited := map[string]bool{root.Uid: true}
+	if err := flattenLinks(&buf, root.Links, lookup, visited, 1); err != nil {
+		return nil, err
+	}
+	buf.WriteString("</linkedEvents>")
+
+	out := cloneEvent(root)
+	if out.Detail == nil {
+		out.Detail = &Detail{}
+	}
+	unknown := out.Detail.Unknown[:0]
+	for _, raw := range out.Detail.Unknown {
+		if rawElementName(raw) != "linkedEvents" {
+			unknown = append(unknown, raw)
+		}
+	}
+	out.Detail.Unknown = append(unknown, RawMessage(buf.Bytes()))
+	return out, nil
+}
+
+// flattenLinks writes a <linkedEvent> element for each link whose target
+// has not been visited, recursing into the target's own links.
+func flattenLinks(buf *bytes.Buffer, links []Link, lookup func(string) (*Event, error), visited map[string]bool, depth int) error {
+	for _, l := range links {
+		if visited[l.Uid] {
+			continue
+		}
+		child, err := lookup(l.Uid)
+		if err != nil {
+			return fmt.Errorf("lookup linked event %q: %w", l.Uid, err)
+		}
+		if child == nil {
+			continue
+		}
+		visited[l.Uid] = true
+
+		buf.WriteString(`<linkedEvent relation="`)
+		buf.WriteString(escapeAttr(l.Relation))
+		buf.WriteString(`" uid="`)
+		buf.WriteString(escapeAttr(child.Uid))
+		buf.WriteString(`" type="`)
+		buf.WriteString(escapeAttr(child.Type))
+		buf.WriteByte('"')
+		if child.How != "" {
+			buf.WriteString(` how="`)
+			buf.WriteString(escapeAttr(child.How))
+			buf.WriteByte('"')
+		}
+		for _, a := range [...]struct {
+			name string
+			t    CoTTime
+		}{{"time", child.Time}, {"stale", child.Stale}} {
+			if v := attrTime(a.t); v != "" {
+				buf.WriteString(` ` + a.name + `="`)
+				buf.WriteString(v)
+				buf.WriteByte('"')
+			}
+		}
+		if !child.NoPoint {
+			ff := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+			buf.WriteString(` lat="` + ff(child.Point.Lat) + `" lon="` + ff(child.Point.Lon) +
+				`" hae="` + ff(child.Point.Hae) + `" ce="` + ff(child.Point.Ce) + `" le="` + ff(child.Point.Le) + `"`)
+		}
+		if depth >= flattenMaxDepth || len(child.Links) == 0 {
+			buf.WriteString("/>")
+			continue
+		}
+		buf.WriteByte('>')
+		if err := flattenLinks(buf, child.Links, lookup, visited, depth+1); err != nil {
+			return err
+		}
+		buf.WriteString("</linkedEvent>")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package cotlib
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+func TestFlattenLinked(t *testing.T) {
+	newEvt := func(uid, typ string, lat, lon float64) *Event {
+		t.Helper()
+		e, err := NewEvent(uid, typ, lat, lon, 0)
+		if err != nil {
+			t.Fatalf("new event %s: %v", uid, err)
+		}
+		t.Cleanup(func() { ReleaseEvent(e) })
+		return e
+	}
+	parent := newEvt("PARENT", "a-f-G-U-C", 10, 20)
+	child1 := newEvt("CHILD-1", "a-f-G", 10.5, 20.5)
+	child2 := newEvt("CHILD-2", "a-f-G", 11, 21)
+	parent.AddLink(&Link{Uid: "PARENT", Type: "a-f-G-U-C", Relation: "p-p"})
+	parent.AddLink(&Link{Uid: "CHILD-1", Type: "a-f-G", Relation: "p-c"})
+	parent.AddLink(&Link{Uid: "CHILD-2", Type: "a-f-G", Relation: "p-c"})
+	child1.AddLink(&Link{Uid: "PARENT", Type: "a-f-G-U-C", Relation: "p-p"})
+	child1.AddLink(&Link{Uid: "CHILD-1", Type: "a-f-G", Relation: "p-p"})
+
+	events := map[string]*Event{"PARENT": parent, "CHILD-1": child1, "CHILD-2": child2}
+	lookups := 0
+	lookup := func(uid string) (*Event, error) {
+		lookups++
+		return events[uid], nil
+	}
+
+	flat, err := FlattenLinked(parent, lookup)
+	if err != nil {
+		t.Fatalf("FlattenLinked: %v", err)
+	}
+	defer ReleaseEvent(flat)
+	if lookups != 2 {
+		t.Errorf("lookup called %d times, want 2", lookups)
+	}
+	if parent.Detail != nil {
+		t.Error("FlattenLinked modified root")
+	}
+
+	// Round trip through XML and read the embedded events back.
+	out, err := flat.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	decoded, err := UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, out)
+	}
+	defer ReleaseEvent(decoded)
+	if len(decoded.Detail.Unknown) != 1 {
+		t.Fatalf("unknown details = %q", decoded.Detail.Unknown)
+	}
+	type linked struct {
+		UID      string   `xml:"uid,attr"`
+		Type     string   `xml:"type,attr"`
+		Relation string   `xml:"relation,attr"`
+		Lat      float64  `xml:"lat,attr"`
+		Children []linked `xml:"linkedEvent"`
+	}
+	var got struct {
+		Events []linked `xml:"linkedEvent"`
+	}
+	if err := xml.Unmarshal(decoded.Detail.Unknown[0], &got); err != nil {
+		t.Fatalf("parse linkedEvents: %v", err)
+	}
+	if len(got.Events) != 2 {
+		t.Fatalf("linked events = %+v, want CHILD-1 and CHILD-2", got.Events)
+	}
+	if e := got.Events[0]; e.UID != "CHILD-1" || e.Type != "a-f-G" || e.Relation != "p-c" || e.Lat != 10.5 || len(e.Children) != 0 {
+		t.Errorf("first linked event = %+v", e)
+	}
+	if e := got.Events[1]; e.UID != "CHILD-2" || e.Lat != 11 {
+		t.Errorf("second linked event = %+v", e)
+	}
+
+	// Flattening again replaces rather than duplicates the detail.
+	again, err := FlattenLinked(flat, lookup)
+	if err != nil {
+		t.Fatalf("FlattenLinked again: %v", err)
+	}
+	defer ReleaseEvent(again)
+	if len(again.Detail.Unknown) != 1 {
+		t.Errorf("unknown details after reflattening = %d, want 1", len(again.Detail.Unknown))
+	}
+
+	boom := errors.New("store offline")
+	if _, err := FlattenLinked(parent, func(string) (*Event, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Errorf("lookup error = %v, want %v", err, boom)
+	}
+}
@@ -0,0 +1,159 @@
+package cotlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// geoJSONFeature is a GeoJSON Feature with a Point geometry.
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   *geoJSONPoint     `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+// geoJSONPoint is a GeoJSON Point geometry.
+type geoJSONPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// geoJSONProperties holds the CoT fields carried in a Feature's properties.
+type geoJSONProperties struct {
+	UID      string   `json:"uid,omitempty"`
+	Type     string   `json:"type,omitempty"`
+	How      string   `json:"how,omitempty"`
+	Callsign string   `json:"callsign,omitempty"`
+	Time     string   `json:"time,omitempty"`
+	Start    string   `json:"start,omitempty"`
+	Stale    string   `json:"stale,omitempty"`
+	Ce       *float64 `json:"ce,omitempty"`
+	Le       *float64 `json:"le,omitempty"`
+}
+
+// ToGeoJSON encodes the event as a GeoJSON Feature with a Point geometry.
+// The coordinates are [lon, lat, hae] and the uid, type, how, contact
+// callsign, times, ce and le are stored in the Feature's properties.
+func (e *Event) ToGeoJSON() ([]byte, error) {
+	if e == nil {
+		return nil, fmt.Errorf("nil event")
+	}
+	ce, le := e.Point.Ce, e.Point.Le
+	f := geoJSONFeature{
+		Type: "Feature",
+		Geometry: &geoJSONPoint{
+			Type:        "Point",
+			Coordinates: []float64{e.Point.Lon, e.Point.Lat, e.Point.Hae},
+		},
+		Properties: geoJSONProperties{
+			UID:   e.Uid,
+			Type:  e.Type,
+			How:   e.How,
+			Time:  e.Time.Time().UTC().Format(CotTimeFormat),
+			Start: e.Start.Time().UTC().Format(CotTimeFormat),
+			Stale: e.Stale.Time().UTC().Format(CotTimeFormat),
+			Ce:    &ce,
+			Le:    &le,
+		},
+	}
+	if e.Detail != nil && e.Detail.Contact != nil {
+		f.Properties.Callsign = e.Detail.Contact.Callsign
+	}
+	return json.Marshal(f)
+}
+
+// FromGeoJSON builds and validates an Event from a GeoJSON Feature with a
+// Point geometry, reading the properties written by ToGeoJSON.
+//
+// The geometry and the uid property are required. Other properties fall
+// back to defaults: type "a-u-G", how "m-g", time the current time, start
+// the event time, stale the event time plus the default stale offset, and
+// ce/le 9999999 (unknown). Times use the CoT format (RFC 3339 in UTC).
+//
+// The returned Event is obtained from the internal pool and may be
+// released with ReleaseEvent.
+func FromGeoJSON(data []byte) (*Event, error) {
+	var f geoJSONFeature
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decode GeoJSON: %w", err)
+	}
+	if f.Type != "Feature" {
+		return nil, fmt.Errorf("GeoJSON type %q is not Feature: %w", f.Type, ErrInvalidInput)
+	}
+	if f.Geometry == nil || f.Geometry.Type != "Point" {
+		return nil, fmt.Errorf("GeoJSON feature has no Point geometry: %w", ErrInvalidInput)
+	}
+	coords := f.Geometry.Coordinates
+	if len(coords) < 2 || len(coords) > 3 {
+		return nil, fmt.Errorf("GeoJSON point has %d coordinates: %w", len(coords), ErrInvalidInput)
+	}
+	p := f.Properties
+	if p.UID == "" {
+		return nil, fmt.Errorf("GeoJSON feature missing uid property: %w", ErrInvalidInput)
+	}
+
+	parse := func(name, v string, def time.Time) (time.Time, error) {
+		if v == "" {
+			return def, nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s property %q: %w", name, v, ErrInvalidInput)
+		}
+		return t.UTC(), nil
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	evtTime, err := parse("time", p.Time, now)
+	if err != nil {
+		return nil, err
+	}
+	start, err := parse("start", p.Start, evtTime)
+	if err != nil {
+		return nil, err
+	}
+	stale, err := parse("stale", p.Stale, evtTime.Add(defaultStaleOffset))
+	if err != nil {
+		return nil, err
+	}
+
+	evt := getEvent()
+	*evt = Event{
+		Version: "2.0",
+		Uid:     p.UID,
+		Type:    "a-u-G",
+		How:     "m-g",
+		Time:    CoTTime(evtTime),
+		Start:   CoTTime(start),
+		Stale:   CoTTime(stale),
+		Point: Point{
+			Lat: coords[1],
+			Lon: coords[0],
+			Ce:  9999999.0,
+			Le:  9999999.0,
+		},
+	}
+	if p.Type != "" {
+		evt.Type = p.Type
+	}
+	if p.How != "" {
+		evt.How = p.How
+	}
+	if len(coords) == 3 {
+		evt.Point.Hae = coords[2]
+	}
+	if p.Ce != nil {
+		evt.Point.Ce = *p.Ce
+	}
+	if p.Le != nil {
+		evt.Point.Le = *p.Le
+	}
+	if p.Callsign != "" {
+		evt.Detail = &Detail{Contact: &Contact{Callsign: p.Callsign}}
+	}
+	if err := evt.Validate(); err != nil {
+		ReleaseEvent(evt)
+		return nil, err
+	}
+	return evt, nil
+}
@@ -0,0 +1,63 @@
+package cotlib
+
+import "testing"
+
+func TestGeoJSONRoundTrip(t *testing.T) {
+	evt, err := NewEventBuilder("GJ1", "a-f-G-U-C", 34.5, -117.25, 120).
+		WithHow("h-e").
+		WithContact(&Contact{Callsign: "ALPHA"}).
+		Build()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	defer ReleaseEvent(evt)
+
+	data, err := evt.ToGeoJSON()
+	if err != nil {
+		t.Fatalf("ToGeoJSON: %v", err)
+	}
+	out, err := FromGeoJSON(data)
+	if err != nil {
+		t.Fatalf("FromGeoJSON: %v", err)
+	}
+	defer ReleaseEvent(out)
+
+	if out.Uid != evt.Uid || out.Type != evt.Type || out.How != evt.How {
+		t.Errorf("uid/type/how = %s/%s/%s, want %s/%s/%s", out.Uid, out.Type, out.How, evt.Uid, evt.Type, evt.How)
+	}
+	if out.Point != evt.Point {
+		t.Errorf("point = %+v, want %+v", out.Point, evt.Point)
+	}
+	if !out.Time.Time().Equal(evt.Time.Time()) || !out.Stale.Time().Equal(evt.Stale.Time()) {
+		t.Errorf("times = %v/%v, want %v/%v", out.Time, out.Stale, evt.Time, evt.Stale)
+	}
+	if out.Detail == nil || out.Detail.Contact == nil || out.Detail.Contact.Callsign != "ALPHA" {
+		t.Errorf("callsign not preserved: %+v", out.Detail)
+	}
+}
+
+func TestFromGeoJSONDefaultsAndErrors(t *testing.T) {
+	evt, err := FromGeoJSON([]byte(`{"type":"Feature","geometry":{"type":"Point","coordinates":[20,10]},"properties":{"uid":"GJ2"}}`))
+	if err != nil {
+		t.Fatalf("FromGeoJSON: %v", err)
+	}
+	if evt.Type != "a-u-G" || evt.Point.Lat != 10 || evt.Point.Lon != 20 {
+		t.Errorf("defaults not applied: type=%s point=%+v", evt.Type, evt.Point)
+	}
+	ReleaseEvent(evt)
+
+	bad := map[string]string{
+		"missing_uid":   `{"type":"Feature","geometry":{"type":"Point","coordinates":[20,10]},"properties":{}}`,
+		"no_geometry":   `{"type":"Feature","properties":{"uid":"GJ3"}}`,
+		"not_point":     `{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]},"properties":{"uid":"GJ3"}}`,
+		"bad_time":      `{"type":"Feature","geometry":{"type":"Point","coordinates":[20,10]},"properties":{"uid":"GJ3","time":"yesterday"}}`,
+		"not_a_feature": `{"type":"FeatureCollection","features":[]}`,
+	}
+	for name, data := range bad {
+		t.Run(name, func(t *testing.T) {
+			if _, err := FromGeoJSON([]byte(data)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,513 @@
+package cotlib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// earthRadius is the mean Earth radius in meters used for great-circle math.
+const earthRadius = 6371008.8
+
+// vertex is a single coordinate of a multi-vertex geometry.
+type vertex struct {
+	Lat float64
+	Lon float64
+}
+
+// haversine returns the great-circle distance in meters between two coordinates.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Destination returns the point reached by travelling distanceM meters
+// from p along the initial bearing bearingDeg (degrees clockwise from true
+// north). It solves the direct problem on a sphere with the mean Earth
+// radius, the same model used by the library's distance calculations, which
+// is accurate to within about 0.5% of the distance. The longitude is
+// wrapped into [-180, 180]; HAE, CE and LE are copied from p.
+func (p Point) Destination(bearingDeg, distanceM float64) Point {
+	phi1 := p.Lat * math.Pi / 180
+	lambda1 := p.Lon * math.Pi / 180
+	theta := bearingDeg * math.Pi / 180
+	delta := distanceM / earthRadius
+
+	sinPhi2 := math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta)
+	phi2 := math.Asin(sinPhi2)
+	lambda2 := lambda1 + math.Atan2(
+		math.Sin(theta)*math.Sin(delta)*math.Cos(phi1),
+		math.Cos(delta)-math.Sin(phi1)*sinPhi2,
+	)
+
+	out := p
+	out.Lat = phi2 * 180 / math.Pi
+	out.Lon = math.Mod(lambda2*180/math.Pi+540, 360) - 180
+	return out
+}
+
+// metersPerFoot is the length of the international foot in meters.
+const metersPerFoot = 0.3048
+
+// LatLonRadians returns the point's latitude and longitude in radians.
+func (p Point) LatLonRadians() (latRad, lonRad float64) {
+	return p.Lat * math.Pi / 180, p.Lon * math.Pi / 180
+}
+
+// HAEFeet returns the point's height above ellipsoid in international
+// feet. The 9999999 unknown-altitude sentinel is not special-cased, so
+// callers should check for it first.
+func (p Point) HAEFeet() float64 {
+	return p.Hae / metersPerFoot
+}
+
+// FromHAEFeet converts a height above ellipsoid in international feet to
+// meters, the unit of Point.Hae.
+func FromHAEFeet(feet float64) float64 {
+	return feet * metersPerFoot
+}
+
+// Predict dead-reckons the event's position forward by after, using the
+// course (degrees) and speed (meters per second) from its track detail.
+// It returns an error if the event has no position or no track, or if the
+// track cannot be parsed.
+func (e *Event) Predict(after time.Duration) (Point, error) {
+	if e == nil {
+		return Point{}, fmt.Errorf("nil event")
+	}
+	if e.NoPoint {
+		return Point{}, fmt.Errorf("event has no point")
+	}
+	if e.Detail == nil || e.Detail.Track == nil || len(e.Detail.Track.Raw) == 0 {
+		return Point{}, fmt.Errorf("event has no track")
+	}
+	course, speed, err := e.Detail.Track.values()
+	if err != nil {
+		return Point{}, err
+	}
+	return e.Point.Destination(course, speed*after.Seconds()), nil
+}
+
+// values parses the course (degrees true) and speed (meters per second)
+// attributes of the track.
+func (t *Track) values() (course, speed float64, err error) {
+	if t == nil || len(t.Raw) == 0 {
+		return 0, 0, fmt.Errorf("empty track")
+	}
+	var track struct {
+		Course float64 `xml:"course,attr"`
+		Speed  float64 `xml:"speed,attr"`
+	}
+	if err := xml.Unmarshal(t.Raw, &track); err != nil {
+		return 0, 0, fmt.Errorf("parse track: %w", err)
+	}
+	return track.Course, track.Speed, nil
+}
+
+// Speed conversion factors from meters per second.
+const (
+	metersPerNauticalMile = 1852
+	metersPerStatuteMile  = 1609.344
+)
+
+// SpeedKnots returns the track speed in knots. It returns 0 if the track
+// cannot be parsed.
+func (t *Track) SpeedKnots() float64 {
+	_, speed, err := t.values()
+	if err != nil {
+		return 0
+	}
+	return speed * 3600 / metersPerNauticalMile
+}
+
+// SpeedMPH returns the track speed in statute miles per hour. It returns 0
+// if the track cannot be parsed.
+func (t *Track) SpeedMPH() float64 {
+	_, speed, err := t.values()
+	if err != nil {
+		return 0
+	}
+	return speed * 3600 / metersPerStatuteMile
+}
+
+// cardinals are the eight compass points in clockwise order from north.
+var cardinals = [8]string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// CourseCardinal returns the nearest of the eight compass points (N, NE,
+// E, ... NW) to the track course. Courses outside 0-360 are wrapped. It
+// returns "" if the track cannot be parsed.
+func (t *Track) CourseCardinal() string {
+	course, _, err := t.values()
+	if err != nil || math.IsNaN(course) || math.IsInf(course, 0) {
+		return ""
+	}
+	course = math.Mod(course, 360)
+	if course < 0 {
+		course += 360
+	}
+	return cardinals[int(math.Floor((course+22.5)/45))%8]
+}
+
+// Coarsen reduces the precision of the event's position for publication,
+// snapping the point to a grid with cells of about meters on each side.
+// Latitude is snapped in steps of meters along the meridian and longitude
+// in steps of meters along the snapped parallel; a known HAE is rounded to
+// the nearest multiple of meters. CE is widened by half the cell diagonal
+// and LE by half a cell, so the error estimates still cover the original
+// position. Details are left unchanged, so a <precisionlocation>, shape
+// vertices, route waypoints and a link line attribute still reveal exact
+// coordinates; remove them before publishing if they are sensitive. It
+// returns an error wrapping ErrInvalidInput if meters is not a positive
+// finite number; events without a point are left unchanged.
+func (e *Event) Coarsen(meters float64) error {
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+	if !(meters > 0) || math.IsInf(meters, 1) {
+		return fmt.Errorf("coarsen resolution %v: %w", meters, ErrInvalidInput)
+	}
+	if e.NoPoint {
+		return nil
+	}
+	p := &e.Point
+
+	latStep := meters / earthRadius * 180 / math.Pi
+	p.Lat = math.Max(-90, math.Min(90, math.Round(p.Lat/latStep)*latStep))
+	lonStep := latStep / math.Cos(p.Lat*math.Pi/180)
+	if lonStep >= 360 || math.IsNaN(lonStep) {
+		p.Lon = 0
+	} else {
+		p.Lon = math.Round(p.Lon/lonStep) * lonStep
+		if p.Lon > 180 || p.Lon < -180 {
+			p.Lon = math.Mod(p.Lon+540, 360) - 180
+		}
+	}
+	if p.Hae != 9999999.0 {
+		p.Hae = math.Round(p.Hae/meters) * meters
+	}
+	p.Ce = math.Min(p.Ce+meters*math.Sqrt2/2, 9999999.0)
+	p.Le = math.Min(p.Le+meters/2, 9999999.0)
+	return nil
+}
+
+// Coarsened returns a copy of the event with its position coarsened as by
+// Coarsen, leaving e unchanged. The detail is copied, but the extension
+// values it references are shared with e, and coordinate-bearing details
+// keep their exact positions as described for Coarsen.
+func (e *Event) Coarsened(meters float64) (*Event, error) {
+	if e == nil {
+		return nil, fmt.Errorf("nil event")
+	}
+	out := cloneEvent(e)
+	if err := out.Coarsen(meters); err != nil {
+		ReleaseEvent(out)
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseLinkPoint parses a route link point attribute of the form
+// "lat,lon" or "lat,lon,hae".
+func parseLinkPoint(s string) (vertex, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return vertex{}, fmt.Errorf("invalid link point %q", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return vertex{}, fmt.Errorf("invalid link point %q: %w", s, err)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return vertex{}, fmt.Errorf("invalid link point %q: %w", s, err)
+	}
+	if err := ValidateLatLon(lat, lon); err != nil {
+		return vertex{}, fmt.Errorf("invalid link point %q: %w", s, err)
+	}
+	return vertex{Lat: lat, Lon: lon}, nil
+}
+
+// shapePolyline holds the vertices of a shape polyline.
+type shapePolyline struct {
+	Closed   bool `xml:"closed,attr"`
+	Vertices []struct {
+		Lat float64 `xml:"lat,attr"`
+		Lon float64 `xml:"lon,attr"`
+	} `xml:"vertex"`
+}
+
+// parseShapePolyline extracts the polyline from a raw shape extension.
+// It returns nil if the shape does not contain a polyline.
+func parseShapePolyline(raw RawMessage) (*shapePolyline, error) {
+	var helper struct {
+		XMLName  xml.Name       `xml:"shape"`
+		Polyline *shapePolyline `xml:"polyline"`
+	}
+	if err := xml.Unmarshal(raw, &helper); err != nil {
+		return nil, fmt.Errorf("parse shape: %w", err)
+	}
+	return helper.Polyline, nil
+}
+
+// geometryParts holds the coordinates found in an event's detail by
+// walkGeometry. Unparseable coordinates are skipped and the first problem
+// with the shape and with the route is recorded.
+type geometryParts struct {
+	shape       []vertex // shape polyline vertices
+	hasShape    bool     // the shape carries a polyline
+	shapeClosed bool     // the polyline is marked closed
+	shapeErr    error
+	route       []vertex // route waypoint link points
+	routeErr    error
+	line        []vertex // points of a telestration link's line attribute
+}
+
+// walkGeometry extracts every coordinate set carried by the event's detail.
+// It is the single parser behind geometry, AllCoordinates and BoundingBox.
+func (e *Event) walkGeometry() geometryParts {
+	var g geometryParts
+	if e.Detail == nil {
+		return g
+	}
+
+	if e.Detail.Shape != nil && len(e.Detail.Shape.Raw) > 0 {
+		pl, err := parseShapePolyline(e.Detail.Shape.Raw)
+		switch {
+		case err != nil:
+			g.shapeErr = err
+		case pl != nil:
+			g.hasShape, g.shapeClosed = true, pl.Closed
+			if len(pl.Vertices) == 0 {
+				g.shapeErr = fmt.Errorf("shape polyline has no vertices")
+			}
+			for _, v := range pl.Vertices {
+				if err := ValidateLatLon(v.Lat, v.Lon); err != nil {
+					if g.shapeErr == nil {
+						g.shapeErr = fmt.Errorf("invalid shape vertex: %w", err)
+					}
+					continue
+				}
+				g.shape = append(g.shape, vertex{Lat: v.Lat, Lon: v.Lon})
+			}
+		}
+	}
+
+	for _, rl := range e.Detail.RouteLinks {
+		v, err := parseLinkPoint(rl.Point)
+		if err != nil {
+			if g.routeErr == nil {
+				g.routeErr = err
+			}
+			continue
+		}
+		g.route = append(g.route, v)
+	}
+
+	if e.Detail.LinkDetail != nil && len(e.Detail.LinkDetail.Raw) > 0 {
+		var helper struct {
+			Line string `xml:"line,attr"`
+		}
+		if err := xml.Unmarshal(e.Detail.LinkDetail.Raw, &helper); err == nil {
+			for _, f := range strings.FieldsFunc(helper.Line, func(r rune) bool { return r == ' ' || r == ';' }) {
+				if v, err := parseLinkPoint(f); err == nil {
+					g.line = append(g.line, v)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// geometry returns the vertices describing the event's geometry and whether
+// they form a closed polygon. It returns no vertices for point events.
+func (e *Event) geometry() ([]vertex, bool, error) {
+	g := e.walkGeometry()
+	if g.shapeErr != nil {
+		return nil, false, g.shapeErr
+	}
+	if g.hasShape {
+		return g.shape, g.shapeClosed, nil
+	}
+
+	if e.Detail != nil && len(e.Detail.RouteLinks) > 0 {
+		if g.routeErr != nil {
+			return nil, false, g.routeErr
+		}
+		vs := g.route
+		// Rectangles list their corners without repeating the first one.
+		closed := strings.HasPrefix(e.Type, "u-d-r")
+		if len(vs) > 2 && vs[0] == vs[len(vs)-1] {
+			closed = true
+		}
+		return vs, closed, nil
+	}
+
+	return nil, false, nil
+}
+
+// AllCoordinates returns every coordinate the event refers to, for spatial
+// indexing and bounding boxes: the event point followed by any shape
+// polyline vertices, route waypoint link points and the coordinates of a
+// telestration link's line attribute. A plain point event yields a single
+// element and an event without a point and geometry yields none. Vertices
+// carry no altitude or error estimate, so they have an HAE of 0 and the
+// 9999999 CE and LE sentinels. Coordinates that cannot be parsed are
+// skipped. The vertices come from the same walk Centroid uses.
+func (e *Event) AllCoordinates() []Point {
+	if e == nil {
+		return nil
+	}
+	var pts []Point
+	if !e.NoPoint {
+		pts = append(pts, e.Point)
+	}
+	g := e.walkGeometry()
+	for _, set := range [][]vertex{g.shape, g.route, g.line} {
+		for _, v := range set {
+			p := unknownPoint
+			p.Lat, p.Lon = v.Lat, v.Lon
+			pts = append(pts, p)
+		}
+	}
+	return pts
+}
+
+// BoundingBox returns the smallest latitude/longitude box containing every
+// coordinate returned by AllCoordinates. ok is false if the event refers to
+// no coordinates. The box is planar and does not handle geometries that
+// cross the antimeridian.
+func (e *Event) BoundingBox() (minLat, minLon, maxLat, maxLon float64, ok bool) {
+	pts := e.AllCoordinates()
+	if len(pts) == 0 {
+		return 0, 0, 0, 0, false
+	}
+	minLat, minLon, maxLat, maxLon = pts[0].Lat, pts[0].Lon, pts[0].Lat, pts[0].Lon
+	for _, p := range pts[1:] {
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+		minLon, maxLon = math.Min(minLon, p.Lon), math.Max(maxLon, p.Lon)
+	}
+	return minLat, minLon, maxLat, maxLon, true
+}
+
+// Centroid returns a single representative coordinate for the event.
+//
+// For point events (including ellipses) the event point is returned. For
+// closed shapes and rectangles the area-weighted polygon centroid is used,
+// and for open polylines and routes the point halfway along the line's
+// length is returned. Coordinates are treated as planar, which is adequate
+// for geometries that are small and do not cross the antimeridian.
+//
+// The returned point carries the event's HAE, CE and LE values.
+// An error is returned if the geometry cannot be parsed.
+func (e *Event) Centroid() (Point, error) {
+	if e == nil {
+		return Point{}, fmt.Errorf("nil event")
+	}
+	vs, closed, err := e.geometry()
+	if err != nil {
+		return Point{}, err
+	}
+	if len(vs) == 0 {
+		return e.Point, nil
+	}
+
+	var c vertex
+	if closed {
+		c = polygonCentroid(vs)
+	} else {
+		c = polylineMidpoint(vs)
+	}
+	p := e.Point
+	p.Lat = c.Lat
+	p.Lon = c.Lon
+	return p, nil
+}
+
+// polygonCentroid returns the area-weighted centroid of a polygon. The ring
+// may be open or closed. Degenerate polygons fall back to the vertex mean.
+func polygonCentroid(vs []vertex) vertex {
+	if len(vs) > 1 && vs[0] == vs[len(vs)-1] {
+		vs = vs[:len(vs)-1]
+	}
+	var area, cx, cy float64
+	for i := range vs {
+		j := (i + 1) % len(vs)
+		cross := vs[i].Lon*vs[j].Lat - vs[j].Lon*vs[i].Lat
+		area += cross
+		cx += (vs[i].Lon + vs[j].Lon) * cross
+		cy += (vs[i].Lat + vs[j].Lat) * cross
+	}
+	if area == 0 {
+		return vertexMean(vs)
+	}
+	area /= 2
+	return vertex{Lat: cy / (6 * area), Lon: cx / (6 * area)}
+}
+
+// polylineMidpoint returns the point halfway along a polyline by length.
+func polylineMidpoint(vs []vertex) vertex {
+	if len(vs) == 1 {
+		return vs[0]
+	}
+	lengths := make([]float64, len(vs)-1)
+	var total float64
+	for i := 0; i < len(vs)-1; i++ {
+		lengths[i] = haversine(vs[i].Lat, vs[i].Lon, vs[i+1].Lat, vs[i+1].Lon)
+		total += lengths[i]
+	}
+	if total == 0 {
+		return vs[0]
+	}
+	half := total / 2
+	for i, l := range lengths {
+		if l > 0 && half <= l {
+			f := half / l
+			return vertex{
+				Lat: vs[i].Lat + (vs[i+1].Lat-vs[i].Lat)*f,
+				Lon: vs[i].Lon + (vs[i+1].Lon-vs[i].Lon)*f,
+			}
+		}
+		half -= l
+	}
+	return vs[len(vs)-1]
+}
+
+// vertexMean returns the arithmetic mean of the vertices.
+func vertexMean(vs []vertex) vertex {
+	var c vertex
+	for _, v := range vs {
+		c.Lat += v.Lat
+		c.Lon += v.Lon
+	}
+	n := float64(len(vs))
+	return vertex{Lat: c.Lat / n, Lon: c.Lon / n}
+}
+
+// ImpliedSpeed returns the speed in meters per second needed to move from
+// the position reported by prev to the position reported by e, using the
+// great-circle distance between the points and the difference between the
+// event times. It returns an error if the events have different uids or
+// identical times. Callers can compare the result against a plausible
+// maximum to reject spoofed or teleporting tracks.
+func (e *Event) ImpliedSpeed(prev *Event) (metersPerSec float64, err error) {
+	if e == nil || prev == nil {
+		return 0, fmt.Errorf("nil event")
+	}
+	if e.Uid != prev.Uid {
+		return 0, fmt.Errorf("uid mismatch: %s != %s", e.Uid, prev.Uid)
+	}
+	dt := e.Time.Time().Sub(prev.Time.Time()).Seconds()
+	if dt == 0 {
+		return 0, fmt.Errorf("events have identical times")
+	}
+	dist := haversine(prev.Point.Lat, prev.Point.Lon, e.Point.Lat, e.Point.Lon)
+	return dist / math.Abs(dt), nil
+}
@@ -0,0 +1,424 @@
+package cotlib
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEventCentroid(t *testing.T) {
+	const eps = 1e-9
+
+	t.Run("point", func(t *testing.T) {
+		evt, err := NewEvent("C1", "a-f-G", 12.5, -45.25, 10)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		c, err := evt.Centroid()
+		if err != nil {
+			t.Fatalf("centroid: %v", err)
+		}
+		if c != evt.Point {
+			t.Errorf("centroid = %+v, want event point %+v", c, evt.Point)
+		}
+	})
+
+	t.Run("polyline", func(t *testing.T) {
+		evt, err := NewEventBuilder("C2", "b-m-r", 0, 0, 0).
+			WithRouteLink(RouteLink{Uid: "wp1", Type: "b-m-p-w", Point: "0,0", Relation: "c"}).
+			WithRouteLink(RouteLink{Uid: "wp2", Type: "b-m-p-w", Point: "0,1", Relation: "c"}).
+			WithRouteLink(RouteLink{Uid: "wp3", Type: "b-m-p-w", Point: "0,3", Relation: "c"}).
+			Build()
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		c, err := evt.Centroid()
+		if err != nil {
+			t.Fatalf("centroid: %v", err)
+		}
+		if math.Abs(c.Lat) > eps || math.Abs(c.Lon-1.5) > eps {
+			t.Errorf("centroid = (%v,%v), want (0,1.5)", c.Lat, c.Lon)
+		}
+	})
+
+	t.Run("square_polygon", func(t *testing.T) {
+		evt, err := NewEvent("C3", "u-d-f", 0, 0, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.Detail = &Detail{Shape: &Shape{Raw: []byte(`<shape><polyline closed="true">` +
+			`<vertex lat="10" lon="20" hae="0"/><vertex lat="10" lon="22" hae="0"/>` +
+			`<vertex lat="12" lon="22" hae="0"/><vertex lat="12" lon="20" hae="0"/>` +
+			`</polyline></shape>`)}}
+		c, err := evt.Centroid()
+		if err != nil {
+			t.Fatalf("centroid: %v", err)
+		}
+		if math.Abs(c.Lat-11) > eps || math.Abs(c.Lon-21) > eps {
+			t.Errorf("centroid = (%v,%v), want (11,21)", c.Lat, c.Lon)
+		}
+	})
+
+	t.Run("unparseable", func(t *testing.T) {
+		evt, err := NewEvent("C4", "b-m-r", 0, 0, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.Detail = &Detail{RouteLinks: []RouteLink{{Point: "north,east"}}}
+		if _, err := evt.Centroid(); err == nil {
+			t.Error("expected error for unparseable route point")
+		}
+	})
+}
+
+func TestEventAllCoordinates(t *testing.T) {
+	latLons := func(pts []Point) [][2]float64 {
+		out := make([][2]float64, len(pts))
+		for i, p := range pts {
+			out[i] = [2]float64{p.Lat, p.Lon}
+		}
+		return out
+	}
+	equal := func(got, want [][2]float64) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("point", func(t *testing.T) {
+		evt, err := NewEvent("AC1", "a-f-G", 12.5, -45.25, 10)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		pts := evt.AllCoordinates()
+		if len(pts) != 1 || pts[0] != evt.Point {
+			t.Errorf("AllCoordinates() = %+v, want [%+v]", pts, evt.Point)
+		}
+	})
+
+	t.Run("polyline_shape", func(t *testing.T) {
+		evt, err := NewEvent("AC2", "u-d-f", 10, 20, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.Detail = &Detail{Shape: &Shape{Raw: []byte(`<shape><polyline closed="false">` +
+			`<vertex lat="10" lon="20"/><vertex lat="10.5" lon="20.5"/><vertex lat="11" lon="20"/>` +
+			`</polyline></shape>`)}}
+		pts := evt.AllCoordinates()
+		want := [][2]float64{{10, 20}, {10, 20}, {10.5, 20.5}, {11, 20}}
+		if got := latLons(pts); !equal(got, want) {
+			t.Fatalf("AllCoordinates() = %v, want %v", got, want)
+		}
+		if pts[1].Ce != 9999999 || pts[1].Le != 9999999 {
+			t.Errorf("vertex errors = %v/%v, want unknown sentinels", pts[1].Ce, pts[1].Le)
+		}
+	})
+
+	t.Run("route", func(t *testing.T) {
+		evt, err := NewEventBuilder("AC3", "b-m-r", 0, 0, 0).
+			WithRouteLink(RouteLink{Uid: "wp1", Type: "b-m-p-w", Point: "1,2", Relation: "c"}).
+			WithRouteLink(RouteLink{Uid: "wp2", Type: "b-m-p-w", Point: "3,4,100", Relation: "c"}).
+			WithRouteLink(RouteLink{Uid: "wp3", Type: "b-m-p-w", Point: "north,east", Relation: "c"}).
+			Build()
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.NoPoint = true
+		want := [][2]float64{{1, 2}, {3, 4}}
+		if got := latLons(evt.AllCoordinates()); !equal(got, want) {
+			t.Errorf("AllCoordinates() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("telestration_line", func(t *testing.T) {
+		evt, err := NewEvent("AC4", "u-d-f-m", 5, 6, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.Detail = &Detail{LinkDetail: &DetailLink{Raw: []byte(`<link line="5,6 5.5,6.5;6,7"/>`)}}
+		want := [][2]float64{{5, 6}, {5, 6}, {5.5, 6.5}, {6, 7}}
+		if got := latLons(evt.AllCoordinates()); !equal(got, want) {
+			t.Errorf("AllCoordinates() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestEventBoundingBox(t *testing.T) {
+	t.Run("shape", func(t *testing.T) {
+		evt, err := NewEvent("BB1", "u-d-f", 11, 21, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.Detail = &Detail{Shape: &Shape{Raw: []byte(`<shape><polyline closed="true">` +
+			`<vertex lat="10" lon="20"/><vertex lat="10" lon="22"/>` +
+			`<vertex lat="12" lon="22"/><vertex lat="12" lon="20"/>` +
+			`</polyline></shape>`)}}
+		minLat, minLon, maxLat, maxLon, ok := evt.BoundingBox()
+		if !ok || minLat != 10 || minLon != 20 || maxLat != 12 || maxLon != 22 {
+			t.Errorf("BoundingBox() = %v,%v,%v,%v,%v, want 10,20,12,22,true", minLat, minLon, maxLat, maxLon, ok)
+		}
+		// Centroid reads the same vertices and falls inside the box.
+		c, err := evt.Centroid()
+		if err != nil {
+			t.Fatalf("centroid: %v", err)
+		}
+		if c.Lat < minLat || c.Lat > maxLat || c.Lon < minLon || c.Lon > maxLon {
+			t.Errorf("centroid (%v,%v) outside bounding box", c.Lat, c.Lon)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		evt, err := NewEvent("BB2", "a-f-G", 0, 0, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer ReleaseEvent(evt)
+		evt.NoPoint = true
+		if _, _, _, _, ok := evt.BoundingBox(); ok {
+			t.Error("BoundingBox() ok for event without coordinates")
+		}
+	})
+}
+
+func TestEventImpliedSpeed(t *testing.T) {
+	prev, err := NewEvent("S1", "a-f-G", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(prev)
+	cur, err := NewEvent("S1", "a-f-G", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(cur)
+
+	// One thousandth of a degree of latitude is ~111.2 m.
+	if err := cur.UpdatePosition(0.001, 0, 0, prev.Time.Time().Add(10*time.Second)); err != nil {
+		t.Fatalf("update position: %v", err)
+	}
+	speed, err := cur.ImpliedSpeed(prev)
+	if err != nil {
+		t.Fatalf("implied speed: %v", err)
+	}
+	if math.Abs(speed-11.12) > 0.01 {
+		t.Errorf("speed = %v m/s, want ~11.12", speed)
+	}
+
+	if _, err := prev.ImpliedSpeed(prev); err == nil {
+		t.Error("expected error for zero time delta")
+	}
+
+	other, err := NewEvent("S2", "a-f-G", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(other)
+	if _, err := cur.ImpliedSpeed(other); err == nil {
+		t.Error("expected error for uid mismatch")
+	}
+}
+
+func TestPointDestination(t *testing.T) {
+	const eps = 1e-9
+	degree := earthRadius * math.Pi / 180 // meters per degree of arc
+
+	tests := []struct {
+		name             string
+		from             Point
+		bearing, dist    float64
+		wantLat, wantLon float64
+	}{
+		{"north one degree", Point{Lat: 0, Lon: 0}, 0, degree, 1, 0},
+		{"east one degree", Point{Lat: 0, Lon: 0}, 90, degree, 0, 1},
+		{"south ten degrees", Point{Lat: 45, Lon: 10}, 180, 10 * degree, 35, 10},
+		{"across antimeridian", Point{Lat: 0, Lon: 179.5}, 90, degree, 0, -179.5},
+	}
+	for _, tt := range tests {
+		got := tt.from.Destination(tt.bearing, tt.dist)
+		if math.Abs(got.Lat-tt.wantLat) > eps || math.Abs(got.Lon-tt.wantLon) > eps {
+			t.Errorf("%s: got %.9f,%.9f want %v,%v", tt.name, got.Lat, got.Lon, tt.wantLat, tt.wantLon)
+		}
+	}
+
+	// Off-axis bearings preserve the travelled distance.
+	from := Point{Lat: 34.05, Lon: -118.25, Hae: 100}
+	to := from.Destination(37, 25000)
+	if d := haversine(from.Lat, from.Lon, to.Lat, to.Lon); math.Abs(d-25000) > 1e-6 {
+		t.Errorf("distance = %v, want 25000", d)
+	}
+	if to.Hae != 100 {
+		t.Errorf("HAE = %v, want 100", to.Hae)
+	}
+}
+
+func TestEventPredict(t *testing.T) {
+	evt, err := NewEvent("P1", "a-f-G", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+
+	if _, err := evt.Predict(time.Minute); err == nil {
+		t.Error("expected error without a track")
+	}
+
+	// 10 m/s due east for 1000 s is 10 km along the equator.
+	evt.Detail = &Detail{Track: &Track{Raw: RawMessage(`<track course="90" speed="10"/>`)}}
+	p, err := evt.Predict(1000 * time.Second)
+	if err != nil {
+		t.Fatalf("Predict: %v", err)
+	}
+	wantLon := 10000 / earthRadius * 180 / math.Pi
+	if math.Abs(p.Lat) > 1e-9 || math.Abs(p.Lon-wantLon) > 1e-9 {
+		t.Errorf("Predict = %v,%v want 0,%v", p.Lat, p.Lon, wantLon)
+	}
+
+	// Stationary tracks stay put.
+	evt.Detail.Track.Raw = RawMessage(`<track course="270" speed="0"/>`)
+	if p, err := evt.Predict(time.Hour); err != nil || p.Lat != 0 || p.Lon != 0 {
+		t.Errorf("stationary Predict = %+v, %v", p, err)
+	}
+}
+
+func TestEventCoarsen(t *testing.T) {
+	evt, err := NewEvent("CO1", "a-f-G", 30.0004, -85.0012, 12)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Point.Ce, evt.Point.Le = 5, 5
+	evt.Detail = &Detail{Contact: &Contact{Callsign: "ALPHA"}}
+	orig := evt.Point
+
+	out, err := evt.Coarsened(100)
+	if err != nil {
+		t.Fatalf("Coarsened: %v", err)
+	}
+	defer ReleaseEvent(out)
+	if evt.Point != orig {
+		t.Errorf("Coarsened modified the original point: %+v", evt.Point)
+	}
+	out.Detail.Contact = nil
+	if evt.Detail.Contact == nil {
+		t.Error("editing the coarsened copy's detail changed the original")
+	}
+
+	latStep := 100 / earthRadius * 180 / math.Pi
+	lonStep := latStep / math.Cos(out.Point.Lat*math.Pi/180)
+	p := out.Point
+	if r := math.Remainder(p.Lat, latStep); math.Abs(r) > 1e-9 {
+		t.Errorf("lat %v is not on the grid (remainder %v)", p.Lat, r)
+	}
+	if r := math.Remainder(p.Lon, lonStep); math.Abs(r) > 1e-9 {
+		t.Errorf("lon %v is not on the grid (remainder %v)", p.Lon, r)
+	}
+	if math.Abs(p.Lat-orig.Lat) > latStep/2+1e-12 || math.Abs(p.Lon-orig.Lon) > lonStep/2+1e-12 {
+		t.Errorf("point %v,%v is not the nearest cell to %v,%v", p.Lat, p.Lon, orig.Lat, orig.Lon)
+	}
+	if d := haversine(orig.Lat, orig.Lon, p.Lat, p.Lon); d > p.Ce {
+		t.Errorf("CE %v does not cover the %v m displacement", p.Ce, d)
+	}
+	if p.Ce <= orig.Ce || p.Le <= orig.Le {
+		t.Errorf("error estimates not widened: CE %v LE %v", p.Ce, p.Le)
+	}
+	if p.Hae != 0 {
+		t.Errorf("HAE = %v, want 0", p.Hae)
+	}
+	if err := out.Validate(); err != nil {
+		t.Errorf("coarsened event invalid: %v", err)
+	}
+
+	// Points within the same cell coarsen to the same position.
+	near, err := NewEvent("CO2", "a-f-G", p.Lat+latStep/3, p.Lon-lonStep/3, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(near)
+	if err := near.Coarsen(100); err != nil {
+		t.Fatalf("Coarsen: %v", err)
+	}
+	if near.Point.Lat != p.Lat || near.Point.Lon != p.Lon {
+		t.Errorf("nearby point coarsened to %v,%v want %v,%v", near.Point.Lat, near.Point.Lon, p.Lat, p.Lon)
+	}
+
+	for _, m := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if err := evt.Coarsen(m); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Coarsen(%v) = %v, want ErrInvalidInput", m, err)
+		}
+	}
+}
+
+func TestPointUnitConversions(t *testing.T) {
+	const eps = 1e-9
+	lat, lon := Point{Lat: 90, Lon: -180}.LatLonRadians()
+	if math.Abs(lat-math.Pi/2) > eps || math.Abs(lon+math.Pi) > eps {
+		t.Errorf("LatLonRadians = %v, %v want π/2, -π", lat, lon)
+	}
+	lat, lon = Point{Lat: 45, Lon: 30}.LatLonRadians()
+	if math.Abs(lat-math.Pi/4) > eps || math.Abs(lon-math.Pi/6) > eps {
+		t.Errorf("LatLonRadians = %v, %v want π/4, π/6", lat, lon)
+	}
+
+	if ft := (Point{Hae: 1000}).HAEFeet(); math.Abs(ft-3280.84) > 0.005 {
+		t.Errorf("HAEFeet(1000 m) = %v, want ≈3280.84", ft)
+	}
+	if m := FromHAEFeet(1); m != 0.3048 {
+		t.Errorf("FromHAEFeet(1) = %v, want 0.3048", m)
+	}
+	if m := FromHAEFeet((Point{Hae: -123.5}).HAEFeet()); math.Abs(m+123.5) > eps {
+		t.Errorf("round trip = %v, want -123.5", m)
+	}
+}
+
+func TestTrackConversions(t *testing.T) {
+	const eps = 1e-9
+	for _, tc := range []struct {
+		raw        string
+		knots, mph float64
+		cardinal   string
+	}{
+		{`<track course="0" speed="0"/>`, 0, 0, "N"},
+		{`<track course="45" speed="1852"/>`, 3600, 1852 * 3600 / 1609.344, "NE"},
+		{`<track course="100" speed="10"/>`, 10 * 3600 / 1852.0, 10 * 3600 / 1609.344, "E"},
+		{`<track course="202.4" speed="0.44704"/>`, 0.44704 * 3600 / 1852, 1, "S"},
+		{`<track course="337.6" speed="1"/>`, 3600 / 1852.0, 3600 / 1609.344, "N"},
+		{`<track course="-90" speed="1"/>`, 3600 / 1852.0, 3600 / 1609.344, "W"},
+		{`<track course="292.5" speed="1"/>`, 3600 / 1852.0, 3600 / 1609.344, "NW"},
+	} {
+		tr := &Track{Raw: RawMessage(tc.raw)}
+		if got := tr.SpeedKnots(); math.Abs(got-tc.knots) > eps {
+			t.Errorf("%s: SpeedKnots() = %v, want %v", tc.raw, got, tc.knots)
+		}
+		if got := tr.SpeedMPH(); math.Abs(got-tc.mph) > eps {
+			t.Errorf("%s: SpeedMPH() = %v, want %v", tc.raw, got, tc.mph)
+		}
+		if got := tr.CourseCardinal(); got != tc.cardinal {
+			t.Errorf("%s: CourseCardinal() = %q, want %q", tc.raw, got, tc.cardinal)
+		}
+	}
+
+	var empty *Track
+	if empty.SpeedKnots() != 0 || empty.SpeedMPH() != 0 || empty.CourseCardinal() != "" {
+		t.Error("nil track should report zero values")
+	}
+	bad := &Track{Raw: RawMessage(`<track course="north"/>`)}
+	if got := bad.CourseCardinal(); got != "" {
+		t.Errorf("unparseable CourseCardinal() = %q", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"log/slog"
 	"sync/atomic"
+	"time"
 )
 
 // logger is the package-level logger instance
@@ -21,6 +22,10 @@ func init() {
 	SetMaxElementCount(10000)
 	SetMaxTokenLen(1024)
 	SetMaxValueLen(512 * 1024)
+	SetMaxNamespaceLen(1024)
+	SetMaxAttributeCount(256)
+	SetMaxFutureStart(24 * time.Hour)
+	SetMaxLinks(1000)
 }
 
 // SetLogger sets the package-level logger
@@ -0,0 +1,89 @@
+package cotlib
+
+import "encoding/xml"
+
+// ZMist is a single casualty report in the ZMIST format carried by a
+// MEDEVAC request: zap number, mechanism of injury, injuries sustained,
+// signs and symptoms, and treatment given.
+type ZMist struct {
+	Title string `xml:"title,attr,omitempty"`
+	Z     string `xml:"z,attr,omitempty"`
+	M     string `xml:"m,attr,omitempty"`
+	I     string `xml:"i,attr,omitempty"`
+	S     string `xml:"s,attr,omitempty"`
+	T     string `xml:"t,attr,omitempty"`
+}
+
+// Medevac represents the TAK _medevac_ extension sent with CASEVAC and
+// 9-line MEDEVAC requests. The commonly used attributes and the ZMIST
+// casualty reports are decoded into fields; Raw preserves the original
+// element, including attributes not modelled here, and is emitted when set.
+type Medevac struct {
+	XMLName             xml.Name   `xml:"_medevac_"`
+	Title               string     `xml:"title,attr,omitempty"`
+	Casevac             bool       `xml:"casevac,attr,omitempty"`
+	Freq                string     `xml:"freq,attr,omitempty"`
+	Urgent              int        `xml:"urgent,attr,omitempty"`
+	Priority            int        `xml:"priority,attr,omitempty"`
+	Routine             int        `xml:"routine,attr,omitempty"`
+	Hoist               bool       `xml:"hoist,attr,omitempty"`
+	ExtractionEquipment bool       `xml:"extraction_equipment,attr,omitempty"`
+	Ventilator          bool       `xml:"ventilator,attr,omitempty"`
+	Litter              int        `xml:"litter,attr,omitempty"`
+	Ambulatory          int        `xml:"ambulatory,attr,omitempty"`
+	Security            int        `xml:"security,attr,omitempty"`
+	HLZMarking          int        `xml:"hlz_marking,attr,omitempty"`
+	USMilitary          int        `xml:"us_military,attr,omitempty"`
+	USCivilian          int        `xml:"us_civilian,attr,omitempty"`
+	NonUSMilitary       int        `xml:"nonus_military,attr,omitempty"`
+	NonUSCivilian       int        `xml:"nonus_civilian,attr,omitempty"`
+	EPW                 int        `xml:"epw,attr,omitempty"`
+	Child               int        `xml:"child,attr,omitempty"`
+	MedlineRemarks      string     `xml:"medline_remarks,attr,omitempty"`
+	ZMists              []ZMist    `xml:"zMistsMap>zMist"`
+	Raw                 RawMessage `xml:"-"`
+}
+
+// UnmarshalXML captures the raw element and decodes its attributes and
+// ZMIST reports. Reports are read both from the usual <zMistsMap> wrapper
+// and from <zMist> elements placed directly under _medevac_.
+func (m *Medevac) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	raw, err := captureRaw(dec, start)
+	if err != nil {
+		return err
+	}
+	type alias Medevac
+	var a alias
+	if err := xml.Unmarshal(raw, &a); err != nil {
+		return err
+	}
+	var loose struct {
+		ZMists []ZMist `xml:"zMist"`
+	}
+	if err := xml.Unmarshal(raw, &loose); err != nil {
+		return err
+	}
+	*m = Medevac(a)
+	m.ZMists = append(m.ZMists, loose.ZMists...)
+	m.Raw = raw
+	return nil
+}
+
+// MarshalXML writes Raw when present and the decoded fields otherwise.
+func (m Medevac) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if len(m.Raw) > 0 {
+		return encodeRaw(enc, m.Raw)
+	}
+	start.Name = xml.Name{Local: "_medevac_"}
+	type alias Medevac
+	return enc.EncodeElement(alias(m), start)
+}
+
+// ZMistReports returns the ZMIST casualty reports from the event's
+// _medevac_ detail, or nil if there are none. The returned slice is a copy.
+func (e *Event) ZMistReports() []ZMist {
+	if e == nil || e.Detail == nil || e.Detail.Medevac == nil || len(e.Detail.Medevac.ZMists) == 0 {
+		return nil
+	}
+	return append([]ZMist(nil), e.Detail.Medevac.ZMists...)
+}
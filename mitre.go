@@ -0,0 +1,126 @@
+package cotlib
+
+import (
+	"context"
+	"strings"
+
+	"github.com/NERVsystems/cotlib/cottypes"
+)
+
+// mitreEventAttrs lists the optional event attributes defined by the MITRE
+// base event schema that are not mapped to Event fields.
+var mitreEventAttrs = map[string]bool{
+	"access": true,
+	"qos":    true,
+	"opex":   true,
+}
+
+// cloneRaw returns a copy of r.
+func cloneRaw(r RawMessage) RawMessage {
+	if r == nil {
+		return nil
+	}
+	return append(RawMessage(nil), r...)
+}
+
+// ToMITRE returns a copy of the event with TAK-specific content removed so
+// it can be forwarded to consumers that only understand base CoT.
+//
+// The strokeColor and usericon attributes and any unknown attributes not
+// defined by the MITRE base event schema are dropped. Only the contact
+// callsign, track, shape, uid, remarks, link and sensor details are kept;
+// all other typed details (including <_radio_>) and route waypoints are
+// removed, as are unknown detail elements following the TAK "__" naming
+// convention. Links to TAK types (see cottypes.IsTAK) are removed.
+//
+// The returned Event is obtained from the internal pool and may be released
+// with ReleaseEvent. The original event is not modified.
+func (e *Event) ToMITRE() *Event {
+	if e == nil {
+		return nil
+	}
+	out := getEvent()
+	*out = Event{
+		Version: e.Version,
+		Uid:     e.Uid,
+		Type:    e.Type,
+		How:     e.How,
+		Time:    e.Time,
+		Start:   e.Start,
+		Stale:   e.Stale,
+		Point:   e.Point,
+		Message: e.Message,
+	}
+
+	for _, a := range e.UnknownAttrs {
+		if a.Name.Space == "" && mitreEventAttrs[a.Name.Local] {
+			out.UnknownAttrs = append(out.UnknownAttrs, a)
+		}
+	}
+
+	cat := cottypes.GetCatalog()
+	for _, l := range e.Links {
+		if cat != nil {
+			if t, err := cat.GetType(context.Background(), l.Type); err == nil && cottypes.IsTAK(t) {
+				continue
+			}
+		}
+		out.Links = append(out.Links, l)
+	}
+
+	if e.Detail == nil {
+		return out
+	}
+	d := &Detail{}
+	if e.Detail.Contact != nil {
+		d.Contact = &Contact{Callsign: e.Detail.Contact.Callsign}
+	}
+	if e.Detail.Track != nil {
+		d.Track = &Track{Raw: cloneRaw(e.Detail.Track.Raw)}
+	}
+	if e.Detail.Shape != nil {
+		d.Shape = &Shape{Raw: cloneRaw(e.Detail.Shape.Raw)}
+	}
+	if e.Detail.UID != nil {
+		d.UID = &UID{Raw: cloneRaw(e.Detail.UID.Raw)}
+	}
+	if e.Detail.Remarks != nil {
+		r := *e.Detail.Remarks
+		r.Raw = cloneRaw(r.Raw)
+		d.Remarks = &r
+	}
+	if e.Detail.LinkDetail != nil {
+		d.LinkDetail = &DetailLink{Raw: cloneRaw(e.Detail.LinkDetail.Raw)}
+	}
+	if r := e.Detail.Radio; r != nil && r.XMLName.Local == "sensor" {
+		rc := *r
+		rc.Raw = cloneRaw(r.Raw)
+		d.Radio = &rc
+	}
+	for _, raw := range e.Detail.Unknown {
+		if strings.HasPrefix(rawElementName(raw), "__") {
+			continue
+		}
+		d.Unknown = append(d.Unknown, cloneRaw(raw))
+	}
+	out.Detail = d
+	return out
+}
+
+// rawElementName returns the local name of the root element in raw.
+func rawElementName(raw RawMessage) string {
+	s := strings.TrimLeft(string(raw), " \t\r\n")
+	if !strings.HasPrefix(s, "<") {
+		return ""
+	}
+	s = s[1:]
+	end := strings.IndexAny(s, " \t\r\n/>")
+	if end < 0 {
+		return ""
+	}
+	name := s[:end]
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package cotlib
+
+import (
+	"fmt"
+	"time"
+)
+
+// Profile selects the set of rules applied when validating an event.
+// Producers disagree on which attributes and details are mandatory, so a
+// profile lets consumers tune validation to the dialect they ingest.
+//
+// The differences between the profiles are:
+//
+//   - ProfileTAK is the default used by Validate and ValidateAt. The how
+//     value is optional but must be known when present, wildcard types are
+//     accepted, and all detail extensions are validated against their schemas.
+//   - ProfileStrict applies every ProfileTAK check and additionally requires
+//     a how value and a type that is registered in the catalog (wildcard
+//     patterns such as "a-.-G" are rejected).
+//   - ProfileLenient accepts unknown how values and skips the detail schema
+//     step entirely. It is intended for legacy ATAK producers whose details
+//     frequently deviate from the published schemas.
+type Profile int
+
+const (
+	// ProfileTAK applies the default validation rules.
+	ProfileTAK Profile = iota
+	// ProfileStrict applies MITRE-strict validation rules.
+	ProfileStrict
+	// ProfileLenient applies relaxed rules for legacy producers.
+	ProfileLenient
+)
+
+// String returns the name of the profile.
+func (p Profile) String() string {
+	switch p {
+	case ProfileTAK:
+		return "tak"
+	case ProfileStrict:
+		return "strict"
+	case ProfileLenient:
+		return "lenient"
+	default:
+		return fmt.Sprintf("Profile(%d)", int(p))
+	}
+}
+
+// ValidateProfile checks if the event is valid under the given profile
+// using the current time as reference.
+func (e *Event) ValidateProfile(p Profile) error {
+	return e.validateProfileAt(time.Now().UTC(), p)
+}
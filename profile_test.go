@@ -0,0 +1,98 @@
+package cotlib_test
+
+import (
+	"testing"
+
+	"github.com/NERVsystems/cotlib"
+)
+
+func TestValidateProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(e *cotlib.Event)
+		strict  bool
+		tak     bool
+		lenient bool
+	}{
+		{
+			name:    "baseline",
+			mutate:  func(e *cotlib.Event) {},
+			strict:  true,
+			tak:     true,
+			lenient: true,
+		},
+		{
+			name:    "missing how",
+			mutate:  func(e *cotlib.Event) { e.How = "" },
+			strict:  false,
+			tak:     true,
+			lenient: true,
+		},
+		{
+			name:    "unknown how",
+			mutate:  func(e *cotlib.Event) { e.How = "z-z-z" },
+			strict:  false,
+			tak:     false,
+			lenient: true,
+		},
+		{
+			name:    "wildcard type",
+			mutate:  func(e *cotlib.Event) { e.Type = "a-.-G" },
+			strict:  false,
+			tak:     true,
+			lenient: true,
+		},
+		{
+			name: "invalid detail",
+			mutate: func(e *cotlib.Event) {
+				e.Detail = &cotlib.Detail{
+					Track: &cotlib.Track{Raw: []byte(`<track speed="10"/>`)},
+				}
+			},
+			strict:  false,
+			tak:     false,
+			lenient: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt, err := cotlib.NewEvent("P1", "a-f-G", 10, 20, 0)
+			if err != nil {
+				t.Fatalf("new event: %v", err)
+			}
+			defer cotlib.ReleaseEvent(evt)
+			tt.mutate(evt)
+
+			want := map[cotlib.Profile]bool{
+				cotlib.ProfileStrict:  tt.strict,
+				cotlib.ProfileTAK:     tt.tak,
+				cotlib.ProfileLenient: tt.lenient,
+			}
+			for p, ok := range want {
+				err := evt.ValidateProfile(p)
+				if ok && err != nil {
+					t.Errorf("%s: unexpected error: %v", p, err)
+				}
+				if !ok && err == nil {
+					t.Errorf("%s: expected error", p)
+				}
+			}
+
+			if got := evt.Validate() == nil; got != tt.tak {
+				t.Errorf("Validate() ok = %v, want ProfileTAK result %v", got, tt.tak)
+			}
+		})
+	}
+}
+
+func TestValidateProfileUnknown(t *testing.T) {
+	evt, err := cotlib.NewEvent("P2", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	if err := evt.ValidateProfile(cotlib.Profile(99)); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
@@ -0,0 +1,91 @@
+package cotlib
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// Radio represents the radio frequency detail carried by EW and SIGINT
+// events, either as a TAK <_radio_> element or as a MITRE <sensor> element
+// with a frequency attribute. Frequency and Bandwidth are in hertz. XMLName
+// records which element was decoded; a Radio built in code is written as
+// <_radio_>. Only the first radio element of a detail is decoded here; any
+// further <_radio_> or frequency-bearing <sensor> is kept in Detail.Unknown.
+// Raw preserves the original element, including attributes not
+// modelled here, and is emitted when set.
+type Radio struct {
+	XMLName    xml.Name   `xml:""`
+	Frequency  float64    `xml:"frequency,attr,omitempty"`
+	Bandwidth  float64    `xml:"bandwidth,attr,omitempty"`
+	Modulation string     `xml:"modulation,attr,omitempty"`
+	Raw        RawMessage `xml:"-"`
+}
+
+// isRadioSensor reports whether a <sensor> start element carries a
+// frequency and should be decoded as a Radio. Other sensors are orientation
+// reports and are kept as unknown details.
+func isRadioSensor(start xml.StartElement) bool {
+	for _, a := range start.Attr {
+		if a.Name.Space == "" && a.Name.Local == "frequency" {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalXML captures the raw element and decodes its attributes.
+func (r *Radio) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	raw, err := captureRaw(dec, start)
+	if err != nil {
+		return err
+	}
+	type alias Radio
+	var a alias
+	if err := xml.Unmarshal(raw, &a); err != nil {
+		return err
+	}
+	*r = Radio(a)
+	r.Raw = raw
+	return nil
+}
+
+// MarshalXML writes Raw when present and the decoded fields otherwise.
+func (r Radio) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if len(r.Raw) > 0 {
+		return encodeRaw(enc, r.Raw)
+	}
+	start = xml.StartElement{Name: xml.Name{Local: "_radio_"}}
+	if r.XMLName.Local != "" {
+		start.Name = xml.Name{Local: r.XMLName.Local}
+	}
+	if r.Frequency != 0 {
+		start.Attr = append(start.Attr, decimalAttr("frequency", r.Frequency))
+	}
+	if r.Bandwidth != 0 {
+		start.Attr = append(start.Attr, decimalAttr("bandwidth", r.Bandwidth))
+	}
+	if r.Modulation != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "modulation"}, Value: r.Modulation})
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// decimalAttr returns an attribute holding v in xs:decimal form. The
+// encoding/xml default switches to exponent notation for large values,
+// which decimal schema types reject.
+func decimalAttr(name string, v float64) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: strconv.FormatFloat(v, 'f', -1, 64)}
+}
+
+// RadioFrequency returns the frequency in hertz reported by the event's
+// radio detail. ok is false if the event has no radio detail or it does not
+// carry a frequency.
+func (e *Event) RadioFrequency() (hz float64, ok bool) {
+	if e == nil || e.Detail == nil || e.Detail.Radio == nil || e.Detail.Radio.Frequency <= 0 {
+		return 0, false
+	}
+	return e.Detail.Radio.Frequency, true
+}
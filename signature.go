@@ -0,0 +1,241 @@
+package cotlib
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// SignatureHMACSHA256 is the Signature algorithm written by Event.Sign.
+const SignatureHMACSHA256 = "HMAC-SHA256"
+
+// errNoSignature is returned by Verify when the event carries no signature.
+var errNoSignature = errors.New("event has no signature")
+
+// Signature represents a <signature> detail carrying a keyed hash of the
+// event for tamper evidence. Value holds the base64-encoded MAC computed
+// over the event's CanonicalBytes. Only signatures using the
+// SignatureHMACSHA256 algorithm are decoded into this type; other
+// <signature> elements are kept in Detail.Unknown. Raw preserves the
+// original element and is emitted when set.
+type Signature struct {
+	XMLName   xml.Name   `xml:"signature"`
+	Algorithm string     `xml:"algorithm,attr"`
+	Value     string     `xml:"value,attr"`
+	Raw       RawMessage `xml:"-"`
+}
+
+// isHMACSignature reports whether a <signature> start element was written
+// by Event.Sign and should be decoded as a Signature.
+func isHMACSignature(start xml.StartElement) bool {
+	for _, a := range start.Attr {
+		if a.Name.Space == "" && a.Name.Local == "algorithm" {
+			return a.Value == SignatureHMACSHA256
+		}
+	}
+	return false
+}
+
+// UnmarshalXML captures the raw element and decodes its attributes.
+func (s *Signature) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	raw, err := captureRaw(dec, start)
+	if err != nil {
+		return err
+	}
+	type alias Signature
+	var a alias
+	if err := xml.Unmarshal(raw, &a); err != nil {
+		return err
+	}
+	*s = Signature(a)
+	s.Raw = raw
+	return nil
+}
+
+// MarshalXML writes Raw when present and the decoded fields otherwise.
+func (s Signature) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if len(s.Raw) > 0 {
+		return encodeRaw(enc, s.Raw)
+	}
+	start.Name = xml.Name{Local: "signature"}
+	type alias Signature
+	return enc.EncodeElement(alias(s), start)
+}
+
+// write appends the <signature> element to buf.
+func (s *Signature) write(buf *bytes.Buffer) {
+	if len(s.Raw) > 0 {
+		buf.Write(s.Raw)
+		return
+	}
+	buf.WriteString(`<signature algorithm="`)
+	buf.WriteString(escapeAttr(s.Algorithm))
+	buf.WriteString(`" value="`)
+	buf.WriteString(escapeAttr(s.Value))
+	buf.WriteString(`"/>`)
+}
+
+// CanonicalBytes returns a deterministic encoding of the event for signing
+// and comparison. Attributes are written in the default order regardless of
+// SetEventAttributeOrder, with unknown attributes sorted by name; the point
+// uses the precision of ToXML so a decoded copy of a serialised event
+// encodes identically; the detail is encoded without its signature element
+// and omitted when nothing else remains; link children are re-encoded as
+// decoding would. The derived Message field is not included.
+func (e *Event) CanonicalBytes() ([]byte, error) {
+	if e == nil {
+		return nil, fmt.Errorf("nil event")
+	}
+	var buf bytes.Buffer
+	var tmp [32]byte
+
+	// Times are always canonicalized to CotTimeFormat, even when the event
+	// kept its original strings under SetPreserveTimeStrings.
+	norm := *e
+	norm.origTime, norm.origStart, norm.origStale = "", "", ""
+	buf.WriteString("<event")
+	for _, name := range defaultEventAttrOrder {
+		norm.writeEventAttr(&buf, name)
+	}
+	attrs := append([]xml.Attr(nil), e.UnknownAttrs...)
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].Name.Space != attrs[j].Name.Space {
+			return attrs[i].Name.Space < attrs[j].Name.Space
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	for _, a := range attrs {
+		buf.WriteByte(' ')
+		if a.Name.Space != "" {
+			buf.WriteString(a.Name.Space)
+			buf.WriteByte(':')
+		}
+		buf.WriteString(a.Name.Local)
+		buf.WriteString(`="`)
+		buf.WriteString(escapeAttr(a.Value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+
+	pt := e.Point
+	if e.NoPoint {
+		pt = unknownPoint
+	}
+	buf.WriteString(`<point lat="`)
+	buf.Write(strconv.AppendFloat(tmp[:0], pt.Lat, 'f', 6, 64))
+	buf.WriteString(`" lon="`)
+	buf.Write(strconv.AppendFloat(tmp[:0], pt.Lon, 'f', 6, 64))
+	buf.WriteString(`" hae="`)
+	buf.Write(strconv.AppendFloat(tmp[:0], pt.Hae, 'f', 1, 64))
+	buf.WriteString(`" ce="`)
+	buf.Write(strconv.AppendFloat(tmp[:0], pt.Ce, 'f', 1, 64))
+	buf.WriteString(`" le="`)
+	buf.Write(strconv.AppendFloat(tmp[:0], pt.Le, 'f', 1, 64))
+	buf.WriteString(`"/>`)
+
+	if e.Detail != nil {
+		d := *e.Detail
+		d.Signature = nil
+		var detail bytes.Buffer
+		enc := xml.NewEncoder(&detail)
+		if err := enc.EncodeElement(&d, xml.StartElement{Name: xml.Name{Local: "detail"}}); err != nil {
+			return nil, fmt.Errorf("marshal detail: %w", err)
+		}
+		if err := enc.Flush(); err != nil {
+			return nil, fmt.Errorf("marshal detail: %w", err)
+		}
+		if !bytes.Equal(detail.Bytes(), []byte("<detail></detail>")) {
+			buf.Write(detail.Bytes())
+		}
+	}
+
+	for _, l := range e.Links {
+		buf.WriteString(`<link uid="`)
+		buf.WriteString(escapeAttr(l.Uid))
+		buf.WriteString(`" type="`)
+		buf.WriteString(escapeAttr(l.Type))
+		buf.WriteString(`" relation="`)
+		buf.WriteString(escapeAttr(l.Relation))
+		if children := canonicalChildren(l.Children); children != "" {
+			buf.WriteString(`">`)
+			buf.WriteString(children)
+			buf.WriteString(`</link>`)
+			continue
+		}
+		buf.WriteString(`"/>`)
+	}
+	buf.WriteString("</event>")
+	return buf.Bytes(), nil
+}
+
+// Sign computes an HMAC-SHA256 of the event's CanonicalBytes with key and
+// stores it in a <signature> detail, replacing any previous signature.
+// Changing the event afterwards invalidates the signature.
+func (e *Event) Sign(key []byte) error {
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("empty signing key: %w", ErrInvalidInput)
+	}
+	mac, err := e.mac(key)
+	if err != nil {
+		return err
+	}
+	if e.Detail == nil {
+		e.Detail = &Detail{}
+	}
+	e.Detail.Signature = &Signature{
+		Algorithm: SignatureHMACSHA256,
+		Value:     base64.StdEncoding.EncodeToString(mac),
+	}
+	return nil
+}
+
+// Verify reports whether the event's <signature> detail matches an
+// HMAC-SHA256 of its CanonicalBytes under key. It returns false and no
+// error for a well-formed signature that does not match, which indicates
+// the event was altered or signed with a different key. An error is
+// returned if key is empty, the event has no signature or the signature is
+// malformed or uses an unsupported algorithm.
+func (e *Event) Verify(key []byte) (bool, error) {
+	if e == nil {
+		return false, fmt.Errorf("nil event")
+	}
+	if len(key) == 0 {
+		return false, fmt.Errorf("empty signing key: %w", ErrInvalidInput)
+	}
+	if e.Detail == nil || e.Detail.Signature == nil {
+		return false, errNoSignature
+	}
+	sig := e.Detail.Signature
+	if sig.Algorithm != SignatureHMACSHA256 {
+		return false, fmt.Errorf("unsupported signature algorithm %q: %w", sig.Algorithm, ErrInvalidInput)
+	}
+	got, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil {
+		return false, fmt.Errorf("decode signature: %w", ErrInvalidInput)
+	}
+	want, err := e.mac(key)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(got, want), nil
+}
+
+// mac returns the HMAC-SHA256 of the event's canonical bytes.
+func (e *Event) mac(key []byte) ([]byte, error) {
+	data, err := e.CanonicalBytes()
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil), nil
+}
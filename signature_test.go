@@ -0,0 +1,194 @@
+package cotlib
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventSignVerify(t *testing.T) {
+	key := []byte("relay-shared-secret")
+
+	evt, err := NewEvent("SIG1", "a-f-G-U-C", 34.123456, -117.654321, 120)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Detail = &Detail{
+		Contact: &Contact{Callsign: "ALPHA"},
+		Track:   &Track{Raw: RawMessage(`<track course="45" speed="2.5"/>`)},
+	}
+	evt.AddLink(&Link{Uid: "HQ", Type: "a-f-G", Relation: "p-p"})
+
+	if _, err := evt.Verify(key); err == nil {
+		t.Error("Verify on unsigned event: expected error")
+	}
+	if err := evt.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if ok, err := evt.Verify(key); err != nil || !ok {
+		t.Fatalf("Verify = %v, %v; want true", ok, err)
+	}
+	if err := evt.Validate(); err != nil {
+		t.Fatalf("signed event does not validate: %v", err)
+	}
+
+	// The signature survives serialisation across a relay.
+	data, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	relayed, err := UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, data)
+	}
+	defer ReleaseEvent(relayed)
+	if ok, err := relayed.Verify(key); err != nil || !ok {
+		t.Errorf("relayed Verify = %v, %v; want true\n%s", ok, err, data)
+	}
+
+	if ok, _ := relayed.Verify([]byte("other key")); ok {
+		t.Error("Verify with wrong key succeeded")
+	}
+
+	relayed.Point.Lat += 0.01
+	if ok, err := relayed.Verify(key); err != nil || ok {
+		t.Errorf("tampered point: Verify = %v, %v; want false", ok, err)
+	}
+	relayed.Point.Lat = evt.Point.Lat
+	relayed.Detail.Contact.Callsign = "BRAVO"
+	if ok, err := relayed.Verify(key); err != nil || ok {
+		t.Errorf("tampered detail: Verify = %v, %v; want false", ok, err)
+	}
+
+	if err := evt.Sign(nil); err == nil {
+		t.Error("Sign with empty key: expected error")
+	}
+	if _, err := evt.Verify(nil); err == nil {
+		t.Error("Verify with empty key: expected error")
+	}
+}
+
+func TestForeignSignaturePassthrough(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(CotTimeFormat) }
+	build := func(sig string) []byte {
+		return []byte(`<event version="2.0" uid="SIG3" type="a-f-G" how="m-g" time="` + ts(0) +
+			`" start="` + ts(0) + `" stale="` + ts(time.Minute) +
+			`"><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail>` + sig + `</detail></event>`)
+	}
+
+	foreign := `<signature scheme="x509"><cert>MIIB</cert></signature>`
+	evt, err := UnmarshalXMLEvent(context.Background(), build(foreign))
+	if err != nil {
+		t.Fatalf("unmarshal foreign signature: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	if evt.Detail.Signature != nil {
+		t.Error("foreign signature decoded as typed Signature")
+	}
+	if len(evt.Detail.Unknown) != 1 || string(evt.Detail.Unknown[0]) != foreign {
+		t.Errorf("Unknown = %q, want %s", evt.Detail.Unknown, foreign)
+	}
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !strings.Contains(string(out), foreign) {
+		t.Errorf("foreign signature not preserved: %s", out)
+	}
+
+	// Typed signatures are re-emitted as received.
+	hmacSig := `<signature value="AAAA" algorithm="HMAC-SHA256"></signature>`
+	evt2, err := UnmarshalXMLEvent(context.Background(), build(hmacSig))
+	if err != nil {
+		t.Fatalf("unmarshal HMAC signature: %v", err)
+	}
+	defer ReleaseEvent(evt2)
+	if evt2.Detail.Signature == nil || evt2.Detail.Signature.Value != "AAAA" {
+		t.Fatalf("Signature = %+v, want typed HMAC signature", evt2.Detail.Signature)
+	}
+	out, err = evt2.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !strings.Contains(string(out), hmacSig) {
+		t.Errorf("HMAC signature not preserved: %s", out)
+	}
+}
+
+func TestSignVerifyWithoutDetail(t *testing.T) {
+	key := []byte("relay-shared-secret")
+	evt, err := NewEvent("SIG4", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	if err := evt.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if ok, err := evt.Verify(key); err != nil || !ok {
+		t.Fatalf("Verify = %v, %v; want true", ok, err)
+	}
+	data, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	relayed, err := UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer ReleaseEvent(relayed)
+	if ok, err := relayed.Verify(key); err != nil || !ok {
+		t.Errorf("relayed Verify = %v, %v; want true\n%s", ok, err, data)
+	}
+}
+
+func TestSignVerifyLinkChildrenRelay(t *testing.T) {
+	key := []byte("relay-shared-secret")
+	evt, err := NewEvent("SIG5", "u-d-f", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.AddLink(&Link{Uid: "SHAPE-1", Type: "u-d-f", Relation: "p-p", Children: `<Style/>`})
+	if err := evt.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	data, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	relayed, err := UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer ReleaseEvent(relayed)
+	if relayed.Links[0].Children == evt.Links[0].Children {
+		t.Fatalf("expected decoding to re-encode %q", evt.Links[0].Children)
+	}
+	if ok, err := relayed.Verify(key); err != nil || !ok {
+		t.Errorf("relayed Verify = %v, %v; want true\n%s", ok, err, data)
+	}
+}
+
+func TestCanonicalBytesIgnorePreservedTimes(t *testing.T) {
+	evt, err := NewEvent("SIG2", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	want, err := evt.CanonicalBytes()
+	if err != nil {
+		t.Fatalf("CanonicalBytes: %v", err)
+	}
+	evt.origTime = evt.Time.Time().In(time.FixedZone("CEST", 2*60*60)).Format(time.RFC3339Nano)
+	got, err := evt.CanonicalBytes()
+	if err != nil {
+		t.Fatalf("CanonicalBytes: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("preserved time changed the canonical form:\ngot  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package cotlib
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/NERVsystems/cotlib/cottypes"
+)
+
+// DetectAndDecode reads CoT events from a connection that may carry either
+// a stream of XML events or TAK protocol (protobuf) stream framing. The
+// framing is chosen from the first non-whitespace byte: the TAK protocol
+// magic byte 0xbf selects protobuf, and '<' selects XML.
+//
+// It returns the first event and a function that reads the following
+// events using the detected framing. The function returns io.EOF once the
+// connection is exhausted. All returned events are validated, are obtained
+// from the internal pool and should be released with ReleaseEvent.
+//
+// The context is used for logging and is checked before each read; it does
+// not interrupt a blocked read on conn.
+func DetectAndDecode(ctx context.Context, conn io.Reader) (*Event, func() (*Event, error), error) {
+	br := bufio.NewReader(conn)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !isXMLSpace(b[0]) {
+			break
+		}
+		if _, err := br.ReadByte(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	b, _ := br.Peek(1)
+	var read func() ([]byte, error)
+	switch b[0] {
+	case takProtoMagic:
+		read = func() ([]byte, error) { return readTAKProtoFrame(br) }
+	case '<':
+		read = func() ([]byte, error) { return readXMLEvent(br) }
+	default:
+		return nil, nil, fmt.Errorf("unrecognized stream framing byte 0x%02x: %w", b[0], ErrInvalidInput)
+	}
+
+	next := func() (*Event, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := read()
+		if err != nil {
+			return nil, err
+		}
+		return UnmarshalXMLEvent(ctx, data)
+	}
+
+	first, err := next()
+	if err != nil {
+		return nil, nil, err
+	}
+	return first, next, nil
+}
+
+// isXMLSpace reports whether c is XML whitespace.
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// readTAKProtoFrame reads one TAK protocol stream frame (0xbf, varint
+// length, TakMessage) and returns the equivalent XML event.
+func readTAKProtoFrame(br *bufio.Reader) ([]byte, error) {
+	magic, err := br.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if magic != takProtoMagic {
+		return nil, fmt.Errorf("invalid TAK protocol magic 0x%02x: %w", magic, ErrInvalidInput)
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if n > uint64(currentMaxXMLSize()) {
+		return nil, fmt.Errorf("TAK protocol frame of %d bytes exceeds limit: %w", n, ErrInvalidInput)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(br, msg); err != nil {
+		return nil, noEOF(err)
+	}
+	return takMessageXML(msg)
+}
+
+// readXMLEvent reads bytes up to and including the next </event> end tag.
+// Whitespace between events is skipped and io.EOF is returned once the
+// stream ends cleanly, including when only comments or processing
+// instructions follow the last event.
+func readXMLEvent(br *bufio.Reader) ([]byte, error) {
+	var buf []byte
+	for {
+		chunk, err := br.ReadSlice('>')
+		if len(buf) == 0 {
+			chunk = bytes.TrimLeft(chunk, " \t\r\n")
+		}
+		buf = append(buf, chunk...)
+		if int64(len(buf)) > currentMaxXMLSize() {
+			return nil, ErrInvalidInput
+		}
+		if err == nil {
+			if bytes.HasSuffix(buf, []byte("</event>")) {
+				return buf, nil
+			}
+			continue
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) && len(buf) > 0 && !onlyMisc(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+}
+
+// onlyMisc reports whether data consists solely of complete XML comments,
+// processing instructions and whitespace.
+func onlyMisc(data []byte) bool {
+	dec := cottypes.NewSecureDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+		switch t := tok.(type) {
+		case xml.Comment, xml.ProcInst:
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// noEOF converts io.EOF into io.ErrUnexpectedEOF for reads in the middle
+// of a frame.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
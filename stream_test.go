@@ -0,0 +1,196 @@
+package cotlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// protoBuf is a minimal protobuf encoder for building test frames.
+type protoBuf struct{ b []byte }
+
+func (p *protoBuf) key(field, wt int) { p.b = binary.AppendUvarint(p.b, uint64(field<<3|wt)) }
+
+func (p *protoBuf) str(field int, s string) { p.raw(field, []byte(s)) }
+
+func (p *protoBuf) raw(field int, b []byte) {
+	p.key(field, wireBytes)
+	p.b = binary.AppendUvarint(p.b, uint64(len(b)))
+	p.b = append(p.b, b...)
+}
+
+func (p *protoBuf) uint(field int, v uint64) {
+	p.key(field, wireVarint)
+	p.b = binary.AppendUvarint(p.b, v)
+}
+
+func (p *protoBuf) double(field int, v float64) {
+	p.key(field, wireFixed64)
+	p.b = binary.LittleEndian.AppendUint64(p.b, math.Float64bits(v))
+}
+
+// takFrame encodes a TAK protocol stream frame for a simple position report.
+func takFrame(uid string, lat, lon float64, now time.Time) []byte {
+	var contact protoBuf
+	contact.str(1, "*:-1:stcp")
+	contact.str(2, "ALPHA")
+	var track protoBuf
+	track.double(1, 5)
+	track.double(2, 90)
+	var detail protoBuf
+	detail.str(1, `<uid Droid="ALPHA"/>`)
+	detail.raw(2, contact.b)
+	detail.raw(7, track.b)
+
+	var cot protoBuf
+	cot.str(1, "a-f-G-U-C")
+	cot.str(5, uid)
+	ms := uint64(now.UnixMilli())
+	cot.uint(6, ms)
+	cot.uint(7, ms)
+	cot.uint(8, ms+uint64(time.Minute/time.Millisecond))
+	cot.str(9, "m-g")
+	cot.double(10, lat)
+	cot.double(11, lon)
+	cot.double(12, 100)
+	cot.double(13, 10)
+	cot.double(14, 5)
+	cot.raw(15, detail.b)
+
+	var msg protoBuf
+	msg.raw(2, cot.b)
+
+	frame := []byte{takProtoMagic}
+	frame = binary.AppendUvarint(frame, uint64(len(msg.b)))
+	return append(frame, msg.b...)
+}
+
+func TestDetectAndDecode(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	t.Run("xml", func(t *testing.T) {
+		var stream bytes.Buffer
+		for _, uid := range []string{"X1", "X2"} {
+			evt, err := NewEvent(uid, "a-f-G", 10, 20, 0)
+			if err != nil {
+				t.Fatalf("new event: %v", err)
+			}
+			data, err := evt.ToXML()
+			if err != nil {
+				t.Fatalf("ToXML: %v", err)
+			}
+			ReleaseEvent(evt)
+			stream.Write(data)
+			stream.WriteString("\n")
+		}
+
+		first, next, err := DetectAndDecode(ctx, &stream)
+		if err != nil {
+			t.Fatalf("DetectAndDecode: %v", err)
+		}
+		defer ReleaseEvent(first)
+		if first.Uid != "X1" {
+			t.Errorf("first uid = %q, want X1", first.Uid)
+		}
+		second, err := next()
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		defer ReleaseEvent(second)
+		if second.Uid != "X2" {
+			t.Errorf("second uid = %q, want X2", second.Uid)
+		}
+		if _, err := next(); !errors.Is(err, io.EOF) {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		var stream bytes.Buffer
+		stream.Write(takFrame("P1", 10.5, -20.25, now))
+		stream.Write(takFrame("P2", 11, -21, now))
+
+		first, next, err := DetectAndDecode(ctx, &stream)
+		if err != nil {
+			t.Fatalf("DetectAndDecode: %v", err)
+		}
+		defer ReleaseEvent(first)
+		if first.Uid != "P1" || first.Type != "a-f-G-U-C" || first.How != "m-g" {
+			t.Errorf("first = %s/%s/%s, want P1/a-f-G-U-C/m-g", first.Uid, first.Type, first.How)
+		}
+		if first.Point.Lat != 10.5 || first.Point.Lon != -20.25 || first.Point.Hae != 100 {
+			t.Errorf("first point = %+v", first.Point)
+		}
+		if first.Detail == nil || first.Detail.Contact == nil || first.Detail.Contact.Callsign != "ALPHA" {
+			t.Fatalf("contact not decoded: %+v", first.Detail)
+		}
+		if first.Detail.Track == nil || first.Detail.UID == nil {
+			t.Errorf("track or xml detail missing: %+v", first.Detail)
+		}
+
+		second, err := next()
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		defer ReleaseEvent(second)
+		if second.Uid != "P2" {
+			t.Errorf("second uid = %q, want P2", second.Uid)
+		}
+		if _, err := next(); !errors.Is(err, io.EOF) {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	})
+
+	t.Run("xml_comments_and_pis", func(t *testing.T) {
+		evt, err := NewEvent("C1", "a-f-G", 10, 20, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		data, err := evt.ToXML()
+		ReleaseEvent(evt)
+		if err != nil {
+			t.Fatalf("ToXML: %v", err)
+		}
+		var stream bytes.Buffer
+		stream.WriteString("<!-- generated by X -->\n")
+		stream.Write(data)
+		stream.WriteString("\n<?keepalive?>\n")
+		stream.Write(data)
+		stream.WriteString("\n<!-- end of feed -->\n")
+
+		first, next, err := DetectAndDecode(ctx, &stream)
+		if err != nil {
+			t.Fatalf("DetectAndDecode: %v", err)
+		}
+		ReleaseEvent(first)
+		second, err := next()
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		ReleaseEvent(second)
+		if _, err := next(); !errors.Is(err, io.EOF) {
+			t.Errorf("expected io.EOF after trailing comment, got %v", err)
+		}
+	})
+
+	t.Run("unknown_framing", func(t *testing.T) {
+		_, _, err := DetectAndDecode(ctx, bytes.NewReader([]byte("hello")))
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("expected ErrInvalidInput, got %v", err)
+		}
+	})
+
+	t.Run("truncated_frame", func(t *testing.T) {
+		frame := takFrame("P3", 0, 0, now)
+		_, _, err := DetectAndDecode(ctx, bytes.NewReader(frame[:len(frame)-4]))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+		}
+	})
+}
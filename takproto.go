@@ -0,0 +1,310 @@
+package cotlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// takProtoMagic is the byte that starts every TAK protocol frame.
+const takProtoMagic = 0xbf
+
+// Protocol buffer wire types used by the TAK protocol messages.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncatedProto is returned when a protobuf message ends unexpectedly.
+var errTruncatedProto = errors.New("truncated protobuf message")
+
+// protoReader walks the fields of an encoded protobuf message.
+type protoReader struct {
+	b []byte
+}
+
+// next returns the number and wire type of the next field.
+func (p *protoReader) next() (int, int, error) {
+	key, err := p.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(key >> 3), int(key & 7), nil
+}
+
+func (p *protoReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(p.b)
+	if n <= 0 {
+		return 0, errTruncatedProto
+	}
+	p.b = p.b[n:]
+	return v, nil
+}
+
+func (p *protoReader) fixed64() (uint64, error) {
+	if len(p.b) < 8 {
+		return 0, errTruncatedProto
+	}
+	v := binary.LittleEndian.Uint64(p.b)
+	p.b = p.b[8:]
+	return v, nil
+}
+
+func (p *protoReader) double() (float64, error) {
+	v, err := p.fixed64()
+	return math.Float64frombits(v), err
+}
+
+func (p *protoReader) bytes() ([]byte, error) {
+	n, err := p.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(p.b)) {
+		return nil, errTruncatedProto
+	}
+	v := p.b[:n]
+	p.b = p.b[n:]
+	return v, nil
+}
+
+// skip discards a field of the given wire type.
+func (p *protoReader) skip(wt int) error {
+	switch wt {
+	case wireVarint:
+		_, err := p.varint()
+		return err
+	case wireFixed64:
+		_, err := p.fixed64()
+		return err
+	case wireBytes:
+		_, err := p.bytes()
+		return err
+	case wireFixed32:
+		if len(p.b) < 4 {
+			return errTruncatedProto
+		}
+		p.b = p.b[4:]
+		return nil
+	default:
+		return fmt.Errorf("unsupported protobuf wire type %d", wt)
+	}
+}
+
+// protoFields calls fn for each field of msg. fn reports whether it
+// consumed the field; unconsumed fields are skipped.
+func protoFields(msg []byte, fn func(p *protoReader, field, wt int) (bool, error)) error {
+	p := &protoReader{b: msg}
+	for len(p.b) > 0 {
+		field, wt, err := p.next()
+		if err != nil {
+			return err
+		}
+		ok, err := fn(p, field, wt)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := p.skip(wt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// protoStrings decodes a message consisting only of string fields, keyed
+// by field number.
+func protoStrings(msg []byte) (map[int]string, error) {
+	out := make(map[int]string)
+	err := protoFields(msg, func(p *protoReader, field, wt int) (bool, error) {
+		if wt != wireBytes {
+			return false, nil
+		}
+		b, err := p.bytes()
+		out[field] = string(b)
+		return true, err
+	})
+	return out, err
+}
+
+// writeProtoAttrs writes name="value" pairs for the string fields of a
+// TAK protobuf detail message. names maps field numbers to attribute names.
+func writeProtoAttrs(buf *bytes.Buffer, msg []byte, names []string) error {
+	fields, err := protoStrings(msg)
+	if err != nil {
+		return err
+	}
+	for i, name := range names {
+		buf.WriteByte(' ')
+		buf.WriteString(name)
+		buf.WriteString(`="`)
+		buf.WriteString(escapeAttr(fields[i+1]))
+		buf.WriteByte('"')
+	}
+	return nil
+}
+
+// takDetailXML converts a TAK protocol Detail message into the children of
+// a CoT <detail> element.
+func takDetailXML(buf *bytes.Buffer, msg []byte) error {
+	var xmlDetail []byte
+	err := protoFields(msg, func(p *protoReader, field, wt int) (bool, error) {
+		if wt != wireBytes {
+			return false, nil
+		}
+		b, err := p.bytes()
+		if err != nil {
+			return true, err
+		}
+		switch field {
+		case 1:
+			xmlDetail = b
+		case 2:
+			buf.WriteString("<contact")
+			err = writeProtoAttrs(buf, b, []string{"endpoint", "callsign"})
+			buf.WriteString("/>")
+		case 3:
+			buf.WriteString("<__group")
+			err = writeProtoAttrs(buf, b, []string{"name", "role"})
+			buf.WriteString("/>")
+		case 4:
+			buf.WriteString("<precisionlocation")
+			err = writeProtoAttrs(buf, b, []string{"geopointsrc", "altsrc"})
+			buf.WriteString("/>")
+		case 5:
+			var battery uint64
+			err = protoFields(b, func(p *protoReader, field, wt int) (bool, error) {
+				if field != 1 || wt != wireVarint {
+					return false, nil
+				}
+				var err error
+				battery, err = p.varint()
+				return true, err
+			})
+			buf.WriteString(`<status battery="`)
+			buf.WriteString(strconv.FormatUint(battery, 10))
+			buf.WriteString(`"/>`)
+		case 6:
+			buf.WriteString("<takv")
+			err = writeProtoAttrs(buf, b, []string{"device", "platform", "os", "version"})
+			buf.WriteString("/>")
+		case 7:
+			var speed, course float64
+			err = protoFields(b, func(p *protoReader, field, wt int) (bool, error) {
+				if wt != wireFixed64 {
+					return false, nil
+				}
+				v, err := p.double()
+				switch field {
+				case 1:
+					speed = v
+				case 2:
+					course = v
+				}
+				return true, err
+			})
+			buf.WriteString(`<track speed="`)
+			buf.WriteString(strconv.FormatFloat(speed, 'f', -1, 64))
+			buf.WriteString(`" course="`)
+			buf.WriteString(strconv.FormatFloat(course, 'f', -1, 64))
+			buf.WriteString(`"/>`)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	buf.Write(xmlDetail)
+	return err
+}
+
+// takMessageXML converts an encoded TAK protocol TakMessage into the
+// equivalent CoT XML event.
+func takMessageXML(msg []byte) ([]byte, error) {
+	var cot []byte
+	err := protoFields(msg, func(p *protoReader, field, wt int) (bool, error) {
+		if field != 2 || wt != wireBytes {
+			return false, nil
+		}
+		var err error
+		cot, err = p.bytes()
+		return true, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decode TakMessage: %w", err)
+	}
+	if cot == nil {
+		return nil, fmt.Errorf("TakMessage has no cotEvent: %w", ErrInvalidInput)
+	}
+
+	attrs := make(map[int]string)
+	times := make(map[int]uint64)
+	point := make(map[int]float64)
+	var detail bytes.Buffer
+	hasDetail := false
+	err = protoFields(cot, func(p *protoReader, field, wt int) (bool, error) {
+		switch {
+		case wt == wireBytes && field == 15:
+			b, err := p.bytes()
+			if err != nil {
+				return true, err
+			}
+			hasDetail = true
+			return true, takDetailXML(&detail, b)
+		case wt == wireBytes && (field <= 5 || field == 9):
+			b, err := p.bytes()
+			attrs[field] = string(b)
+			return true, err
+		case wt == wireVarint && field >= 6 && field <= 8:
+			v, err := p.varint()
+			times[field] = v
+			return true, err
+		case wt == wireFixed64 && field >= 10 && field <= 14:
+			v, err := p.double()
+			point[field] = v
+			return true, err
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decode CotEvent: %w", err)
+	}
+
+	ts := func(field int) string {
+		return time.UnixMilli(int64(times[field])).UTC().Format(CotTimeFormat)
+	}
+	ff := func(field int) string {
+		return strconv.FormatFloat(point[field], 'f', -1, 64)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<event version="2.0"`)
+	for _, a := range []struct {
+		name  string
+		field int
+	}{{"uid", 5}, {"type", 1}, {"how", 9}, {"access", 2}, {"qos", 3}, {"opex", 4}} {
+		v, ok := attrs[a.field]
+		if !ok || (v == "" && a.name != "uid" && a.name != "type") {
+			continue
+		}
+		buf.WriteString(" " + a.name + `="`)
+		buf.WriteString(escapeAttr(v))
+		buf.WriteByte('"')
+	}
+	buf.WriteString(` time="` + ts(6) + `" start="` + ts(7) + `" stale="` + ts(8) + `">`)
+	buf.WriteString(`<point lat="` + ff(10) + `" lon="` + ff(11) + `" hae="` + ff(12) +
+		`" ce="` + ff(13) + `" le="` + ff(14) + `"/>`)
+	if hasDetail {
+		buf.WriteString("<detail>")
+		buf.Write(detail.Bytes())
+		buf.WriteString("</detail>")
+	}
+	buf.WriteString("</event>")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,82 @@
+package cotlib
+
+import (
+	"encoding/xml"
+)
+
+// UASTelemetry represents the <_uastool_> detail carried by drone feeds.
+// Battery is the remaining charge in percent and is omitted when zero.
+// Home is the launch or return point reported by the aircraft. Raw
+// preserves the original element, including vendor attributes not
+// modelled here, and is emitted when set; otherwise the element is rebuilt
+// from the fields.
+type UASTelemetry struct {
+	XMLName    xml.Name   `xml:"_uastool_"`
+	OperatorID string     `xml:"operatorId,attr,omitempty"`
+	Battery    float64    `xml:"battery,attr,omitempty"`
+	FlightMode string     `xml:"flightMode,attr,omitempty"`
+	Home       *Point     `xml:"home,omitempty"`
+	Raw        RawMessage `xml:"-"`
+}
+
+// UnmarshalXML captures the raw element and decodes its fields.
+func (u *UASTelemetry) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	raw, err := captureRaw(dec, start)
+	if err != nil {
+		return err
+	}
+	type alias UASTelemetry
+	var a alias
+	if err := xml.Unmarshal(raw, &a); err != nil {
+		return err
+	}
+	*u = UASTelemetry(a)
+	u.Raw = raw
+	return nil
+}
+
+// MarshalXML writes Raw when present and the decoded fields otherwise.
+func (u UASTelemetry) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	if len(u.Raw) > 0 {
+		return encodeRaw(enc, u.Raw)
+	}
+	start = xml.StartElement{Name: xml.Name{Local: "_uastool_"}}
+	if u.OperatorID != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "operatorId"}, Value: u.OperatorID})
+	}
+	if u.Battery != 0 {
+		start.Attr = append(start.Attr, decimalAttr("battery", u.Battery))
+	}
+	if u.FlightMode != "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "flightMode"}, Value: u.FlightMode})
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if h := u.Home; h != nil {
+		home := xml.StartElement{Name: xml.Name{Local: "home"}, Attr: []xml.Attr{
+			decimalAttr("lat", h.Lat),
+			decimalAttr("lon", h.Lon),
+			decimalAttr("hae", h.Hae),
+			decimalAttr("ce", h.Ce),
+			decimalAttr("le", h.Le),
+		}}
+		if err := enc.EncodeToken(home); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(home.End()); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// UASHomePoint returns the home point reported by the event's UAS
+// telemetry detail. ok is false if the event has no such detail or it does
+// not report a home point.
+func (e *Event) UASHomePoint() (Point, bool) {
+	if e == nil || e.Detail == nil || e.Detail.UASTelemetry == nil || e.Detail.UASTelemetry.Home == nil {
+		return Point{}, false
+	}
+	return *e.Detail.UASTelemetry.Home, true
+}
@@ -6,6 +6,9 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -115,6 +118,36 @@ func TestValidateRelation(t *testing.T) {
 	}
 }
 
+// TestValidateHowsAndRelations checks that every bad code is reported.
+func TestValidateHowsAndRelations(t *testing.T) {
+	if err := cotlib.ValidateHows([]string{"h-g-i-g-o", "m-g", "h-e"}); err != nil {
+		t.Errorf("ValidateHows(valid) = %v", err)
+	}
+	err := cotlib.ValidateHows([]string{"h-g-i-g-o", "bogus-how", "m-g", "x-x-x"})
+	if !errors.Is(err, cotlib.ErrInvalidHow) {
+		t.Fatalf("ValidateHows error does not wrap ErrInvalidHow: %v", err)
+	}
+	for _, code := range []string{"bogus-how", "x-x-x"} {
+		if !strings.Contains(err.Error(), code) {
+			t.Errorf("ValidateHows error %q does not mention %s", err, code)
+		}
+	}
+	if strings.Contains(err.Error(), "m-g") {
+		t.Errorf("ValidateHows error %q mentions a valid code", err)
+	}
+
+	if err := cotlib.ValidateRelations([]string{"c", "p-p", "p"}); err != nil {
+		t.Errorf("ValidateRelations(valid) = %v", err)
+	}
+	err = cotlib.ValidateRelations([]string{"p-p", "", "bad-rel"})
+	if !errors.Is(err, cotlib.ErrInvalidRelation) {
+		t.Fatalf("ValidateRelations error does not wrap ErrInvalidRelation: %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "empty relation") || !strings.Contains(msg, "bad-rel") {
+		t.Errorf("ValidateRelations error %q does not list every bad code", msg)
+	}
+}
+
 // TestEventValidationWithHowAndRelation tests event validation including how and relation fields.
 func TestEventValidationWithHowAndRelation(t *testing.T) {
 	t.Run("valid_event_with_how", func(t *testing.T) {
@@ -296,6 +329,21 @@ func TestHowRelationDescriptors(t *testing.T) {
 		}
 	})
 
+	t.Run("find_hows", func(t *testing.T) {
+		found := false
+		for _, h := range cotlib.FindHows("gps") {
+			if h.What == "gps" && h.Value == "h-g-i-g-o" {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("Expected h-g-i-g-o (gps) in FindHows(\"gps\")")
+		}
+		if n, all := len(cotlib.FindHows("")), len(cotlib.AllHows()); n != all || all == 0 {
+			t.Errorf("FindHows(\"\") returned %d values, AllHows %d", n, all)
+		}
+	})
+
 	t.Run("get_relation_description", func(t *testing.T) {
 		desc, err := cotlib.GetRelationDescription("c")
 		if err != nil {
@@ -491,6 +539,87 @@ func TestTAKChatFallback(t *testing.T) {
 	cotlib.ReleaseEvent(evt)
 }
 
+func TestRequiresTAKDialect(t *testing.T) {
+	now := time.Now().UTC()
+	event := func(detail string) []byte {
+		return []byte(fmt.Sprintf(`<event version="2.0" uid="U" type="b-t-f" how="h-g-i-g-o" time="%[1]s" start="%[1]s" stale="%[2]s">`+
+			`<point lat="0" lon="0" hae="0" ce="1" le="1"/><detail>%[3]s</detail></event>`,
+			now.Format(cotlib.CotTimeFormat), now.Add(time.Minute).Format(cotlib.CotTimeFormat), detail))
+	}
+
+	for _, tc := range []struct {
+		name   string
+		detail string
+		want   []string
+	}{
+		{"no detail", ``, nil},
+		{"strict chat", `<__chat id="1" sender="A" message="hello"/>`, nil},
+		{"tak chat", `<__chat chatroom="room" groupOwner="false" id="1" senderCallsign="A"><chatgrp id="room" uid0="u"/></__chat>`, []string{"__chat"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			evt, err := cotlib.UnmarshalXMLEvent(context.Background(), event(tc.detail))
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			defer cotlib.ReleaseEvent(evt)
+			got, names := evt.RequiresTAKDialect()
+			if got != (len(tc.want) > 0) || strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("RequiresTAKDialect() = %v, %v; want %v", got, names, tc.want)
+			}
+		})
+	}
+}
+
+func TestChatSchemaVariant(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"cot", `<__chat sender="A" message="hello"/>`, cotlib.ChatVariantCoT},
+		{"tak", `<__chat chatroom="room" groupOwner="false" id="1" senderCallsign="A"><chatgrp id="room" uid0="u"/></__chat>`, cotlib.ChatVariantTAK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c cotlib.Chat
+			if err := xml.Unmarshal([]byte(tt.raw), &c); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if c.SchemaVariant != tt.want {
+				t.Errorf("SchemaVariant = %q, want %q", c.SchemaVariant, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetailValidationErrorPath(t *testing.T) {
+	evt, err := cotlib.NewEvent("P1", "b-t-f", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	evt.Detail = &cotlib.Detail{Chat: &cotlib.Chat{
+		ID:             "1",
+		Chatroom:       "room",
+		GroupOwner:     "false",
+		SenderCallsign: "A",
+		ChatGrps:       []cotlib.ChatGrp{{ID: "room"}},
+	}}
+	err = evt.Validate()
+	if err == nil {
+		t.Fatal("expected error for chatgrp without uid0")
+	}
+	if !strings.Contains(err.Error(), "detail/__chat/chatgrp@uid0") {
+		t.Errorf("error %q does not contain detail path", err)
+	}
+
+	evt.Detail = &cotlib.Detail{Track: &cotlib.Track{Raw: []byte(`<track speed="10"/>`)}}
+	err = evt.Validate()
+	if err == nil || !strings.Contains(err.Error(), "detail/track@course") {
+		t.Errorf("expected track error with detail path, got %v", err)
+	}
+}
+
 func TestUnmarshalInvalidChatExtensions(t *testing.T) {
 	now := time.Now().UTC()
 	base := `<event version="2.0" uid="U" type="a-f-G" time="%[1]s" start="%[1]s" stale="%[2]s">` +
@@ -1263,3 +1392,1222 @@ func TestRouteLinks(t *testing.T) {
 		}
 	})
 }
+
+func TestHierarchyTree(t *testing.T) {
+	h := &cotlib.Hierarchy{Raw: []byte(`<hierarchy>` +
+		`<group uid="root" name="HQ">` +
+		`<contact uid="c1" name="Alpha"/>` +
+		`<group uid="sub" name="Platoon">` +
+		`<contact uid="c2" name="Bravo"/>` +
+		`<contact uid="c3" name="Charlie"/>` +
+		`</group>` +
+		`</group>` +
+		`</hierarchy>`)}
+
+	tree, err := h.Tree()
+	if err != nil {
+		t.Fatalf("tree: %v", err)
+	}
+	if tree.UID != "root" || tree.Name != "HQ" {
+		t.Fatalf("root = %s/%s, want root/HQ", tree.UID, tree.Name)
+	}
+	if len(tree.Children) != 2 {
+		t.Fatalf("root children = %d, want 2", len(tree.Children))
+	}
+	if c := tree.Children[0]; c.UID != "c1" || c.Name != "Alpha" || len(c.Children) != 0 {
+		t.Errorf("first child = %+v, want contact c1/Alpha", c)
+	}
+	sub := tree.Children[1]
+	if sub.UID != "sub" || sub.Name != "Platoon" {
+		t.Fatalf("sub group = %s/%s, want sub/Platoon", sub.UID, sub.Name)
+	}
+	if len(sub.Children) != 2 || sub.Children[0].UID != "c2" || sub.Children[1].UID != "c3" {
+		t.Errorf("sub group children = %+v, want c2 and c3", sub.Children)
+	}
+
+	// Raw marshaling is preserved.
+	out, err := xml.Marshal(h)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	again, err := (&cotlib.Hierarchy{Raw: out}).Tree()
+	if err != nil {
+		t.Fatalf("tree after marshal: %v", err)
+	}
+	if fmt.Sprint(again) != fmt.Sprint(tree) {
+		t.Errorf("tree after marshal = %+v, want %+v", again, tree)
+	}
+
+	if _, err := (&cotlib.Hierarchy{Raw: []byte(`<hierarchy/>`)}).Tree(); err == nil {
+		t.Error("expected error for hierarchy without root group")
+	}
+}
+
+func TestValidateLinkSemantics(t *testing.T) {
+	evt, err := cotlib.NewEvent("LS1", "a-f-G", 1, 1, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+
+	evt.InjectIdentity("self-uid", "Cyan", "Team Member")
+	if err := evt.AddValidatedLink("unit-1", "a-f-G-U-C", "p-p"); err != nil {
+		t.Fatalf("add link: %v", err)
+	}
+	if err := evt.ValidateLinkSemantics(); err != nil {
+		t.Errorf("sensible links rejected: %v", err)
+	}
+
+	if err := evt.AddValidatedLink("route-1", "b-m-r", "p-p"); err != nil {
+		t.Fatalf("add link: %v", err)
+	}
+	if err := evt.Validate(); err != nil {
+		t.Fatalf("Validate should not apply link semantics: %v", err)
+	}
+	err = evt.ValidateLinkSemantics()
+	if err == nil {
+		t.Fatal("expected error for p-p link to a route")
+	}
+	if !errors.Is(err, cotlib.ErrInvalidRelation) {
+		t.Errorf("error = %v, want ErrInvalidRelation", err)
+	}
+}
+
+func TestLinkedUIDs(t *testing.T) {
+	evt, err := cotlib.NewEvent("L1", "a-f-G", 10, 20, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	evt.AddLink(&cotlib.Link{Uid: "PARENT", Type: "a-f-G", Relation: "p-p"})
+	evt.AddLink(&cotlib.Link{Uid: "SENSOR", Type: "b-m-p-s-p-i", Relation: "c"})
+	evt.AddLink(&cotlib.Link{Uid: "PARENT", Type: "a-f-G", Relation: "c"})
+	evt.AddLink(&cotlib.Link{Uid: "TARGET", Type: "a-h-G", Relation: "c"})
+
+	if got, want := fmt.Sprint(evt.LinkedUIDs()), "[PARENT SENSOR TARGET]"; got != want {
+		t.Errorf("LinkedUIDs() = %s, want %s", got, want)
+	}
+	if got := evt.LinksByRelation("p-p"); len(got) != 1 || got[0].Uid != "PARENT" {
+		t.Errorf("LinksByRelation(p-p) = %+v", got)
+	}
+	if got := evt.LinksByRelation("c"); len(got) != 3 {
+		t.Errorf("LinksByRelation(c) returned %d links, want 3", len(got))
+	}
+	if got := evt.LinksByRelation("p-c"); got != nil {
+		t.Errorf("LinksByRelation(p-c) = %+v, want nil", got)
+	}
+}
+
+func TestToMITRE(t *testing.T) {
+	const baseSchema = "mitre-CoT_Base-Event_Schema__(PUBLIC_RELEASE)"
+
+	evt, err := cotlib.NewEvent("M1", "a-f-G-U-C", 10, 20, 5)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	evt.StrokeColor = "ff00ff00"
+	evt.UserIcon = "COT_MAPPING_SPOTMAP/b-m-p-s-m/-65536"
+	evt.UnknownAttrs = []xml.Attr{
+		{Name: xml.Name{Local: "access"}, Value: "Unclassified"},
+		{Name: xml.Name{Local: "takAttr"}, Value: "x"},
+	}
+	evt.Detail = &cotlib.Detail{
+		Contact:        &cotlib.Contact{Callsign: "ALPHA", Endpoint: "*:-1:stcp"},
+		GroupExtension: &cotlib.GroupExtension{Raw: []byte(`<__group name="Cyan" role="Team Member"/>`)},
+		Takv:           &cotlib.Takv{Raw: []byte(`<takv platform="ATAK" version="4.0"/>`)},
+		Track:          &cotlib.Track{Raw: []byte(`<track course="90" speed="10"/>`)},
+		Unknown: []cotlib.RawMessage{
+			cotlib.RawMessage(`<__custom foo="bar"/>`),
+			cotlib.RawMessage(`<sensor azimuth="10"/>`),
+		},
+	}
+
+	evt.InjectIdentity("self-uid", "Cyan", "Team Member")
+
+	orig, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("marshal original: %v", err)
+	}
+
+	m := evt.ToMITRE()
+	defer cotlib.ReleaseEvent(m)
+
+	if m.StrokeColor != "" || m.UserIcon != "" {
+		t.Errorf("TAK attributes not stripped: strokeColor=%q usericon=%q", m.StrokeColor, m.UserIcon)
+	}
+	if len(m.UnknownAttrs) != 1 || m.UnknownAttrs[0].Name.Local != "access" {
+		t.Errorf("UnknownAttrs = %v, want only access", m.UnknownAttrs)
+	}
+	if m.Detail.GroupExtension != nil || m.Detail.Takv != nil || m.Detail.Group != nil {
+		t.Error("TAK details not stripped")
+	}
+	if len(m.Links) != 0 {
+		t.Errorf("links to TAK types not stripped: %+v", m.Links)
+	}
+	if m.Detail.Contact == nil || m.Detail.Contact.Callsign != "ALPHA" || m.Detail.Contact.Endpoint != "" {
+		t.Errorf("contact = %+v, want callsign only", m.Detail.Contact)
+	}
+	if m.Detail.Track == nil {
+		t.Error("track detail should be kept")
+	}
+	if len(m.Detail.Unknown) != 1 || !bytes.Contains(m.Detail.Unknown[0], []byte("sensor")) {
+		t.Errorf("Unknown = %q, want only sensor", m.Detail.Unknown)
+	}
+	if evt.StrokeColor == "" || evt.Detail.Takv == nil {
+		t.Error("original event modified")
+	}
+
+	out, err := m.ToXML()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := validator.ValidateAgainstSchema(baseSchema, out); err != nil {
+		t.Errorf("MITRE event does not validate against base schema: %v\n%s", err, out)
+	}
+	if len(validator.ListAvailableSchemas()) > 0 {
+		if err := validator.ValidateAgainstSchema(baseSchema, orig); err == nil {
+			t.Error("expected original TAK event to fail base schema validation")
+		}
+	}
+	if err := m.Validate(); err != nil {
+		t.Errorf("MITRE event failed validation: %v", err)
+	}
+}
+
+func TestGeofenceEnums(t *testing.T) {
+	center := cotlib.Point{Lat: 10, Lon: 20, Hae: 100}
+	tests := []struct {
+		monitor string
+		trigger string
+		valid   bool
+	}{
+		{"in", "enter", true},
+		{"out", "exit", true},
+		{"both", "entry", true},
+		{"sideways", "enter", false},
+		{"in", "linger", false},
+		{"", "exit", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.monitor+"_"+tt.trigger, func(t *testing.T) {
+			evt, err := cotlib.NewEvent("GF1", "u-d-c-c", center.Lat, center.Lon, center.Hae)
+			if err != nil {
+				t.Fatalf("new event: %v", err)
+			}
+			defer cotlib.ReleaseEvent(evt)
+			gf := cotlib.NewGeofence(center, 500, tt.monitor, tt.trigger)
+			if gf.Monitor() != tt.monitor || gf.Trigger() != tt.trigger {
+				t.Errorf("Monitor/Trigger = %q/%q, want %q/%q", gf.Monitor(), gf.Trigger(), tt.monitor, tt.trigger)
+			}
+			evt.Detail = &cotlib.Detail{Geofence: gf}
+			err = evt.Validate()
+			if tt.valid && err != nil {
+				t.Fatalf("valid geofence rejected: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected error for invalid geofence")
+			}
+		})
+	}
+
+	t.Run("unmarshal", func(t *testing.T) {
+		var gf cotlib.Geofence
+		raw := `<__geofence elevationMonitored="false" minElevation="0" monitor="out" trigger="exit" tracking="true" maxElevation="10" boundingSphere="1"/>`
+		if err := xml.Unmarshal([]byte(raw), &gf); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if gf.Monitor() != "out" || gf.Trigger() != "exit" {
+			t.Errorf("Monitor/Trigger = %q/%q, want out/exit", gf.Monitor(), gf.Trigger())
+		}
+	})
+
+	// Validation and the accessors only read the geofence, so a shared
+	// event can be checked from several goroutines (run with -race).
+	t.Run("concurrent", func(t *testing.T) {
+		evt, err := cotlib.NewEvent("GF2", "u-d-c-c", center.Lat, center.Lon, center.Hae)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer cotlib.ReleaseEvent(evt)
+		raw := `<__geofence elevationMonitored="false" minElevation="0" monitor="in" trigger="enter" tracking="true" maxElevation="10" boundingSphere="1"/>`
+		evt.Detail = &cotlib.Detail{Geofence: &cotlib.Geofence{Raw: cotlib.RawMessage(raw)}}
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := evt.Validate(); err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				if evt.Detail.Geofence.Monitor() != "in" || evt.Detail.Geofence.Trigger() != "enter" {
+					t.Error("unexpected Monitor/Trigger")
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestAttachmentListHashes(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes []string
+	}{
+		{"one", []string{"d41d8cd98f00b204e9800998ecf8427e"}},
+		{"several", []string{"aaa111", "bbb222", "ccc333"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt, err := cotlib.NewEvent("AL1", "a-f-G", 1, 2, 0)
+			if err != nil {
+				t.Fatalf("new event: %v", err)
+			}
+			defer cotlib.ReleaseEvent(evt)
+			evt.Detail = &cotlib.Detail{AttachmentList: cotlib.NewAttachmentList(tt.hashes...)}
+			if err := evt.Validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+
+			data, err := evt.ToXML()
+			if err != nil {
+				t.Fatalf("ToXML: %v", err)
+			}
+			out, err := cotlib.UnmarshalXMLEvent(context.Background(), data)
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			defer cotlib.ReleaseEvent(out)
+			if out.Detail == nil || out.Detail.AttachmentList == nil {
+				t.Fatalf("attachment list missing after round trip: %s", data)
+			}
+			if got := out.Detail.AttachmentList.Hashes(); fmt.Sprint(got) != fmt.Sprint(tt.hashes) {
+				t.Errorf("Hashes() = %v, want %v", got, tt.hashes)
+			}
+		})
+	}
+}
+
+func TestUserIconPath(t *testing.T) {
+	const path = "f7f71666-8b28-4b57-9fbb-e38e61d33b79/Google/hiker.png"
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(cotlib.CotTimeFormat) }
+	decode := func(t *testing.T, attrs, detail string) *cotlib.Event {
+		t.Helper()
+		data := fmt.Sprintf(`<event version="2.0" uid="UI1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"%s><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail>%s</detail></event>`,
+			ts(0), ts(0), ts(time.Minute), attrs, detail)
+		evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(data))
+		if err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return evt
+	}
+
+	t.Run("attribute", func(t *testing.T) {
+		evt := decode(t, ` usericon="`+path+`"`, "")
+		defer cotlib.ReleaseEvent(evt)
+		if got := evt.IconPath(); got != path {
+			t.Errorf("IconPath = %q, want %q", got, path)
+		}
+	})
+
+	t.Run("detail", func(t *testing.T) {
+		evt := decode(t, "", `<usericon iconsetpath="`+path+`"/>`)
+		defer cotlib.ReleaseEvent(evt)
+		if got := evt.IconPath(); got != path {
+			t.Errorf("IconPath = %q, want %q", got, path)
+		}
+	})
+
+	t.Run("set_round_trip", func(t *testing.T) {
+		evt, err := cotlib.NewEvent("UI2", "a-f-G", 1, 2, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer cotlib.ReleaseEvent(evt)
+		evt.SetUserIcon(path)
+		if err := evt.Validate(); err != nil {
+			t.Fatalf("validate: %v", err)
+		}
+		data, err := evt.ToXML()
+		if err != nil {
+			t.Fatalf("ToXML: %v", err)
+		}
+		out, err := cotlib.UnmarshalXMLEvent(context.Background(), data)
+		if err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		defer cotlib.ReleaseEvent(out)
+		if out.UserIcon != path || out.Detail == nil || out.Detail.UserIcon.IconsetPath() != path {
+			t.Errorf("icon forms not both preserved: %s", data)
+		}
+
+		evt.SetUserIcon("")
+		if evt.IconPath() != "" || evt.Detail.UserIcon != nil {
+			t.Error("SetUserIcon(\"\") should clear both forms")
+		}
+	})
+
+	t.Run("invalid_detail", func(t *testing.T) {
+		evt, err := cotlib.NewEvent("UI3", "a-f-G", 1, 2, 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		defer cotlib.ReleaseEvent(evt)
+		evt.Detail = &cotlib.Detail{UserIcon: &cotlib.UserIcon{Raw: []byte(`<usericon/>`)}}
+		if err := evt.Validate(); err == nil {
+			t.Error("expected usericon without iconsetpath to fail validation")
+		}
+	})
+}
+
+func TestRemarksKeywordsAndColor(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(cotlib.CotTimeFormat) }
+	data := fmt.Sprintf(`<event version="2.0" uid="RK1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail><remarks source="BAO.F.ATAK.RK1" keywords="medevac,urgent" color="-65536">LZ is hot</remarks></detail></event>`,
+		ts(0), ts(0), ts(time.Minute))
+
+	evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	r := evt.Detail.Remarks
+	if r.Keywords != "medevac,urgent" || r.Color != "-65536" || r.Source != "BAO.F.ATAK.RK1" || r.Text != "LZ is hot" {
+		t.Fatalf("remarks fields not decoded: %+v", r)
+	}
+
+	// Structured fields take precedence over Raw once edited.
+	r.Keywords = "medevac"
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	again, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("re-unmarshal: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(again)
+	r2 := again.Detail.Remarks
+	if r2.Keywords != "medevac" || r2.Color != "-65536" || r2.Text != "LZ is hot" {
+		t.Errorf("remarks fields not preserved: %+v", r2)
+	}
+
+	// xml.Marshal goes through the struct path as well.
+	b, err := xml.Marshal(&cotlib.Remarks{Keywords: "a,b", Color: "-1", Text: "x"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.HasPrefix(string(b), "<remarks ") || !strings.Contains(string(b), `keywords="a,b" color="-1">x</remarks>`) {
+		t.Errorf("marshal = %s", b)
+	}
+}
+
+func TestMedevacZMistRoundTrip(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(cotlib.CotTimeFormat) }
+	data := fmt.Sprintf(`<event version="2.0" uid="MED1" type="b-r-f-h-c" how="h-g-i-g-o" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail>`+
+		`<_medevac_ title="MED.1" casevac="true" freq="38.90" urgent="2" litter="1" ambulatory="1" hoist="true" hlz_marking="3" medline_remarks="smoke on approach" zone_prot_selection="0">`+
+		`<zMistsMap><zMist title="ZMIST1" z="A1" m="GSW" i="left leg" s="conscious" t="tourniquet"/><zMist title="ZMIST2" z="A2" m="blast" i="burns" s="unconscious" t="IV"/></zMistsMap>`+
+		`</_medevac_></detail></event>`, ts(0), ts(0), ts(time.Minute))
+
+	evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	want := []cotlib.ZMist{
+		{Title: "ZMIST1", Z: "A1", M: "GSW", I: "left leg", S: "conscious", T: "tourniquet"},
+		{Title: "ZMIST2", Z: "A2", M: "blast", I: "burns", S: "unconscious", T: "IV"},
+	}
+	check := func(t *testing.T, evt *cotlib.Event) {
+		t.Helper()
+		m := evt.Detail.Medevac
+		if m == nil {
+			t.Fatal("medevac detail not decoded")
+		}
+		if m.Title != "MED.1" || !m.Casevac || m.Freq != "38.90" || m.Urgent != 2 || m.Litter != 1 || m.Ambulatory != 1 || !m.Hoist || m.HLZMarking != 3 || m.MedlineRemarks != "smoke on approach" {
+			t.Errorf("medevac fields = %+v", m)
+		}
+		got := evt.ZMistReports()
+		if len(got) != len(want) {
+			t.Fatalf("ZMistReports = %+v, want %+v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("zMist %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}
+	check(t, evt)
+	if err := evt.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	// Round trip through the raw element.
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !strings.Contains(string(out), `zone_prot_selection="0"`) {
+		t.Errorf("unmodelled attribute dropped:\n%s", out)
+	}
+	again, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("re-unmarshal: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(again)
+	check(t, again)
+
+	// Round trip through the typed fields.
+	built, err := cotlib.NewEvent("MED2", "b-r-f-h-c", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(built)
+	built.Detail = &cotlib.Detail{Medevac: &cotlib.Medevac{
+		Title: "MED.1", Casevac: true, Freq: "38.90", Urgent: 2, Litter: 1, Ambulatory: 1,
+		Hoist: true, HLZMarking: 3, MedlineRemarks: "smoke on approach", ZMists: want,
+	}}
+	if err := built.Validate(); err != nil {
+		t.Fatalf("validate built: %v", err)
+	}
+	out, err = built.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML built: %v", err)
+	}
+	decoded, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal built: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(decoded)
+	check(t, decoded)
+
+	built.ZMistReports()[0].Title = "changed"
+	if built.Detail.Medevac.ZMists[0].Title != "ZMIST1" {
+		t.Error("ZMistReports returned the detail's backing slice")
+	}
+}
+
+func TestBullseyeRoundTrip(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(cotlib.CotTimeFormat) }
+	data := fmt.Sprintf(`<event version="2.0" uid="BE1" type="u-r-b-bullseye" how="h-e" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail>`+
+		`<bullseye mils="true" distance="1852.5" bearingRef="M" bullseyeUID="BE1" distanceUnits="u-r-b-bullseye" edgeToCenter="false" rangeRingVisible="true" title="Alpha" hasRangeRings="true"/>`+
+		`</detail></event>`, ts(0), ts(0), ts(time.Minute))
+
+	evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	want := cotlib.Bullseye{
+		Mils: true, Distance: 1852.5, BearingRef: "M", BullseyeUID: "BE1", DistanceUnits: cotlib.BullseyeDistanceUnits,
+		RangeRingVisible: true, Title: "Alpha", HasRangeRings: true,
+	}
+	check := func(t *testing.T, evt *cotlib.Event) {
+		t.Helper()
+		b := evt.Detail.Bullseye
+		if b == nil {
+			t.Fatal("bullseye detail not decoded")
+		}
+		if b.Mils != want.Mils || b.Distance != want.Distance || b.BearingRef != want.BearingRef || b.BullseyeUID != want.BullseyeUID ||
+			b.DistanceUnits != want.DistanceUnits || b.EdgeToCenter != want.EdgeToCenter || b.RangeRingVisible != want.RangeRingVisible ||
+			b.Title != want.Title || b.HasRangeRings != want.HasRangeRings {
+			t.Errorf("bullseye = %+v, want %+v", b, want)
+		}
+		if d, ref, ok := evt.BullseyeBearing(); !ok || d != want.Distance || ref != want.BearingRef {
+			t.Errorf("BullseyeBearing() = %v, %q, %v", d, ref, ok)
+		}
+	}
+	check(t, evt)
+	if err := evt.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	again, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("re-unmarshal: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(again)
+	check(t, again)
+
+	// Round trip through the typed fields.
+	built, err := cotlib.NewEvent("BE1", "u-r-b-bullseye", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(built)
+	if _, _, ok := built.BullseyeBearing(); ok {
+		t.Error("BullseyeBearing() ok without a bullseye detail")
+	}
+	b := cotlib.NewBullseye("BE1", "Alpha", 1852.5, "M")
+	b.Mils, b.RangeRingVisible, b.HasRangeRings = true, true, true
+	built.Detail = &cotlib.Detail{Bullseye: b}
+	if err := built.Validate(); err != nil {
+		t.Fatalf("validate built: %v", err)
+	}
+	out, err = built.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML built: %v", err)
+	}
+	decoded, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal built: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(decoded)
+	check(t, decoded)
+	if err := decoded.Validate(); err != nil {
+		t.Fatalf("validate decoded: %v", err)
+	}
+
+	// Large ranges are written as decimals rather than in exponent form.
+	built.Detail.Bullseye = cotlib.NewBullseye("BE1", "Far", 1852000, "T")
+	if err := built.Validate(); err != nil {
+		t.Errorf("validate 1000 nm bullseye: %v", err)
+	}
+}
+
+func TestRadioDetail(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(cotlib.CotTimeFormat) }
+	event := func(detail string) string {
+		return fmt.Sprintf(`<event version="2.0" uid="RF1" type="a-h-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail>%s</detail></event>`,
+			ts(0), ts(0), ts(time.Minute), detail)
+	}
+
+	for _, tc := range []struct {
+		name, detail string
+	}{
+		{"_radio_", `<_radio_ frequency="146520000" bandwidth="12500" modulation="FM" channel="2"/>`},
+		{"sensor", `<sensor frequency="146520000" bandwidth="12500" modulation="FM" azimuth="45"/>`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(event(tc.detail)))
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			defer cotlib.ReleaseEvent(evt)
+			r := evt.Detail.Radio
+			if r == nil {
+				t.Fatalf("radio detail not decoded; unknown = %q", evt.Detail.Unknown)
+			}
+			if r.XMLName.Local != tc.name || r.Frequency != 146520000 || r.Bandwidth != 12500 || r.Modulation != "FM" {
+				t.Errorf("radio = %+v", r)
+			}
+			if hz, ok := evt.RadioFrequency(); !ok || hz != 146520000 {
+				t.Errorf("RadioFrequency() = %v, %v", hz, ok)
+			}
+			if err := evt.Validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			out, err := evt.ToXML()
+			if err != nil {
+				t.Fatalf("ToXML: %v", err)
+			}
+			if !strings.Contains(string(out), strings.TrimSuffix(tc.detail, "/>")) {
+				t.Errorf("element not preserved:\n%s", out)
+			}
+			again, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+			if err != nil {
+				t.Fatalf("re-unmarshal: %v\n%s", err, out)
+			}
+			defer cotlib.ReleaseEvent(again)
+			if hz, ok := again.RadioFrequency(); !ok || hz != 146520000 {
+				t.Errorf("round trip RadioFrequency() = %v, %v", hz, ok)
+			}
+		})
+	}
+
+	// Orientation-only sensors are not radio details.
+	evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(event(`<sensor azimuth="45" fov="30"/>`)))
+	if err != nil {
+		t.Fatalf("unmarshal sensor: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	if evt.Detail.Radio != nil || len(evt.Detail.Unknown) != 1 {
+		t.Errorf("orientation sensor decoded as radio %+v, unknown %q", evt.Detail.Radio, evt.Detail.Unknown)
+	}
+	if _, ok := evt.RadioFrequency(); ok {
+		t.Error("RadioFrequency() ok without a radio detail")
+	}
+
+	// A detail carrying both elements keeps the second in Unknown.
+	both, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(event(
+		`<_radio_ frequency="146520000"/><sensor frequency="433920000" azimuth="45"/>`)))
+	if err != nil {
+		t.Fatalf("unmarshal both: %v", err)
+	}
+	defer cotlib.ReleaseEvent(both)
+	if hz, ok := both.RadioFrequency(); !ok || hz != 146520000 {
+		t.Errorf("both RadioFrequency() = %v, %v", hz, ok)
+	}
+	if len(both.Detail.Unknown) != 1 || !strings.Contains(string(both.Detail.Unknown[0]), `frequency="433920000"`) {
+		t.Errorf("second radio element not kept, unknown = %q", both.Detail.Unknown)
+	}
+	if out, err := both.ToXML(); err != nil || !strings.Contains(string(out), `<sensor frequency="433920000" azimuth="45">`) {
+		t.Errorf("second radio element not emitted (err %v):\n%s", err, out)
+	}
+
+	// Built from fields.
+	built, err := cotlib.NewEvent("RF2", "a-h-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(built)
+	built.Detail = &cotlib.Detail{Radio: &cotlib.Radio{Frequency: 433.92e6, Modulation: "ASK"}}
+	if err := built.Validate(); err != nil {
+		t.Fatalf("validate built: %v", err)
+	}
+	out, err := built.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML built: %v", err)
+	}
+	if !strings.Contains(string(out), `<_radio_ frequency="433920000" modulation="ASK"></_radio_>`) {
+		t.Errorf("built radio encoded as:\n%s", out)
+	}
+	decoded, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal built: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(decoded)
+	if hz, ok := decoded.RadioFrequency(); !ok || hz != 433.92e6 {
+		t.Errorf("built RadioFrequency() = %v, %v", hz, ok)
+	}
+
+	// The schema requires a positive frequency.
+	built.Detail.Radio = &cotlib.Radio{Modulation: "FM"}
+	if err := built.Validate(); err == nil {
+		t.Error("expected error for radio without frequency")
+	}
+}
+
+func TestContactPhoneEmailValidation(t *testing.T) {
+	evt, err := cotlib.NewEvent("CT1", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+
+	for _, tc := range []struct {
+		phone, email string
+		ok           bool
+	}{
+		{"", "", true},
+		{"+1-555-0100", "", true},
+		{"5550100", "ops@example.mil", true},
+		{"", "first.last@sub.example.org", true},
+		{"call me", "", false},
+		{"+", "", false},
+		{"555+0100", "", false},
+		{"", "ops", false},
+		{"", "ops@example", false},
+		{"", "ops@@example.org", false},
+		{"", "ops @example.org", false},
+		{"", "@example.org", false},
+	} {
+		evt.Detail = &cotlib.Detail{Contact: &cotlib.Contact{Callsign: "ALPHA", Phone: tc.phone, EmailAddress: tc.email}}
+		err := evt.Validate()
+		if tc.ok && err != nil {
+			t.Errorf("phone %q email %q: unexpected error %v", tc.phone, tc.email, err)
+		}
+		if !tc.ok && !errors.Is(err, cotlib.ErrInvalidContact) {
+			t.Errorf("phone %q email %q: error %v does not wrap ErrInvalidContact", tc.phone, tc.email, err)
+		}
+		if !tc.ok {
+			if err := evt.ValidateProfile(cotlib.ProfileLenient); err != nil {
+				t.Errorf("phone %q email %q: lenient profile error %v", tc.phone, tc.email, err)
+			}
+		}
+	}
+
+	c := &cotlib.Contact{Callsign: "ALPHA", Phone: " +1 (555) 010-0100 ", EmailAddress: " ops@example.mil\n"}
+	c.Normalize()
+	if c.Phone != "+15550100100" || c.EmailAddress != "ops@example.mil" {
+		t.Errorf("Normalize() = %q, %q", c.Phone, c.EmailAddress)
+	}
+
+	evt.Detail = &cotlib.Detail{Contact: c}
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	decoded, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(decoded)
+	if got := decoded.Detail.Contact; got.Phone != c.Phone || got.EmailAddress != c.EmailAddress {
+		t.Errorf("round trip contact = %+v, want %+v", got, c)
+	}
+}
+
+func TestUASTelemetryDetail(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(cotlib.CotTimeFormat) }
+	detail := `<_uastool_ operatorId="OP-7" battery="87.5" flightMode="LOITER" vendor="dji"><home lat="30.1" lon="-85.2" hae="12" ce="5" le="10"/></_uastool_>`
+	data := fmt.Sprintf(`<event version="2.0" uid="UAS1" type="a-f-A-M-F-Q" how="m-g" time="%s" start="%s" stale="%s"><point lat="30.2" lon="-85.1" hae="120" ce="5" le="5"/><detail>%s</detail></event>`,
+		ts(0), ts(0), ts(time.Minute), detail)
+
+	evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	u := evt.Detail.UASTelemetry
+	if u == nil {
+		t.Fatalf("uas detail not decoded; unknown = %q", evt.Detail.Unknown)
+	}
+	if u.OperatorID != "OP-7" || u.Battery != 87.5 || u.FlightMode != "LOITER" {
+		t.Errorf("uas = %+v", u)
+	}
+	want := cotlib.Point{Lat: 30.1, Lon: -85.2, Hae: 12, Ce: 5, Le: 10}
+	if home, ok := evt.UASHomePoint(); !ok || home != want {
+		t.Errorf("UASHomePoint() = %+v, %v", home, ok)
+	}
+	if err := evt.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !strings.Contains(string(out), `vendor="dji"`) {
+		t.Errorf("vendor attribute not preserved:\n%s", out)
+	}
+
+	// Built from fields.
+	built, err := cotlib.NewEvent("UAS2", "a-f-A-M-F-Q", 30.2, -85.1, 120)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(built)
+	built.Detail = &cotlib.Detail{UASTelemetry: &cotlib.UASTelemetry{
+		OperatorID: "OP-7",
+		Battery:    42,
+		FlightMode: "RTL",
+		Home:       &cotlib.Point{Lat: 30.1, Lon: -85.2, Hae: 12, Ce: 9999999, Le: 9999999},
+	}}
+	if err := built.Validate(); err != nil {
+		t.Fatalf("validate built: %v", err)
+	}
+	out, err = built.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML built: %v", err)
+	}
+	decoded, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal built: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(decoded)
+	if got := decoded.Detail.UASTelemetry; got == nil || got.Battery != 42 || got.FlightMode != "RTL" {
+		t.Fatalf("built round trip = %+v\n%s", got, out)
+	}
+	if home, ok := decoded.UASHomePoint(); !ok || home != *built.Detail.UASTelemetry.Home {
+		t.Errorf("built UASHomePoint() = %+v, %v", home, ok)
+	}
+
+	// The schema bounds the battery percentage and home coordinates.
+	built.Detail.UASTelemetry = &cotlib.UASTelemetry{Battery: 150}
+	if err := built.Validate(); err == nil {
+		t.Error("expected error for battery over 100")
+	}
+	built.Detail.UASTelemetry = &cotlib.UASTelemetry{Home: &cotlib.Point{Lat: 91}}
+	if err := built.Validate(); err == nil {
+		t.Error("expected error for home latitude out of range")
+	}
+	built.Detail = nil
+	if _, ok := built.UASHomePoint(); ok {
+		t.Error("UASHomePoint() ok without a uas detail")
+	}
+}
+
+func TestLabelsOnRoundTrip(t *testing.T) {
+	for _, on := range []bool{true, false} {
+		t.Run(fmt.Sprint(on), func(t *testing.T) {
+			evt, err := cotlib.NewEvent("LBL1", "u-d-f", 1, 2, 0)
+			if err != nil {
+				t.Fatalf("new event: %v", err)
+			}
+			defer cotlib.ReleaseEvent(evt)
+			evt.Detail = &cotlib.Detail{LabelsOn: cotlib.NewLabelsOn(on)}
+			if err := evt.Validate(); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+			out, err := evt.ToXML()
+			if err != nil {
+				t.Fatalf("ToXML: %v", err)
+			}
+			if want := fmt.Sprintf(`<labelson value="%t"/>`, on); !strings.Contains(string(out), want) {
+				t.Errorf("missing %s in:\n%s", want, out)
+			}
+			decoded, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+			if err != nil {
+				t.Fatalf("unmarshal: %v\n%s", err, out)
+			}
+			defer cotlib.ReleaseEvent(decoded)
+			if visible, ok := decoded.LabelsVisible(); !ok || visible != on {
+				t.Errorf("LabelsVisible() = %v, %v; want %v", visible, ok, on)
+			}
+			if len(decoded.Detail.LabelsOn.Raw) == 0 {
+				t.Error("decoded labelson has no Raw")
+			}
+
+			data, err := xml.Marshal(decoded.Detail)
+			if err != nil {
+				t.Fatalf("marshal detail: %v", err)
+			}
+			if want := fmt.Sprintf(`<labelson value="%t"></labelson>`, on); !strings.Contains(string(data), want) {
+				t.Errorf("detail marshal missing %s:\n%s", want, data)
+			}
+		})
+	}
+
+	evt, err := cotlib.NewEvent("LBL2", "u-d-f", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	if _, ok := evt.LabelsVisible(); ok {
+		t.Error("LabelsVisible() ok without a labelson detail")
+	}
+	evt.Detail = &cotlib.Detail{LabelsOn: &cotlib.LabelsOn{Raw: []byte(`<labelson value="maybe"/>`)}}
+	if err := evt.Validate(); err == nil {
+		t.Error("expected schema error for non-boolean value")
+	}
+}
+
+func TestStrokeColorAndUserIconValidation(t *testing.T) {
+	evt, err := cotlib.NewEvent("SC1", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+
+	for _, tc := range []struct {
+		name, color, icon string
+		wantErr           bool
+	}{
+		{"valid argb", "ff00FF00", "", false},
+		{"empty", "", "", false},
+		{"not a color", "notacolor", "", true},
+		{"short", "ff00ff", "", true},
+		{"hash prefix", "#ff00ff0", "", true},
+		{"icon path", "", "34ae1613-9645-4222-a9d2-e5f243dea2865/Military/soldier.png", false},
+		{"icon control char", "", "icons/\x00bad.png", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			evt.StrokeColor, evt.UserIcon = tc.color, tc.icon
+			err := evt.Validate()
+			if tc.wantErr && !errors.Is(err, cotlib.ErrInvalidInput) {
+				t.Errorf("Validate() = %v, want ErrInvalidInput", err)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+		})
+	}
+
+	evt.StrokeColor, evt.UserIcon = "notacolor", ""
+	if err := evt.ValidateProfile(cotlib.ProfileLenient); err != nil {
+		t.Errorf("lenient profile: %v", err)
+	}
+}
+
+func TestDetailValidateStandalone(t *testing.T) {
+	good := &cotlib.Detail{Track: &cotlib.Track{Raw: []byte(`<track course="90.5" speed="3.2"/>`)}}
+	if err := good.Validate(); err != nil {
+		t.Errorf("good track: %v", err)
+	}
+
+	bad := &cotlib.Detail{Track: &cotlib.Track{Raw: []byte(`<track course="north" speed="3.2"/>`)}}
+	err := bad.Validate()
+	if err == nil {
+		t.Fatal("bad track: expected error")
+	}
+	if !strings.Contains(err.Error(), "invalid track") {
+		t.Errorf("bad track error = %v", err)
+	}
+
+	// Explicit validation does not depend on the event-level toggle.
+	cotlib.SetDetailSchemaValidation(false)
+	defer cotlib.SetDetailSchemaValidation(true)
+	if err := bad.Validate(); err == nil {
+		t.Error("bad track with schema validation disabled: expected error")
+	}
+
+	var none *cotlib.Detail
+	if err := none.Validate(); err != nil {
+		t.Errorf("nil detail: %v", err)
+	}
+}
+
+func TestMartiMissionDestRoundTrip(t *testing.T) {
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string { return now.Add(d).Format(cotlib.CotTimeFormat) }
+	data := fmt.Sprintf(`<event version="2.0" uid="MD1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="1" lon="2" hae="0" ce="5" le="5"/><detail><marti><dest after="5f2c0d1e" mission="Op Sentinel"/><dest callsign="ALPHA"/><dest group="Cyan" uid="ANDROID-42"/></marti></detail></event>`,
+		ts(0), ts(0), ts(time.Minute))
+
+	want := []cotlib.MartiDest{
+		{Mission: "Op Sentinel", After: "5f2c0d1e"},
+		{Callsign: "ALPHA"},
+		{UID: "ANDROID-42", Group: "Cyan"},
+	}
+	check := func(t *testing.T, evt *cotlib.Event) {
+		t.Helper()
+		if evt.Detail == nil || evt.Detail.Marti == nil {
+			t.Fatal("marti not decoded")
+		}
+		got := evt.Detail.Marti.Dest
+		if len(got) != len(want) {
+			t.Fatalf("dest = %+v, want %+v", got, want)
+		}
+		for i := range want {
+			g := got[i]
+			g.Raw = nil
+			if !reflect.DeepEqual(g, want[i]) {
+				t.Errorf("dest %d = %+v, want %+v", i, g, want[i])
+			}
+		}
+	}
+
+	evt, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	check(t, evt)
+
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	// Dests keep their source attribute order and unknown attributes.
+	for _, dest := range []string{`<dest after="5f2c0d1e" mission="Op Sentinel"></dest>`, `<dest group="Cyan" uid="ANDROID-42"></dest>`} {
+		if !strings.Contains(string(out), dest) {
+			t.Errorf("%s not emitted:\n%s", dest, out)
+		}
+	}
+	again, err := cotlib.UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("re-unmarshal: %v\n%s", err, out)
+	}
+	defer cotlib.ReleaseEvent(again)
+	check(t, again)
+
+	// Attributes without a field are kept when the schema does not
+	// reject them.
+	cotlib.SetDetailSchemaValidation(false)
+	ext, err := cotlib.UnmarshalXMLEvent(context.Background(), []byte(strings.Replace(data, `<dest callsign="ALPHA"/>`, `<dest priority="high" callsign="ALPHA"/>`, 1)))
+	cotlib.SetDetailSchemaValidation(true)
+	if err != nil {
+		t.Fatalf("unmarshal unknown attribute: %v", err)
+	}
+	defer cotlib.ReleaseEvent(ext)
+	if out, err := ext.ToXML(); err != nil || !strings.Contains(string(out), `<dest priority="high" callsign="ALPHA"></dest>`) {
+		t.Errorf("unknown dest attribute not preserved (err %v):\n%s", err, out)
+	}
+
+	// Edited dests are written from their fields.
+	again.Detail.Marti.Dest[1].Callsign = "BRAVO"
+	out, err = again.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML after edit: %v", err)
+	}
+	if !strings.Contains(string(out), `<dest callsign="BRAVO"/>`) {
+		t.Errorf("edited dest not emitted:\n%s", out)
+	}
+
+	again.Detail.Marti.Dest = []cotlib.MartiDest{{After: "5f2c0d1e"}}
+	if err := again.Validate(); err == nil {
+		t.Error("expected error for dest with after but no mission")
+	}
+}
+
+func TestValidateDetailSubset(t *testing.T) {
+	evt, err := cotlib.NewEvent("SUB1", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	evt.Detail = &cotlib.Detail{
+		Contact: &cotlib.Contact{Callsign: "ALPHA"},
+		Track:   &cotlib.Track{Raw: []byte(`<track course="90" speed="3"/>`)},
+		Status:  &cotlib.Status{Raw: []byte(`<status battery="full"/>`)},
+	}
+
+	if err := cotlib.ValidateDetailSubset(evt, []string{"contact", "track"}); err != nil {
+		t.Errorf("contact+track subset: %v", err)
+	}
+	if err := evt.Validate(); err == nil {
+		t.Error("full validation should reject the malformed status")
+	}
+	if err := cotlib.ValidateDetailSubset(evt, []string{"track", "status"}); err == nil {
+		t.Error("subset including status should fail")
+	}
+
+	evt.Detail.Track.Raw = []byte(`<track course="east" speed="3"/>`)
+	if err := cotlib.ValidateDetailSubset(evt, []string{"contact", "track"}); err == nil {
+		t.Error("malformed track in subset should fail")
+	}
+
+	if err := cotlib.ValidateDetailSubset(evt, []string{"contcat"}); !errors.Is(err, cotlib.ErrInvalidInput) {
+		t.Errorf("unknown schema name = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestEffectiveColor(t *testing.T) {
+	cyan := &cotlib.GroupExtension{Raw: []byte(`<__group name="Cyan" role="Team Member"/>`)}
+	for _, tc := range []struct {
+		name       string
+		stroke     string
+		detail     cotlib.Detail
+		wantARGB   int
+		wantSource string
+		wantOK     bool
+	}{
+		{"color detail wins", "ff00ff00", cotlib.Detail{
+			ColorExtension: &cotlib.ColorExtension{Raw: []byte(`<color argb="-65536"/>`)},
+			StrokeColor:    &cotlib.StrokeColor{Raw: []byte(`<strokecolor value="-16776961"/>`)},
+			GroupExtension: cyan,
+		}, -65536, cotlib.ColorSourceDetail, true},
+		{"color value attribute", "", cotlib.Detail{
+			ColorExtension: &cotlib.ColorExtension{Raw: []byte(`<color value="4294967295"/>`)},
+		}, -1, cotlib.ColorSourceDetail, true},
+		{"stroke attribute over team", "ff00ff00", cotlib.Detail{GroupExtension: cyan}, -16711936, cotlib.ColorSourceStroke, true},
+		{"stroke detail over team", "", cotlib.Detail{
+			StrokeColor:    &cotlib.StrokeColor{Raw: []byte(`<strokecolor value="-16776961"/>`)},
+			GroupExtension: cyan,
+		}, -16776961, cotlib.ColorSourceStroke, true},
+		{"unparseable color falls through", "", cotlib.Detail{
+			ColorExtension: &cotlib.ColorExtension{Raw: []byte(`<color argb="red"/>`)},
+			GroupExtension: &cotlib.GroupExtension{Raw: []byte(`<__group name="Dark Blue" role="Team Member"/>`)},
+		}, -16777089, cotlib.ColorSourceTeam, true},
+		{"group fallback", "", cotlib.Detail{Group: &cotlib.Group{Name: "Cyan", Role: "Team Member"}}, -16711681, cotlib.ColorSourceTeam, true},
+		{"unknown team", "", cotlib.Detail{
+			GroupExtension: &cotlib.GroupExtension{Raw: []byte(`<__group name="Plaid" role="Team Member"/>`)},
+		}, 0, "", false},
+		{"none", "", cotlib.Detail{}, 0, "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			evt, err := cotlib.NewEvent("EC1", "a-f-G", 1, 2, 0)
+			if err != nil {
+				t.Fatalf("new event: %v", err)
+			}
+			defer cotlib.ReleaseEvent(evt)
+			evt.StrokeColor = tc.stroke
+			evt.Detail = &tc.detail
+			argb, source, ok := evt.EffectiveColor()
+			if argb != tc.wantARGB || source != tc.wantSource || ok != tc.wantOK {
+				t.Errorf("EffectiveColor() = %d, %q, %v; want %d, %q, %v", argb, source, ok, tc.wantARGB, tc.wantSource, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewDataPackageEvent(t *testing.T) {
+	const (
+		url  = "https://tak.example:8443/Marti/sync/content?hash=abc"
+		hash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	)
+	evt, err := cotlib.NewDataPackageEvent("DP-1", "Mission.zip", url, hash, 2048, "ANDROID-1", "ALPHA")
+	if err != nil {
+		t.Fatalf("NewDataPackageEvent: %v", err)
+	}
+	defer cotlib.ReleaseEvent(evt)
+	if err := evt.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := validator.ValidateAgainstSchema("tak-details-fileshare", evt.Detail.FileShare.Raw); err != nil {
+		t.Fatalf("fileshare schema: %v", err)
+	}
+	if missing := evt.MissingDetails(); len(missing) != 0 || len(cotlib.ExpectedDetails(evt.Type)) == 0 {
+		t.Errorf("type %s: expected %v, missing %v", evt.Type, cotlib.ExpectedDetails(evt.Type), missing)
+	}
+
+	data, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	for _, want := range []string{`type="b-t-b"`, `filename="Mission.zip"`, `name="Mission"`, `sizeInBytes="2048"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("missing %s:\n%s", want, data)
+		}
+	}
+	got, err := cotlib.UnmarshalXMLEvent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer cotlib.ReleaseEvent(got)
+	if u, ok := got.DataPackageURL(); !ok || u != url {
+		t.Errorf("DataPackageURL = %q, %v, want %q", u, ok, url)
+	}
+
+	for name, args := range map[string][3]string{
+		"relative url": {"/content", hash, "ANDROID-1"},
+		"short hash":   {url, "abcd", "ANDROID-1"},
+		"bad sender":   {url, hash, "1 bad"},
+	} {
+		if evt, err := cotlib.NewDataPackageEvent("DP-2", "pkg", args[0], args[1], 1, args[2], "ALPHA"); err == nil {
+			cotlib.ReleaseEvent(evt)
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := cotlib.NewDataPackageEvent("DP-3", "pkg", url, hash, -1, "ANDROID-1", "ALPHA"); !errors.Is(err, cotlib.ErrInvalidInput) {
+		t.Errorf("negative size error = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestStrictChatRouting(t *testing.T) {
+	chat := func() *cotlib.Chat {
+		return &cotlib.Chat{
+			ID:             "ANDROID-B",
+			Chatroom:       "BRAVO",
+			GroupOwner:     "false",
+			SenderCallsign: "ALPHA",
+			ChatGrps:       []cotlib.ChatGrp{{ID: "ANDROID-B", UID0: "ANDROID-A", UID1: "ANDROID-B"}},
+		}
+	}
+	consistent := &cotlib.Detail{
+		Chat:  chat(),
+		Marti: &cotlib.Marti{Dest: []cotlib.MartiDest{{Callsign: "BRAVO"}, {UID: "ANDROID-B"}}},
+	}
+	mismatched := &cotlib.Detail{
+		Chat:  chat(),
+		Marti: &cotlib.Marti{Dest: []cotlib.MartiDest{{Callsign: "CHARLIE"}}},
+	}
+	wrongUID := &cotlib.Detail{
+		Chat:  chat(),
+		Marti: &cotlib.Marti{Dest: []cotlib.MartiDest{{UID: "ANDROID-C", Callsign: "BRAVO"}}},
+	}
+
+	if err := mismatched.Validate(); err != nil {
+		t.Fatalf("routing checked while disabled: %v", err)
+	}
+
+	cotlib.SetStrictChatRouting(true)
+	defer cotlib.SetStrictChatRouting(false)
+	if err := consistent.Validate(); err != nil {
+		t.Errorf("consistent chat: %v", err)
+	}
+	for name, d := range map[string]*cotlib.Detail{"callsign": mismatched, "uid": wrongUID} {
+		if err := d.Validate(); !errors.Is(err, validator.ErrInvalidChat) {
+			t.Errorf("%s mismatch error = %v, want ErrInvalidChat", name, err)
+		}
+	}
+
+	broadcast := &cotlib.Detail{
+		Chat: &cotlib.Chat{
+			ID: "All Chat Rooms", Chatroom: "All Chat Rooms", GroupOwner: "false", SenderCallsign: "ALPHA",
+			ChatGrps: []cotlib.ChatGrp{{ID: "All Chat Rooms", UID0: "ANDROID-A"}},
+		},
+		Marti: &cotlib.Marti{Dest: []cotlib.MartiDest{{Callsign: "CHARLIE"}}},
+	}
+	if err := broadcast.Validate(); err != nil {
+		t.Errorf("All Chat Rooms: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"sort"
+	"strings"
+)
+
+// Schema categories returned by ListSchemasByCategory.
+const (
+	CategoryDetails      = "details"
+	CategoryDrawing      = "drawing"
+	CategoryRangeBearing = "rangebearing"
+	CategoryEvent        = "event"
+	CategoryOther        = "other"
+)
+
+// ListSchemasByCategory groups the names returned by ListAvailableSchemas
+// for presentation, for example in a diagnostics UI:
+//
+//   - "details": detail extension schemas (details-* and tak-details-*)
+//   - "drawing": drawing shape, marker, route and geofence schemas
+//   - "rangebearing": range and bearing schemas
+//   - "event": event-level schemas, including the MITRE CoT schemas
+//   - "other": everything else, such as the chat and link schemas
+//
+// Names within each category are sorted. Categories without schemas are
+// omitted, so the map is empty when built without schema validation.
+func ListSchemasByCategory() map[string][]string {
+	out := make(map[string][]string)
+	for _, name := range ListAvailableSchemas() {
+		c := schemaCategory(name)
+		out[c] = append(out[c], name)
+	}
+	for _, names := range out {
+		sort.Strings(names)
+	}
+	return out
+}
+
+// schemaCategory returns the category of the named schema.
+func schemaCategory(name string) string {
+	switch {
+	case strings.HasPrefix(name, "details-"), strings.HasPrefix(name, "tak-details-"):
+		return CategoryDetails
+	case strings.HasPrefix(name, "Range_&_Bearing"):
+		return CategoryRangeBearing
+	case strings.HasPrefix(name, "Drawing_Shapes"), strings.HasPrefix(name, "Marker_"),
+		strings.HasPrefix(name, "tak-marker"), name == "Geo_Fence", name == "Route", name == "tak-route":
+		return CategoryDrawing
+	case strings.HasPrefix(name, "event-"), strings.HasPrefix(name, "mitre-"):
+		return CategoryEvent
+	default:
+		return CategoryOther
+	}
+}
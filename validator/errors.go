@@ -1,6 +1,45 @@
 package validator
 
-import "errors"
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
 
 // ErrInvalidChat indicates the chat extension does not conform to the schema.
 var ErrInvalidChat = errors.New("invalid chat")
+
+// SchemaError describes the first violation reported while validating a
+// document against a schema.
+type SchemaError struct {
+	// Path locates the offending node relative to the document root, for
+	// example "__chat/chatgrp@uid0". It is empty if the schema engine did
+	// not report a node.
+	Path string
+	// Message is the schema engine's description of the violation.
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return "validation failed: " + e.Message
+	}
+	return "validation failed at " + e.Path + ": " + e.Message
+}
+
+// schemaErrorAttr extracts the attribute named in a schema error message.
+var schemaErrorAttr = regexp.MustCompile(`attribute '([^']+)'`)
+
+// newSchemaError builds a SchemaError from a libxml2 node path such as
+// "/__chat/chatgrp" and error message. Attributes named in the message are
+// appended to the path when the node itself is an element.
+func newSchemaError(path, msg string) *SchemaError {
+	msg = strings.TrimSpace(msg)
+	path = strings.TrimPrefix(path, "/")
+	if path != "" && !strings.Contains(path, "@") {
+		if m := schemaErrorAttr.FindStringSubmatch(msg); m != nil {
+			path += "@" + m[1]
+		}
+	}
+	return &SchemaError{Path: path, Message: msg}
+}
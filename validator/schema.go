@@ -5,9 +5,46 @@ package validator
 /*
 #cgo pkg-config: libxml-2.0
 #include <libxml/parser.h>
+#include <libxml/tree.h>
 #include <libxml/xmlschemas.h>
+#include <stdio.h>
 #include <stdlib.h>
 
+// schemaError records the first error reported while validating a document.
+typedef struct {
+    char path[512];
+    char msg[512];
+    int set;
+} schemaError;
+
+static void collectError(void *ctx, const xmlError *err) {
+    schemaError *se = (schemaError *)ctx;
+    if (se == NULL || err == NULL || se->set) {
+        return;
+    }
+    se->set = 1;
+    if (err->message) {
+        snprintf(se->msg, sizeof(se->msg), "%s", err->message);
+    }
+    xmlNodePtr node = (xmlNodePtr)err->node;
+    if (node == NULL) {
+        return;
+    }
+    if (node->type == XML_ATTRIBUTE_NODE && node->parent != NULL) {
+        xmlChar *p = xmlGetNodePath(node->parent);
+        if (p) {
+            snprintf(se->path, sizeof(se->path), "%s@%s", (const char *)p, (const char *)node->name);
+            xmlFree(p);
+        }
+        return;
+    }
+    xmlChar *p = xmlGetNodePath(node);
+    if (p) {
+        snprintf(se->path, sizeof(se->path), "%s", (const char *)p);
+        xmlFree(p);
+    }
+}
+
 static xmlSchemaPtr compileSchema(const char* buf, int size) {
     xmlSchemaParserCtxtPtr ctxt = xmlSchemaNewMemParserCtxt(buf, size);
     if (ctxt == NULL) {
@@ -28,13 +65,14 @@ static xmlSchemaPtr compileSchemaFile(const char* filename) {
     return schema;
 }
 
-static int validateDoc(xmlSchemaPtr schema, const char* docbuf, int size) {
+static int validateDoc(xmlSchemaPtr schema, const char* docbuf, int size, schemaError *se) {
     int opts = XML_PARSE_NONET;
     xmlDocPtr doc = xmlReadMemory(docbuf, size, "noname.xml", NULL, opts);
     if (doc == NULL) {
         return -1;
     }
     xmlSchemaValidCtxtPtr vctxt = xmlSchemaNewValidCtxt(schema);
+    xmlSchemaSetValidStructuredErrors(vctxt, (xmlStructuredErrorFunc)collectError, se);
     int ret = xmlSchemaValidateDoc(vctxt, doc);
     xmlSchemaFreeValidCtxt(vctxt);
     xmlFreeDoc(doc);
@@ -105,9 +143,16 @@ func (s *Schema) Validate(xml []byte) error {
 	if len(xml) > math.MaxInt32 {
 		return errors.New("xml input too large")
 	}
-	ret := C.validateDoc(s.ptr, (*C.char)(unsafe.Pointer(&xml[0])), C.int(len(xml)))
+	var se C.schemaError
+	ret := C.validateDoc(s.ptr, (*C.char)(unsafe.Pointer(&xml[0])), C.int(len(xml)), &se)
 	if ret != 0 {
-		return errors.New("validation failed")
+		if se.set == 0 {
+			return errors.New("validation failed")
+		}
+		return newSchemaError(
+			C.GoString((*C.char)(unsafe.Pointer(&se.path[0]))),
+			C.GoString((*C.char)(unsafe.Pointer(&se.msg[0]))),
+		)
 	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package validator_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/NERVsystems/cotlib/validator"
@@ -93,8 +94,20 @@ func TestValidateAgainstTAKDetailSchemas(t *testing.T) {
 		{
 			name:   "marti",
 			schema: "tak-details-marti",
-			good:   []byte(`<marti><dest callsign="A"/><dest callsign="B"/></marti>`),
-			bad:    []byte(`<marti><dest/></marti>`),
+			good:   []byte(`<marti><dest callsign="A"/><dest mission="Ops" after="c-17"/></marti>`),
+			bad:    []byte(`<marti><dest callsign="A" channel="9"/></marti>`),
+		},
+		{
+			name:   "radio",
+			schema: "tak-details-_radio_",
+			good:   []byte(`<_radio_ frequency="146520000" bandwidth="12500" modulation="FM"/>`),
+			bad:    []byte(`<_radio_ frequency="-1"/>`),
+		},
+		{
+			name:   "uastool",
+			schema: "tak-details-_uastool_",
+			good:   []byte(`<_uastool_ operatorId="OP-7" battery="87.5" flightMode="LOITER" vendor="x"><home lat="30.1" lon="-85.2" hae="12"/><gimbal pitch="-30"/></_uastool_>`),
+			bad:    []byte(`<_uastool_ battery="120"/>`),
 		},
 	}
 
@@ -117,6 +130,38 @@ func TestListAvailableSchemas(t *testing.T) {
 	}
 }
 
+func TestListSchemasByCategory(t *testing.T) {
+	byCat := validator.ListSchemasByCategory()
+	for _, c := range []string{validator.CategoryDetails, validator.CategoryDrawing, validator.CategoryRangeBearing, validator.CategoryEvent} {
+		if len(byCat[c]) == 0 {
+			t.Errorf("category %q is empty", c)
+		}
+	}
+	total := 0
+	for _, names := range byCat {
+		total += len(names)
+	}
+	if n := len(validator.ListAvailableSchemas()); total != n {
+		t.Errorf("categories hold %d schemas, want %d", total, n)
+	}
+	want := map[string]string{
+		"tak-details-contact":        validator.CategoryDetails,
+		"Drawing_Shapes_-_Circle":    validator.CategoryDrawing,
+		"Range_&_Bearing_-_Bullseye": validator.CategoryRangeBearing,
+		"event-point":                validator.CategoryEvent,
+		"chat":                       validator.CategoryOther,
+	}
+	for name, c := range want {
+		found := false
+		for _, n := range byCat[c] {
+			found = found || n == name
+		}
+		if !found {
+			t.Errorf("%s not listed under %q", name, c)
+		}
+	}
+}
+
 func TestValidateAdditionalDetailSchemas(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -351,3 +396,30 @@ func TestValidateLinksSchema(t *testing.T) {
 		t.Fatal("expected error for invalid links")
 	}
 }
+
+func TestSchemaErrorPath(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		path string
+	}{
+		{"missing_attribute", `<__chat chatroom="room" groupOwner="false" id="1" senderCallsign="A"><chatgrp id="room"/></__chat>`, "__chat/chatgrp@uid0"},
+		{"bad_attribute", `<__chat chatroom="room" groupOwner="false" id="1" senderCallsign="A"><chatgrp id="room" uid0="a b"/></__chat>`, "__chat/chatgrp@uid0"},
+		{"unexpected_element", `<__chat chatroom="room" groupOwner="false" id="1" senderCallsign="A"><bogus/></__chat>`, "__chat/bogus"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateAgainstSchema("tak-details-__chat", []byte(tt.data))
+			var se *validator.SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected SchemaError, got %v", err)
+			}
+			if se.Path != tt.path {
+				t.Errorf("Path = %q, want %q", se.Path, tt.path)
+			}
+			if se.Message == "" {
+				t.Error("empty message")
+			}
+		})
+	}
+}
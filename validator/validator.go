@@ -12,7 +12,7 @@ import (
 	"sync"
 )
 
-//go:embed schemas/** schemas/details/__chat.xsd schemas/details/__chatreceipt.xsd schemas/details/__geofence.xsd schemas/details/__group.xsd schemas/details/__serverdestination.xsd schemas/details/__video.xsd
+//go:embed schemas/** schemas/details/__chat.xsd schemas/details/__chatreceipt.xsd schemas/details/__geofence.xsd schemas/details/__group.xsd schemas/details/__serverdestination.xsd schemas/details/__video.xsd schemas/details/_medevac_.xsd schemas/details/_radio_.xsd schemas/details/_uastool_.xsd
 var schemasFS embed.FS
 
 //go:embed schemas/details/environment.xsd
@@ -0,0 +1,152 @@
+package cotlib
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// EventView is a read-only copy of an Event returned by Event.Snapshot.
+//
+// It holds no references into the event it was taken from: links and
+// unknown attributes are copied and the detail is frozen as its canonical
+// XML encoding. A view therefore stays valid after the event is passed to
+// ReleaseEvent and may be stored in caches or shared between goroutines.
+// The zero value is an empty view.
+type EventView struct {
+	version      string
+	uid          string
+	typ          string
+	how          string
+	time         time.Time
+	start        time.Time
+	stale        time.Time
+	point        Point
+	hasPoint     bool
+	message      string
+	strokeColor  string
+	userIcon     string
+	caveat       string
+	releasableTo string
+	links        []Link
+	unknownAttrs []xml.Attr
+	detail       []byte
+}
+
+// Snapshot returns an immutable view of the event that remains usable
+// after the event is released back to the pool. It is cheaper than copying
+// the full Detail tree since extensions are kept only as encoded XML. If
+// the detail cannot be encoded the view carries no detail. A nil event
+// yields the zero view.
+func (e *Event) Snapshot() EventView {
+	if e == nil {
+		return EventView{}
+	}
+	v := EventView{
+		version:      e.Version,
+		uid:          e.Uid,
+		typ:          e.Type,
+		how:          e.How,
+		time:         e.Time.Time(),
+		start:        e.Start.Time(),
+		stale:        e.Stale.Time(),
+		point:        e.Point,
+		hasPoint:     !e.NoPoint,
+		message:      e.Message,
+		strokeColor:  e.StrokeColor,
+		userIcon:     e.UserIcon,
+		caveat:       e.Caveat,
+		releasableTo: e.ReleasableTo,
+	}
+	if len(e.Links) > 0 {
+		v.links = append([]Link(nil), e.Links...)
+	}
+	if len(e.UnknownAttrs) > 0 {
+		v.unknownAttrs = append([]xml.Attr(nil), e.UnknownAttrs...)
+	}
+	if e.Detail != nil {
+		if data, err := xml.Marshal(e.Detail); err == nil {
+			v.detail = data
+		}
+	}
+	return v
+}
+
+// Version returns the event version attribute.
+func (v EventView) Version() string { return v.version }
+
+// UID returns the event uid.
+func (v EventView) UID() string { return v.uid }
+
+// Type returns the CoT type.
+func (v EventView) Type() string { return v.typ }
+
+// How returns the how attribute.
+func (v EventView) How() string { return v.how }
+
+// Time returns the event time.
+func (v EventView) Time() time.Time { return v.time }
+
+// Start returns the event start time.
+func (v EventView) Start() time.Time { return v.start }
+
+// Stale returns the event stale time.
+func (v EventView) Stale() time.Time { return v.stale }
+
+// Point returns the event point.
+func (v EventView) Point() Point { return v.point }
+
+// HasPoint reports whether the event carried a position.
+func (v EventView) HasPoint() bool { return v.hasPoint }
+
+// Message returns the GeoChat message text, if any.
+func (v EventView) Message() string { return v.message }
+
+// StrokeColor returns the strokeColor attribute.
+func (v EventView) StrokeColor() string { return v.strokeColor }
+
+// UserIcon returns the usericon attribute.
+func (v EventView) UserIcon() string { return v.userIcon }
+
+// Caveat returns the classification caveat attribute.
+func (v EventView) Caveat() string { return v.caveat }
+
+// ReleasableTo returns the releasableTo attribute.
+func (v EventView) ReleasableTo() string { return v.releasableTo }
+
+// Links returns a copy of the event-level links.
+func (v EventView) Links() []Link {
+	if len(v.links) == 0 {
+		return nil
+	}
+	return append([]Link(nil), v.links...)
+}
+
+// UnknownAttrs returns a copy of the unrecognised event attributes.
+func (v EventView) UnknownAttrs() []xml.Attr {
+	if len(v.unknownAttrs) == 0 {
+		return nil
+	}
+	return append([]xml.Attr(nil), v.unknownAttrs...)
+}
+
+// DetailXML returns a copy of the canonical <detail> encoding, or nil if
+// the event had no detail.
+func (v EventView) DetailXML() []byte {
+	if len(v.detail) == 0 {
+		return nil
+	}
+	return append([]byte(nil), v.detail...)
+}
+
+// Detail decodes the frozen detail into a new Detail owned by the caller.
+// It returns nil and no error if the event had no detail.
+func (v EventView) Detail() (*Detail, error) {
+	if len(v.detail) == 0 {
+		return nil, nil
+	}
+	var d Detail
+	if err := xml.Unmarshal(v.detail, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,69 @@
+package cotlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventSnapshot(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	data := fmt.Sprintf(`<event version="2.0" uid="SNAP1" type="a-f-G" how="m-g" time="%s" start="%s" stale="%s"><point lat="10" lon="20" hae="5" ce="3" le="4"/><detail><contact callsign="ALPHA"/><remarks>hello</remarks></detail><link uid="P1" type="a-f-G" relation="p-p"/></event>`,
+		now.Format(CotTimeFormat), now.Format(CotTimeFormat), now.Add(time.Minute).Format(CotTimeFormat))
+
+	evt, err := UnmarshalXMLEvent(context.Background(), []byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	view := evt.Snapshot()
+	ReleaseEvent(evt)
+
+	// Reuse pooled events so any shared state would be overwritten.
+	for i := 0; i < 4; i++ {
+		other, err := NewEvent("OTHER", "a-h-G", 1, 2, 3)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		other.AddLink(&Link{Uid: "X", Type: "a-h-G", Relation: "p-p"})
+		defer ReleaseEvent(other)
+	}
+
+	if view.UID() != "SNAP1" || view.Type() != "a-f-G" || view.How() != "m-g" || view.Version() != "2.0" {
+		t.Errorf("scalar fields = %s %s %s %s", view.UID(), view.Type(), view.How(), view.Version())
+	}
+	if !view.Time().Equal(now) || !view.Stale().Equal(now.Add(time.Minute)) {
+		t.Errorf("times = %v %v", view.Time(), view.Stale())
+	}
+	if p := view.Point(); !view.HasPoint() || p.Lat != 10 || p.Lon != 20 || p.Hae != 5 {
+		t.Errorf("point = %+v", p)
+	}
+	links := view.Links()
+	if len(links) != 1 || links[0].Uid != "P1" {
+		t.Fatalf("links = %+v", links)
+	}
+	links[0].Uid = "changed"
+	if view.Links()[0].Uid != "P1" {
+		t.Error("Links returned the view's backing slice")
+	}
+
+	if x := string(view.DetailXML()); !strings.Contains(x, `callsign="ALPHA"`) || !strings.Contains(x, "hello") {
+		t.Errorf("detail xml = %s", x)
+	}
+	d, err := view.Detail()
+	if err != nil {
+		t.Fatalf("detail: %v", err)
+	}
+	if d.Contact == nil || d.Contact.Callsign != "ALPHA" {
+		t.Errorf("decoded detail contact = %+v", d.Contact)
+	}
+
+	var zero EventView
+	if zero.UID() != "" || zero.DetailXML() != nil || zero.Links() != nil {
+		t.Error("zero view should be empty")
+	}
+	if (*Event)(nil).Snapshot().UID() != "" {
+		t.Error("nil event snapshot should be empty")
+	}
+}
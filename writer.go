@@ -0,0 +1,81 @@
+package cotlib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errWriterClosed is returned when an EventWriter is used after Close.
+var errWriterClosed = errors.New("event writer closed")
+
+// EventWriter streams events to an io.Writer as CoT XML, reusing a single
+// buffer for every event instead of allocating a new document per call.
+//
+// By default each event is written as a standalone document with its own
+// XML declaration, matching concatenated ToXML output. Calling WriteHeader
+// before the first event wraps the events in a single <events> element,
+// each written as by ToXMLFragment; Close then writes the closing tag.
+//
+// An EventWriter is not safe for concurrent use.
+type EventWriter struct {
+	w       io.Writer
+	buf     bytes.Buffer
+	wrapped bool
+	started bool
+	closed  bool
+}
+
+// NewEventWriter returns an EventWriter that writes to w.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{w: w}
+}
+
+// WriteHeader writes the XML declaration and the opening <events> tag. It
+// must be called before the first Write.
+func (ew *EventWriter) WriteHeader() error {
+	if ew.closed {
+		return errWriterClosed
+	}
+	if ew.started {
+		return fmt.Errorf("WriteHeader called after events were written")
+	}
+	ew.started = true
+	ew.wrapped = true
+	_, err := io.WriteString(ew.w, xmlHeader+"<events>\n")
+	return err
+}
+
+// Write serialises e and writes it to the underlying writer.
+func (ew *EventWriter) Write(e *Event) error {
+	if ew.closed {
+		return errWriterClosed
+	}
+	if e == nil {
+		return fmt.Errorf("nil event")
+	}
+	ew.started = true
+	ew.buf.Reset()
+	if !ew.wrapped {
+		ew.buf.WriteString(xmlHeader)
+	}
+	e.writeXML(&ew.buf)
+	ew.buf.WriteByte('\n')
+	_, err := ew.w.Write(ew.buf.Bytes())
+	return err
+}
+
+// Close writes the closing </events> tag if WriteHeader was called. It
+// does not close the underlying writer. Further calls to Write fail.
+func (ew *EventWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	if !ew.wrapped {
+		return nil
+	}
+	_, err := io.WriteString(ew.w, "</events>\n")
+	return err
+}
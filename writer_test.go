@@ -0,0 +1,403 @@
+package cotlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventWriter(t *testing.T) {
+	const n = 1000
+	events := make([]*Event, n)
+	for i := range events {
+		evt, err := NewEvent(fmt.Sprintf("W%d", i), "a-f-G", float64(i%90), float64(i%180), 0)
+		if err != nil {
+			t.Fatalf("new event: %v", err)
+		}
+		events[i] = evt
+	}
+	defer func() {
+		for _, e := range events {
+			ReleaseEvent(e)
+		}
+	}()
+
+	t.Run("stream", func(t *testing.T) {
+		var out bytes.Buffer
+		w := NewEventWriter(&out)
+		for _, e := range events {
+			if err := w.Write(e); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+		if err := w.Write(events[0]); err == nil {
+			t.Error("expected error writing after Close")
+		}
+
+		first, next, err := DetectAndDecode(context.Background(), &out)
+		if err != nil {
+			t.Fatalf("DetectAndDecode: %v", err)
+		}
+		got := []*Event{first}
+		for {
+			evt, err := next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("next: %v", err)
+			}
+			got = append(got, evt)
+		}
+		if len(got) != n {
+			t.Fatalf("decoded %d events, want %d", len(got), n)
+		}
+		for i, evt := range got {
+			if evt.Uid != events[i].Uid || evt.Point.Lat != events[i].Point.Lat {
+				t.Errorf("event %d = %s %+v, want %s %+v", i, evt.Uid, evt.Point, events[i].Uid, events[i].Point)
+			}
+			ReleaseEvent(evt)
+		}
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		var out bytes.Buffer
+		w := NewEventWriter(&out)
+		if err := w.WriteHeader(); err != nil {
+			t.Fatalf("header: %v", err)
+		}
+		for _, e := range events {
+			if err := w.Write(e); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+
+		var doc struct {
+			XMLName xml.Name `xml:"events"`
+			Events  []Event  `xml:"event"`
+		}
+		if err := xml.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if len(doc.Events) != n {
+			t.Fatalf("decoded %d events, want %d", len(doc.Events), n)
+		}
+		for i := range doc.Events {
+			if doc.Events[i].Uid != events[i].Uid {
+				t.Errorf("event %d uid = %s, want %s", i, doc.Events[i].Uid, events[i].Uid)
+			}
+		}
+	})
+
+	t.Run("header_after_write", func(t *testing.T) {
+		w := NewEventWriter(io.Discard)
+		if err := w.Write(events[0]); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := w.WriteHeader(); err == nil {
+			t.Error("expected error calling WriteHeader after Write")
+		}
+	})
+}
+
+func TestSetEventAttributeOrder(t *testing.T) {
+	evt, err := NewEvent("ORD1", "a-f-G", 1, 2, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.UnknownAttrs = []xml.Attr{{Name: xml.Name{Local: "access"}, Value: "Undefined"}}
+
+	startTag := func() string {
+		t.Helper()
+		data, err := evt.ToXML()
+		if err != nil {
+			t.Fatalf("ToXML: %v", err)
+		}
+		s := string(data)
+		s = s[strings.Index(s, "<event"):]
+		return s[:strings.IndexByte(s, '>')]
+	}
+	names := func(tag string) []string {
+		var out []string
+		for _, f := range strings.Fields(tag)[1:] {
+			out = append(out, f[:strings.IndexByte(f, '=')])
+		}
+		return out
+	}
+
+	if got := strings.Join(names(startTag()), ","); got != "version,type,how,uid,time,start,stale,access" {
+		t.Errorf("default order = %s", got)
+	}
+
+	if err := SetEventAttributeOrder([]string{"uid", "type", "time"}); err != nil {
+		t.Fatalf("SetEventAttributeOrder: %v", err)
+	}
+	defer SetEventAttributeOrder(nil)
+	if got := strings.Join(names(startTag()), ","); got != "uid,type,time,version,how,start,stale,access" {
+		t.Errorf("custom order = %s", got)
+	}
+
+	for _, bad := range [][]string{{"uid", "uid"}, {"callsign"}} {
+		if err := SetEventAttributeOrder(bad); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("SetEventAttributeOrder(%v) = %v, want ErrInvalidInput", bad, err)
+		}
+	}
+	if got := names(startTag())[0]; got != "uid" {
+		t.Errorf("rejected order changed output: first attribute %s", got)
+	}
+
+	if err := SetEventAttributeOrder(nil); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if got := names(startTag())[0]; got != "version" {
+		t.Errorf("reset order: first attribute %s", got)
+	}
+}
+
+func TestToXMLFragment(t *testing.T) {
+	evt, err := NewEvent("FRAG-1", "a-f-G", 30, -85, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+
+	full, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	frag, err := evt.ToXMLFragment()
+	if err != nil {
+		t.Fatalf("ToXMLFragment: %v", err)
+	}
+	if bytes.HasPrefix(frag, []byte("<?xml")) {
+		t.Fatalf("fragment has XML declaration: %s", frag)
+	}
+	if !bytes.HasSuffix(full, frag) {
+		t.Fatalf("fragment differs from ToXML body:\n%s\n%s", full, frag)
+	}
+
+	wrapped := append([]byte("<events>"), frag...)
+	wrapped = append(wrapped, "</events>"...)
+	var doc struct {
+		Events []Event `xml:"event"`
+	}
+	if err := xml.Unmarshal(wrapped, &doc); err != nil {
+		t.Fatalf("unmarshal wrapped fragment: %v", err)
+	}
+	if len(doc.Events) != 1 || doc.Events[0].Uid != "FRAG-1" {
+		t.Fatalf("unexpected wrapped events: %+v", doc.Events)
+	}
+
+	parsed, err := UnmarshalXMLEvent(context.Background(), frag)
+	if err != nil {
+		t.Fatalf("parse fragment: %v", err)
+	}
+	defer ReleaseEvent(parsed)
+	if parsed.Uid != evt.Uid || parsed.Type != evt.Type {
+		t.Fatalf("round trip mismatch: got %s/%s", parsed.Uid, parsed.Type)
+	}
+}
+
+func TestEventTimeStrings(t *testing.T) {
+	evt, err := NewEvent("TS-1", "a-f-G", 30, -85, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	loc := time.FixedZone("EST", -5*60*60)
+	evt.Time = CoTTime(time.Date(2024, 3, 1, 7, 0, 0, 250e6, loc))
+	evt.Start = evt.Time
+	evt.Stale = CoTTime(evt.Time.Time().Add(90 * time.Second))
+
+	if got, want := evt.TimeString(), "2024-03-01T12:00:00Z"; got != want {
+		t.Errorf("TimeString() = %q, want %q", got, want)
+	}
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	for name, got := range map[string]string{
+		"time":  evt.TimeString(),
+		"start": evt.StartString(),
+		"stale": evt.StaleString(),
+	} {
+		if attr := fmt.Sprintf(` %s="%s"`, name, got); !strings.Contains(string(out), attr) {
+			t.Errorf("ToXML missing%s:\n%s", attr, out)
+		}
+	}
+
+	evt.Stale = CoTTime{}
+	if got := evt.StaleString(); got != "" {
+		t.Errorf("unset StaleString() = %q", got)
+	}
+}
+
+func TestPreserveTimeStrings(t *testing.T) {
+	evt, err := NewEvent("PT-1", "a-f-G", 30, -85, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	base, err := evt.ToXML()
+	ReleaseEvent(evt)
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	const layout = "2006-01-02T15:04:05.000-07:00"
+	loc := time.FixedZone("CEST", 2*60*60)
+	doc := string(base)
+	for _, name := range []string{"time", "start", "stale"} {
+		prefix := ` ` + name + `="`
+		i := strings.Index(doc, prefix) + len(prefix)
+		j := i + strings.IndexByte(doc[i:], '"')
+		ts, err := time.Parse(CotTimeFormat, doc[i:j])
+		if err != nil {
+			t.Fatalf("parse %s: %v", name, err)
+		}
+		doc = doc[:i] + ts.Add(125*time.Millisecond).In(loc).Format(layout) + doc[j:]
+	}
+
+	SetPreserveTimeStrings(true)
+	defer SetPreserveTimeStrings(false)
+	got, err := UnmarshalXMLEvent(context.Background(), []byte(doc))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer ReleaseEvent(got)
+	out, err := got.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if string(out) != doc {
+		t.Errorf("round trip changed the document:\ngot  %s\nwant %s", out, doc)
+	}
+	if want := ` time="` + got.TimeString() + `"`; !strings.Contains(doc, want) {
+		t.Errorf("TimeString %q does not match the written attribute", got.TimeString())
+	}
+
+	got.Stale = CoTTime(got.Stale.Time().Add(time.Minute))
+	out, err = got.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if want := ` stale="` + got.StaleString() + `"`; !strings.Contains(string(out), want) {
+		t.Errorf("modified stale not normalized, want%s:\n%s", want, out)
+	}
+
+	SetPreserveTimeStrings(false)
+	plain, err := UnmarshalXMLEvent(context.Background(), []byte(doc))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer ReleaseEvent(plain)
+	if out, _ := plain.ToXML(); strings.Contains(string(out), "+02:00") {
+		t.Errorf("times preserved with the option disabled:\n%s", out)
+	}
+}
+
+func TestLinkChildrenRoundTrip(t *testing.T) {
+	evt, err := NewEvent("LINK-STYLE", "u-d-f", 30, -85, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	base, err := evt.ToXML()
+	ReleaseEvent(evt)
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	const link = `  <link uid="SHAPE-1" type="u-d-f" relation="p-p"><Style><LineStyle><color>ff0000ff</color></LineStyle></Style></link>` + "\n"
+	doc := strings.Replace(string(base), "</event>", link+"</event>", 1)
+
+	got, err := UnmarshalXMLEvent(context.Background(), []byte(doc))
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer ReleaseEvent(got)
+	if len(got.Links) != 1 || !strings.HasPrefix(got.Links[0].Children, "<Style>") {
+		t.Fatalf("links = %+v", got.Links)
+	}
+	out, err := got.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !strings.Contains(string(out), link) {
+		t.Errorf("styled link not preserved:\n%s", out)
+	}
+
+	got.Links[0].Children = "</x><x>"
+	if err := got.Validate(); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unbalanced children error = %v, want ErrInvalidInput", err)
+	}
+
+	plain := strings.Replace(string(base), "</event>", "  <link uid=\"A\" type=\"a-f-G\" relation=\"p-p\">\n  </link>\n</event>", 1)
+	p, err := UnmarshalXMLEvent(context.Background(), []byte(plain))
+	if err != nil {
+		t.Fatalf("unmarshal plain: %v", err)
+	}
+	defer ReleaseEvent(p)
+	if p.Links[0] != (Link{Uid: "A", Type: "a-f-G", Relation: "p-p"}) {
+		t.Errorf("whitespace-only link = %+v", p.Links[0])
+	}
+
+	// Text around child elements is kept as received.
+	mixed := strings.Replace(string(base), "</event>", "<link uid=\"B\" type=\"a-f-G\" relation=\"p-p\">\n  <Style/> note</link></event>", 1)
+	m, err := UnmarshalXMLEvent(context.Background(), []byte(mixed))
+	if err != nil {
+		t.Fatalf("unmarshal mixed: %v", err)
+	}
+	defer ReleaseEvent(m)
+	if got := m.Links[0].Children; got != "\n  <Style></Style> note" {
+		t.Errorf("mixed link children = %q", got)
+	}
+}
+
+func TestEventClassificationAttributes(t *testing.T) {
+	evt, err := NewEvent("CLASS-1", "a-f-G", 30, -85, 0)
+	if err != nil {
+		t.Fatalf("new event: %v", err)
+	}
+	defer ReleaseEvent(evt)
+	evt.Caveat = "FOUO"
+	evt.ReleasableTo = "USA GBR"
+	out, err := evt.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !strings.Contains(string(out), ` caveat="FOUO" releasableTo="USA GBR"`) {
+		t.Errorf("classification attributes missing:\n%s", out)
+	}
+
+	got, err := UnmarshalXMLEvent(context.Background(), out)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	defer ReleaseEvent(got)
+	if caveat, relTo := got.Classification(); caveat != "FOUO" || relTo != "USA GBR" {
+		t.Errorf("Classification() = %q, %q", caveat, relTo)
+	}
+	if len(got.UnknownAttrs) != 0 {
+		t.Errorf("UnknownAttrs = %v, want none", got.UnknownAttrs)
+	}
+	again, err := got.ToXML()
+	if err != nil {
+		t.Fatalf("ToXML: %v", err)
+	}
+	if !bytes.Equal(again, out) {
+		t.Errorf("round trip changed the event:\ngot  %s\nwant %s", again, out)
+	}
+	if v := got.Snapshot(); v.Caveat() != "FOUO" || v.ReleasableTo() != "USA GBR" {
+		t.Errorf("snapshot classification = %q, %q", v.Caveat(), v.ReleasableTo())
+	}
+}